@@ -11,8 +11,10 @@ import (
 // defaultAPI object. Note that the global version of the API
 // may be phased out in the future.
 type API struct {
-	settingEngine *SettingEngine
-	mediaEngine   *MediaEngine
+	settingEngine         *SettingEngine
+	mediaEngine           *MediaEngine
+	newBandwidthEstimator func() BandwidthEstimator
+	interceptor           Interceptor
 }
 
 // NewAPI Creates a new API object for keeping semi-global settings to WebRTC objects
@@ -35,6 +37,14 @@ func NewAPI(options ...func(*API)) *API {
 		a.mediaEngine = &MediaEngine{}
 	}
 
+	if a.newBandwidthEstimator == nil {
+		a.newBandwidthEstimator = newDefaultBandwidthEstimator
+	}
+
+	if a.interceptor == nil {
+		a.interceptor = interceptorChain{}
+	}
+
 	return a
 }
 
@@ -53,3 +63,21 @@ func WithSettingEngine(s SettingEngine) func(a *API) {
 		a.settingEngine = &s
 	}
 }
+
+// WithBandwidthEstimator allows providing the BandwidthEstimator used by every DTLSTransport
+// created by the API, newBandwidthEstimator is called once for each of them.
+// A GCC estimator created with NewGCCBandwidthEstimator is used by default.
+func WithBandwidthEstimator(newBandwidthEstimator func() BandwidthEstimator) func(a *API) {
+	return func(a *API) {
+		a.newBandwidthEstimator = newBandwidthEstimator
+	}
+}
+
+// WithInterceptors allows providing the Interceptors that observe and modify the RTP and RTCP
+// of every RTPSender, RTPReceiver and DTLSTransport created by the API. The first Interceptor
+// is the closest to the application.
+func WithInterceptors(interceptors ...Interceptor) func(a *API) {
+	return func(a *API) {
+		a.interceptor = interceptorChain(interceptors)
+	}
+}
@@ -0,0 +1,91 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// BandwidthEstimator estimates the bitrate that can be sent to the remote, using the
+// feedback the remote sends about the packets it received. A BandwidthEstimator is
+// shared by all the RTPSenders of a DTLSTransport, its methods are never called concurrently.
+type BandwidthEstimator interface {
+	// OnPacketSent is called for every RTP packet sent with a transport-wide sequence number
+	OnPacketSent(transportSequenceNumber uint16, size int, now time.Time)
+
+	// OnRTCP is called with the RTCP packets read from a RTPSender, these include
+	// transport-cc feedback, REMB and Receiver Reports
+	OnRTCP(pkts []rtcp.Packet, now time.Time)
+
+	// TargetBitrate returns the estimated bitrate in bits per second
+	TargetBitrate() uint64
+}
+
+// bandwidthEstimation feeds the BandwidthEstimator of a DTLSTransport, and notifies
+// when its target bitrate changes
+type bandwidthEstimation struct {
+	mu sync.Mutex
+
+	estimator               BandwidthEstimator
+	transportSequenceNumber uint16
+	targetBitrate           uint64
+
+	onTargetBitrateChangeHdlr func(uint64)
+}
+
+func newBandwidthEstimation(estimator BandwidthEstimator) *bandwidthEstimation {
+	return &bandwidthEstimation{
+		estimator:     estimator,
+		targetBitrate: estimator.TargetBitrate(),
+	}
+}
+
+// nextTransportSequenceNumber returns the transport-wide sequence number of the next packet
+func (b *bandwidthEstimation) nextTransportSequenceNumber() uint16 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sequenceNumber := b.transportSequenceNumber
+	b.transportSequenceNumber++
+	return sequenceNumber
+}
+
+func (b *bandwidthEstimation) packetSent(transportSequenceNumber uint16, size int, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.estimator.OnPacketSent(transportSequenceNumber, size, now)
+}
+
+func (b *bandwidthEstimation) rtcp(pkts []rtcp.Packet, now time.Time) {
+	b.mu.Lock()
+	b.estimator.OnRTCP(pkts, now)
+
+	targetBitrate := b.estimator.TargetBitrate()
+	changed := targetBitrate != b.targetBitrate
+	b.targetBitrate = targetBitrate
+	hdlr := b.onTargetBitrateChangeHdlr
+	b.mu.Unlock()
+
+	if changed && hdlr != nil {
+		hdlr(targetBitrate)
+	}
+}
+
+// getTargetBitrate returns the last target bitrate of the BandwidthEstimator
+func (b *bandwidthEstimation) getTargetBitrate() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.targetBitrate
+}
+
+func (b *bandwidthEstimation) onTargetBitrateChange(f func(uint64)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onTargetBitrateChangeHdlr = f
+}
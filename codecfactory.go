@@ -0,0 +1,82 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+)
+
+// PayloaderFactory returns a new rtp.Payloader for the payloads of codec
+type PayloaderFactory func(codec *RTPCodec) rtp.Payloader
+
+// DepacketizerFactory returns a new rtp.Depacketizer for the payloads of codec
+type DepacketizerFactory func(codec *RTPCodec) rtp.Depacketizer
+
+// defaultPayloaders are the factories of the codecs with built-in support
+var defaultPayloaders = map[string]PayloaderFactory{
+	"audio/pcmu": func(*RTPCodec) rtp.Payloader { return &codecs.G711Payloader{} },
+	"audio/pcma": func(*RTPCodec) rtp.Payloader { return &codecs.G711Payloader{} },
+	"audio/g722": func(*RTPCodec) rtp.Payloader { return &codecs.G722Payloader{} },
+	"audio/opus": func(*RTPCodec) rtp.Payloader { return &codecs.OpusPayloader{} },
+	"video/vp8":  func(*RTPCodec) rtp.Payloader { return &codecs.VP8Payloader{} },
+	"video/vp9":  func(*RTPCodec) rtp.Payloader { return &codecs.VP9Payloader{} },
+	"video/h264": func(*RTPCodec) rtp.Payloader { return &codecs.H264Payloader{} },
+}
+
+// defaultDepacketizers are the factories of the codecs with built-in support
+var defaultDepacketizers = map[string]DepacketizerFactory{
+	"audio/opus": func(*RTPCodec) rtp.Depacketizer { return &codecs.OpusPacket{} },
+	"video/vp8":  func(*RTPCodec) rtp.Depacketizer { return &codecs.VP8Packet{} },
+	"video/vp9":  func(*RTPCodec) rtp.Depacketizer { return &codecs.VP9Packet{} },
+	"video/h264": func(*RTPCodec) rtp.Depacketizer { return &codecs.H264Packet{} },
+}
+
+// RegisterPayloader sets the factory of the Payloaders of the codecs with the mime type mimeType,
+// e.g. "video/AV1". PeerConnection.NewTrack uses it for the Tracks of these codecs, like the ones
+// PopulateFromSDP doesn't know. It replaces the built-in Payloader of a mime type, and the
+// Payloader the codec was registered with.
+// RegisterPayloader must be called before m is given to an API, it is not safe for concurrent use.
+func (m *MediaEngine) RegisterPayloader(mimeType string, factory PayloaderFactory) {
+	if m.payloaders == nil {
+		m.payloaders = map[string]PayloaderFactory{}
+	}
+	m.payloaders[strings.ToLower(mimeType)] = factory
+}
+
+// RegisterDepacketizer sets the factory of the Depacketizers of the codecs with the mime type mimeType,
+// it replaces the built-in Depacketizer of a mime type.
+// RegisterDepacketizer must be called before m is given to an API, it is not safe for concurrent use.
+func (m *MediaEngine) RegisterDepacketizer(mimeType string, factory DepacketizerFactory) {
+	if m.depacketizers == nil {
+		m.depacketizers = map[string]DepacketizerFactory{}
+	}
+	m.depacketizers[strings.ToLower(mimeType)] = factory
+}
+
+// NewPayloader returns a new Payloader for codec, created by the factory registered for its mime type
+func (m *MediaEngine) NewPayloader(codec *RTPCodec) (rtp.Payloader, error) {
+	mimeType := strings.ToLower(codec.mimeType())
+	factory, ok := m.payloaders[mimeType]
+	if !ok {
+		if factory, ok = defaultPayloaders[mimeType]; !ok {
+			return nil, ErrNoPayloader
+		}
+	}
+	return factory(codec), nil
+}
+
+// NewDepacketizer returns a new Depacketizer for codec, created by the factory registered for its
+// mime type. It can be used with pkg/media/samplebuilder to read the samples of a remote Track
+func (m *MediaEngine) NewDepacketizer(codec *RTPCodec) (rtp.Depacketizer, error) {
+	mimeType := strings.ToLower(codec.mimeType())
+	factory, ok := m.depacketizers[mimeType]
+	if !ok {
+		if factory, ok = defaultDepacketizers[mimeType]; !ok {
+			return nil, ErrNoDepacketizer
+		}
+	}
+	return factory(codec), nil
+}
@@ -11,4 +11,34 @@ const (
 	receiveMTU = 1460
 
 	mediaSectionApplication = "application"
+
+	sdpAttributeRid       = "rid"
+	sdpAttributeSimulcast = "simulcast"
+
+	sdpSimulcastDirectionSend = "send"
+	sdpSimulcastDirectionRecv = "recv"
+
+	// How many packets of an undeclared SSRC are inspected for the MID and RID header extensions
+	simulcastProbeCount = 10
+
+	// Offset of the sequence number in a marshaled RTP header
+	rtpSequenceNumberOffset = 2
+
+	// Offset of the timestamp in a marshaled RTP header, and the size of the fixed header
+	rtpTimestampOffset = 4
+	rtpHeaderLength    = 12
+
+	// Offset and mask of the PayloadType in a marshaled RTP header
+	rtpPayloadTypeOffset = 1
+	rtpPayloadTypeMask   = 0x7F
+
+	// How many packets of a Track read from several sources, like its RTX stream, are buffered until
+	// the Track is read
+	rtxBufferSize = 128
+
+	// How many RTCP packets of a Track are buffered until the RTPReceiver is read
+	rtcpBufferSize = 64
+
+	// Length of the TransactionID returned by RTPSender.GetParameters
+	transactionIDLength = 16
 )
@@ -16,6 +16,7 @@ import (
 
 	"github.com/pion/dtls/v2"
 	"github.com/pion/dtls/v2/pkg/crypto/fingerprint"
+	"github.com/pion/rtcp"
 	"github.com/pion/srtp"
 	"github.com/pion/webrtc/v2/internal/mux"
 	"github.com/pion/webrtc/v2/internal/util"
@@ -46,6 +47,21 @@ type DTLSTransport struct {
 
 	dtlsMatcher mux.MatchFunc
 
+	// Sends transport-cc feedback for the packets received by every RTPReceiver of this transport
+	twccRecorder *twccRecorder
+
+	// Estimates the bitrate available to the RTPSenders of this transport
+	bandwidthEstimation *bandwidthEstimation
+
+	// Paces the RTP packets of the RTPSenders of this transport, nil if pacing isn't enabled
+	pacer *pacer
+
+	// Writes the RTCP of this transport through the Interceptors of the API
+	rtcpWriter RTCPWriter
+
+	// Counts the NACK, PLI and FIR packets sent about the streams of the RTPReceivers
+	feedbackSent *rtcpFeedbackCounter
+
 	api *API
 }
 
@@ -58,6 +74,13 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 		api:          api,
 		state:        DTLSTransportStateNew,
 		dtlsMatcher:  mux.MatchDTLS,
+		feedbackSent: newRTCPFeedbackCounter(),
+	}
+	t.rtcpWriter = api.interceptor.BindRTCPWriter(RTCPWriterFunc(t.sendRTCP))
+	t.twccRecorder = newTWCCRecorder(api.settingEngine.twccFeedbackInterval, t.writeRTCP)
+	t.bandwidthEstimation = newBandwidthEstimation(api.newBandwidthEstimator())
+	if pacing := api.settingEngine.pacing; pacing.Enabled {
+		t.pacer = newPacer(pacing.Rate, pacing.Factor, pacing.Priority, t.bandwidthEstimation.getTargetBitrate)
 	}
 
 	if len(certificates) > 0 {
@@ -115,6 +138,13 @@ func (t *DTLSTransport) State() DTLSTransportState {
 	return t.state
 }
 
+// OnTargetBitrateChange sets an event handler which is invoked when the BandwidthEstimator
+// changes the bitrate, in bits per second, that can be sent over the transport.
+// The estimate is updated while RTCP is read from the RTPSenders.
+func (t *DTLSTransport) OnTargetBitrateChange(f func(bitrate uint64)) {
+	t.bandwidthEstimation.onTargetBitrateChange(f)
+}
+
 // GetLocalParameters returns the DTLS parameters of the local DTLSTransport upon construction.
 func (t *DTLSTransport) GetLocalParameters() (DTLSParameters, error) {
 	fingerprints := []DTLSFingerprint{}
@@ -235,6 +265,36 @@ func (t *DTLSTransport) getSRTCPSession() (*srtp.SessionSRTCP, error) {
 	return t.srtcpSession, nil
 }
 
+// writeRTCP sends RTCP packets to the connected peer through the Interceptors
+func (t *DTLSTransport) writeRTCP(pkts []rtcp.Packet) error {
+	return t.rtcpWriter.WriteRTCP(pkts)
+}
+
+// sendRTCP sends RTCP packets to the connected peer
+// If no peer is connected the packets are discarded
+func (t *DTLSTransport) sendRTCP(pkts []rtcp.Packet) error {
+	raw, err := rtcp.Marshal(pkts)
+	if err != nil {
+		return err
+	}
+
+	srtcpSession, err := t.getSRTCPSession()
+	if err != nil {
+		return nil
+	}
+
+	writeStream, err := srtcpSession.OpenWriteStream()
+	if err != nil {
+		return fmt.Errorf("WriteRTCP failed to open WriteStream: %v", err)
+	}
+
+	if _, err := writeStream.Write(raw); err != nil {
+		return err
+	}
+	t.feedbackSent.count(pkts)
+	return nil
+}
+
 func (t *DTLSTransport) role() DTLSRole {
 	// If remote has an explicit role use the inverse
 	switch t.remoteParameters.Role {
@@ -359,6 +419,11 @@ func (t *DTLSTransport) Stop() error {
 	// Try closing everything and collect the errors
 	var closeErrs []error
 
+	t.twccRecorder.close()
+	if t.pacer != nil {
+		t.pacer.close()
+	}
+
 	if t.srtpSession != nil {
 		if err := t.srtpSession.Close(); err != nil {
 			closeErrs = append(closeErrs, err)
@@ -76,6 +76,10 @@ var (
 	// ErrCodecNotFound is returned when a codec search to the Media Engine fails
 	ErrCodecNotFound = errors.New("codec not found")
 
+	// ErrHeaderExtensionIDsExhausted is returned when a RTP header extension
+	// is registered but no more one-byte header extension IDs are free
+	ErrHeaderExtensionIDsExhausted = errors.New("no free RTP header extension IDs")
+
 	// ErrNoRemoteDescription indicates that an operation was rejected because
 	// the remote description is not set
 	ErrNoRemoteDescription = errors.New("remote description is not set")
@@ -119,4 +123,45 @@ var (
 	// ErrSessionDescriptionConflictingIcePwd indicates SetRemoteDescription was called with a SessionDescription that
 	// contains multiple conflicting ice-pwd values
 	ErrSessionDescriptionConflictingIcePwd = errors.New("SetRemoteDescription called with multiple conflicting ice-pwd values")
+
+	// ErrIncompatibleTrack indicates ReplaceTrack was called with a Track that doesn't
+	// have the codec of the RTPSender
+	ErrIncompatibleTrack = errors.New("track codec is not compatible with the RTPSender")
+
+	// ErrStaleTransactionID indicates SetParameters was called with parameters that
+	// weren't returned by the last call to GetParameters
+	ErrStaleTransactionID = errors.New("parameters were not returned by the last GetParameters")
+
+	// ErrModifyingEncodings indicates SetParameters was called with encodings that don't
+	// match the RID, SSRC and PayloadType of the encodings of the RTPSender
+	ErrModifyingEncodings = errors.New("encodings cannot be added, removed or reidentified")
+
+	// ErrPayloadTypeInUse indicates RegisterCodec was called with a PayloadType
+	// that is used by another codec
+	ErrPayloadTypeInUse = errors.New("payload type is used by another codec")
+
+	// ErrPayloadTypesExhausted indicates RegisterCodec was called without a PayloadType
+	// and the dynamic range 96-127 is full
+	ErrPayloadTypesExhausted = errors.New("no free dynamic payload types")
+
+	// ErrCodecPreferencesOnlyRTX indicates SetCodecPreferences was called with only
+	// retransmission codecs
+	ErrCodecPreferencesOnlyRTX = errors.New("codec preferences only contain retransmission codecs")
+
+	// ErrNoPayloader indicates no Payloader is registered for the mime type of a codec
+	ErrNoPayloader = errors.New("no payloader registered for the codec")
+
+	// ErrNoDepacketizer indicates no Depacketizer is registered for the mime type of a codec
+	ErrNoDepacketizer = errors.New("no depacketizer registered for the codec")
+
+	// ErrInvalidDTMFTone indicates InsertDTMF was called with a character that isn't a DTMF tone
+	ErrInvalidDTMFTone = errors.New("tones must only contain 0-9, A-D, #, * and ,")
+
+	// ErrDTMFNotNegotiated indicates InsertDTMF was called on a RTPSender that can't send
+	// telephone-events
+	ErrDTMFNotNegotiated = errors.New("telephone-event is not negotiated for the RTPSender")
+
+	// ErrPacerQueueFull indicates a RTP packet was discarded because the pacer already
+	// queued too many bytes
+	ErrPacerQueueFull = errors.New("pacer queue is full")
 )
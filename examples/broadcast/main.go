@@ -3,18 +3,12 @@ package main
 import (
 	"fmt"
 	"io"
-	"time"
 
-	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v2"
 
 	"github.com/pion/webrtc/v2/examples/internal/signal"
 )
 
-const (
-	rtcpPLIInterval = time.Second * 3
-)
-
 func main() {
 	sdpChan := signal.HTTPSDPServer()
 
@@ -53,27 +47,25 @@ func main() {
 	}
 
 	localTrackChan := make(chan *webrtc.Track)
+	publisherChan := make(chan *webrtc.RTPReceiver, 1)
 	// Set a handler for when a new remote track starts, this just distributes all our packets
 	// to connected peers
 	peerConnection.OnTrack(func(remoteTrack *webrtc.Track, receiver *webrtc.RTPReceiver) {
-		// Send a PLI on an interval so that the publisher is pushing a keyframe every rtcpPLIInterval
-		// This can be less wasteful by processing incoming RTCP events, then we would emit a NACK/PLI when a viewer requests it
-		go func() {
-			ticker := time.NewTicker(rtcpPLIInterval)
-			for range ticker.C {
-				if rtcpSendErr := peerConnection.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: remoteTrack.SSRC()}}); rtcpSendErr != nil {
-					fmt.Println(rtcpSendErr)
-				}
-			}
-		}()
-
 		// Create a local track, all our SFU clients will be fed via this track
 		localTrack, newTrackErr := peerConnection.NewTrack(remoteTrack.PayloadType(), remoteTrack.SSRC(), "video", "pion")
 		if newTrackErr != nil {
 			panic(newTrackErr)
 		}
+		publisherChan <- receiver
 		localTrackChan <- localTrack
 
+		// The forwarder keeps the stream of the local track continuous, another publisher
+		// could be forwarded to it without the viewers noticing a jump
+		forwarder, newForwarderErr := webrtc.NewForwarder(localTrack)
+		if newForwarderErr != nil {
+			panic(newForwarderErr)
+		}
+
 		rtpBuf := make([]byte, 1400)
 		for {
 			i, readErr := remoteTrack.Read(rtpBuf)
@@ -82,7 +74,7 @@ func main() {
 			}
 
 			// ErrClosedPipe means we don't have any subscribers, this is ok if no peers have connected yet
-			if _, err = localTrack.Write(rtpBuf[:i]); err != nil && err != io.ErrClosedPipe {
+			if _, err = forwarder.Write(rtpBuf[:i]); err != nil && err != io.ErrClosedPipe {
 				panic(err)
 			}
 		}
@@ -110,6 +102,7 @@ func main() {
 	fmt.Println(signal.Encode(answer))
 
 	localTrack := <-localTrackChan
+	publisher := <-publisherChan
 	for {
 		fmt.Println("")
 		fmt.Println("Curl an base64 SDP to start sendonly peer connection")
@@ -123,11 +116,29 @@ func main() {
 			panic(err)
 		}
 
-		_, err = peerConnection.AddTrack(localTrack)
+		sender, err := peerConnection.AddTrack(localTrack)
 		if err != nil {
 			panic(err)
 		}
 
+		// Forward the keyframe requests of the viewers to the publisher, requests of many viewers
+		// at once are merged into a single one
+		sender.OnKeyframeRequest(func(*webrtc.Track) {
+			if rtcpSendErr := publisher.RequestKeyframe(); rtcpSendErr != nil {
+				fmt.Println(rtcpSendErr)
+			}
+		})
+
+		// Keyframe requests are handled while RTCP is read
+		go func() {
+			rtcpBuf := make([]byte, 1500)
+			for {
+				if _, rtcpErr := sender.Read(rtcpBuf); rtcpErr != nil {
+					return
+				}
+			}
+		}()
+
 		// Set the remote SessionDescription
 		err = peerConnection.SetRemoteDescription(recvOnlyOffer)
 		if err != nil {
@@ -2,11 +2,9 @@ package main
 
 import (
 	"fmt"
-	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v2"
 	"github.com/pion/webrtc/v2/examples/internal/signal"
-	"time"
 )
 
 type Session struct {
@@ -68,6 +66,10 @@ func CreateSession(viewId, streamId string) (*Session, string) {
 	peerConnection.OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
 		fmt.Println(state)
 	})
+	// The estimate is updated from the REMB sent by the viewer while RTCP is read in readRTCP
+	peerConnection.OnTargetBitrateChange(func(bitrate uint64) {
+		fmt.Printf("bitrate: %d\n", bitrate)
+	})
 	peerConnection.OnICECandidate(func(candidate *webrtc.ICECandidate) {
 		if candidate != nil {
 			encodedDescr := signal.Encode(peerConnection.LocalDescription())
@@ -93,22 +95,14 @@ func (s *Session) StartSession(sdp string) error {
 func (s *Session) run() {
 	go s.videoPump()
 	go s.audioPump()
-	go s.GetREMBPeriodically()
+	go s.readRTCP()
 }
 
-func (s *Session) GetREMBPeriodically() {
-	timer := time.NewTicker(time.Second)
-	defer timer.Stop()
-	for range timer.C {
-		rtcps, err := s.videoSender.ReadRTCP()
-		if err != nil {
-			panic(err)
-		}
-		for _, v := range rtcps {
-			switch v.(type) {
-			case *rtcp.ReceiverEstimatedMaximumBitrate:
-				println(fmt.Sprintf("bitrate: %d", v.(*rtcp.ReceiverEstimatedMaximumBitrate).Bitrate))
-			}
+// readRTCP reads the RTCP sent by the viewer, this feeds the bandwidth estimator
+func (s *Session) readRTCP() {
+	for {
+		if _, err := s.videoSender.ReadRTCP(); err != nil {
+			return
 		}
 	}
 }
@@ -5,7 +5,6 @@ import (
 	"math/rand"
 	"time"
 
-	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v2"
 	"github.com/pion/webrtc/v2/examples/internal/signal"
 )
@@ -71,13 +70,12 @@ func main() {
 	// Set a handler for when a new remote track starts, this handler copies inbound RTP packets,
 	// replaces the SSRC and sends them back
 	peerConnection.OnTrack(func(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
-		// Send a PLI on an interval so that the publisher is pushing a keyframe every rtcpPLIInterval
-		// This is a temporary fix until we implement incoming RTCP events, then we would push a PLI only when a viewer requests it
+		// Request a keyframe on an interval so that the publisher is pushing a keyframe every 3 seconds
+		// This could be less wasteful by requesting one when the remote asks for it with RTPSender.OnKeyframeRequest
 		go func() {
 			ticker := time.NewTicker(time.Second * 3)
 			for range ticker.C {
-				errSend := peerConnection.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: track.SSRC()}})
-				if errSend != nil {
+				if errSend := receiver.RequestKeyframe(); errSend != nil {
 					fmt.Println(errSend)
 				}
 			}
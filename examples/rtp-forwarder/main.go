@@ -6,7 +6,6 @@ import (
 	"net"
 	"time"
 
-	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v2"
 	"github.com/pion/webrtc/v2/examples/internal/signal"
 )
@@ -94,15 +93,17 @@ func main() {
 			return
 		}
 
-		// Send a PLI on an interval so that the publisher is pushing a keyframe every rtcpPLIInterval
-		go func() {
-			ticker := time.NewTicker(time.Second * 2)
-			for range ticker.C {
-				if rtcpErr := peerConnection.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: track.SSRC()}}); rtcpErr != nil {
-					fmt.Println(rtcpErr)
+		// Request a keyframe on an interval so that the publisher is pushing a keyframe every 2 seconds
+		if track.Kind() == webrtc.RTPCodecTypeVideo {
+			go func() {
+				ticker := time.NewTicker(time.Second * 2)
+				for range ticker.C {
+					if rtcpErr := receiver.RequestKeyframe(); rtcpErr != nil {
+						fmt.Println(rtcpErr)
+					}
 				}
-			}
-		}()
+			}()
+		}
 
 		b := make([]byte, 1500)
 		for {
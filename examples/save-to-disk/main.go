@@ -5,7 +5,6 @@ import (
 	"os"
 	"time"
 
-	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v2"
 	"github.com/pion/webrtc/v2/pkg/media"
 	"github.com/pion/webrtc/v2/pkg/media/ivfwriter"
@@ -81,16 +80,17 @@ func main() {
 	// an ivf file, since we could have multiple video tracks we provide a counter.
 	// In your application this is where you would handle/process video
 	peerConnection.OnTrack(func(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
-		// Send a PLI on an interval so that the publisher is pushing a keyframe every rtcpPLIInterval
-		go func() {
-			ticker := time.NewTicker(time.Second * 3)
-			for range ticker.C {
-				errSend := peerConnection.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: track.SSRC()}})
-				if errSend != nil {
-					fmt.Println(errSend)
+		// Request a keyframe on an interval so that the publisher is pushing a keyframe every 3 seconds
+		if track.Kind() == webrtc.RTPCodecTypeVideo {
+			go func() {
+				ticker := time.NewTicker(time.Second * 3)
+				for range ticker.C {
+					if errSend := receiver.RequestKeyframe(); errSend != nil {
+						fmt.Println(errSend)
+					}
 				}
-			}
-		}()
+			}()
+		}
 
 		codec := track.Codec()
 		if codec.Name == webrtc.Opus {
@@ -6,8 +6,6 @@ import (
 	"math/rand"
 	"time"
 
-	"github.com/pion/rtcp"
-	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v2"
 	"github.com/pion/webrtc/v2/examples/internal/signal"
 )
@@ -64,6 +62,13 @@ func main() {
 		panic(err)
 	}
 
+	// The forwarder rewrites the packets of whichever track is current into a single continuous
+	// stream, so the browser doesn't see the sequence numbers and timestamps jump on a switch
+	forwarder, err := webrtc.NewForwarder(outputTrack)
+	if err != nil {
+		panic(err)
+	}
+
 	// In addition to the implicit transceiver added by the track, we add two more
 	// for the other tracks
 	_, err = peerConnection.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo,
@@ -87,19 +92,15 @@ func main() {
 	currTrack := 0
 	// The total number of tracks
 	trackCount := 0
-	// The channel of packets with a bit of buffer
-	packets := make(chan *rtp.Packet, 60)
 
 	// Set a handler for when a new remote track starts
 	peerConnection.OnTrack(func(track *webrtc.Track, receiver *webrtc.RTPReceiver) {
 		fmt.Printf("Track has started, of type %d: %s \n", track.PayloadType(), track.Codec().Name)
 		trackNum := trackCount
 		trackCount++
-		// The last timestamp so that we can change the packet to only be the delta
-		var lastTimestamp uint32
 
-		// Whether this track is the one currently sending to the channel (on change
-		// of this we send a PLI to have the entire picture updated)
+		// Whether this track is the one currently sending to the forwarder (on change
+		// of this we request a keyframe to have the entire picture updated)
 		var isCurrTrack bool
 		for {
 			// Read RTP packets being sent to Pion
@@ -108,25 +109,19 @@ func main() {
 				panic(readErr)
 			}
 
-			// Change the timestamp to only be the delta
-			oldTimestamp := rtp.Timestamp
-			if lastTimestamp == 0 {
-				rtp.Timestamp = 0
-			} else {
-				rtp.Timestamp -= lastTimestamp
-			}
-			lastTimestamp = oldTimestamp
-
 			// Check if this is the current track
 			if currTrack == trackNum {
-				// If just switched to this track, send PLI to get picture refresh
+				// If just switched to this track, request a keyframe to get picture refresh
 				if !isCurrTrack {
 					isCurrTrack = true
-					if writeErr := peerConnection.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: track.SSRC()}}); writeErr != nil {
+					if writeErr := receiver.RequestKeyframe(); writeErr != nil {
 						fmt.Println(writeErr)
 					}
 				}
-				packets <- rtp
+				// Write out the packet, ignoring closed pipe if nobody is listening
+				if writeErr := forwarder.WriteRTP(rtp); writeErr != nil && writeErr != io.ErrClosedPipe {
+					panic(writeErr)
+				}
 			} else {
 				isCurrTrack = false
 			}
@@ -152,26 +147,6 @@ func main() {
 	// Output the answer in base64 so we can paste it in browser
 	fmt.Printf("Paste below base64 in browser:\n%v\n", signal.Encode(answer))
 
-	// Asynchronously take all packets in the channel and write them out to our
-	// track
-	go func() {
-		var currTimestamp uint32
-		for i := uint16(0); ; i++ {
-			packet := <-packets
-			// Timestamp on the packet is really a diff, so add it to current
-			currTimestamp += packet.Timestamp
-			packet.Timestamp = currTimestamp
-			// Set the output SSRC
-			packet.SSRC = outputTrack.SSRC()
-			// Keep an increasing sequence number
-			packet.SequenceNumber = i
-			// Write out the packet, ignoring closed pipe if nobody is listening
-			if err := outputTrack.WriteRTP(packet); err != nil && err != io.ErrClosedPipe {
-				panic(err)
-			}
-		}
-	}()
-
 	// Wait for connection, then rotate the track every 5s
 	fmt.Printf("Waiting for connection\n")
 	for {
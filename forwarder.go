@@ -0,0 +1,228 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// Forwarder writes the RTP packets of any number of sources to a local Track as a single
+// continuous stream. The packets get the SSRC and PayloadType of the Track, and when the source
+// changes the sequence numbers and timestamps continue from the last packet written, as do the
+// PictureID and TL0PICIDX of VP8. Receivers of the Track then see no jump when a publisher
+// reconnects or a Simulcast layer is switched. A keyframe of the new source should still be
+// requested when switching.
+type Forwarder struct {
+	mu    sync.Mutex
+	track *Track
+	vp8   bool
+
+	// SSRC of the source the last packet was written from
+	source        uint32
+	started       bool
+	sourceChanged bool
+
+	sequenceNumberOffset uint16
+	timestampOffset      uint32
+	lastSequenceNumber   uint16
+	lastTimestamp        uint32
+	lastPacketTime       time.Time
+
+	// The VP8 offsets are computed from the first packet of a source that has the field
+	pictureIDChanged, tl0PicIdxChanged bool
+	pictureIDOffset                    uint16
+	tl0PicIdxOffset                    uint8
+	lastPictureID                      uint16
+	lastTL0PicIdx                      uint8
+	havePictureID, haveTL0PicIdx       bool
+}
+
+// NewForwarder creates a Forwarder writing to track, which must be a local Track
+func NewForwarder(track *Track) (*Forwarder, error) {
+	if track == nil {
+		return nil, fmt.Errorf("Track must not be nil")
+	}
+
+	track.mu.RLock()
+	defer track.mu.RUnlock()
+	if track.receiver != nil {
+		return nil, fmt.Errorf("Forwarder can not write to a remote track")
+	}
+
+	return &Forwarder{
+		track: track,
+		vp8:   track.codec != nil && strings.EqualFold(track.codec.Name, VP8),
+	}, nil
+}
+
+// Track returns the Track the Forwarder writes to
+func (f *Forwarder) Track() *Track {
+	return f.track
+}
+
+// SourceChanged makes the next packet start a new source even if it has the SSRC of the
+// previous one, as when a publisher reconnects with the same SSRC
+func (f *Forwarder) SourceChanged() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sourceChanged = true
+}
+
+// Write writes a RTP packet of a source to the Track
+func (f *Forwarder) Write(b []byte) (n int, err error) {
+	packet := &rtp.Packet{}
+	if err = packet.Unmarshal(b); err != nil {
+		return 0, err
+	}
+
+	if err = f.WriteRTP(packet); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// WriteRTP writes a RTP packet of a source to the Track, p isn't modified
+func (f *Forwarder) WriteRTP(p *rtp.Packet) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// The lock is held while writing so packets are sent in the order they were rewritten
+	return f.track.WriteRTP(f.rewrite(p, time.Now()))
+}
+
+// rewrite maps a packet of a source onto the stream of the Track
+func (f *Forwarder) rewrite(p *rtp.Packet, now time.Time) *rtp.Packet {
+	if !f.started || f.sourceChanged || p.SSRC != f.source {
+		if f.started {
+			f.sequenceNumberOffset = f.lastSequenceNumber + 1 - p.SequenceNumber
+
+			// The timestamps must increase even if the source changed right after a packet was written
+			elapsed := uint32(0)
+			if codec := f.track.Codec(); codec != nil {
+				elapsed = uint32(now.Sub(f.lastPacketTime).Seconds() * float64(codec.ClockRate))
+			}
+			if elapsed == 0 {
+				elapsed = 1
+			}
+			f.timestampOffset = f.lastTimestamp + elapsed - p.Timestamp
+			f.pictureIDChanged, f.tl0PicIdxChanged = true, true
+		}
+		f.source, f.started, f.sourceChanged = p.SSRC, true, false
+	}
+
+	h := cloneHeader(&p.Header)
+	h.SSRC = f.track.SSRC()
+	h.PayloadType = f.track.PayloadType()
+	h.SequenceNumber += f.sequenceNumberOffset
+	h.Timestamp += f.timestampOffset
+
+	// Reordered packets don't move the stream back
+	if f.lastPacketTime.IsZero() || h.SequenceNumber-f.lastSequenceNumber < 0x8000 {
+		f.lastSequenceNumber, f.lastTimestamp = h.SequenceNumber, h.Timestamp
+	}
+	f.lastPacketTime = now
+
+	payload := p.Payload
+	if f.vp8 {
+		payload = f.rewriteVP8(payload)
+	}
+	return &rtp.Packet{Header: *h, Payload: payload}
+}
+
+// skip accounts for a packet of the current source that isn't written, the sequence numbers
+// written stay continuous
+func (f *Forwarder) skip(p *rtp.Packet) {
+	if !f.started || f.sourceChanged || p.SSRC != f.source {
+		return
+	}
+
+	if diff := p.SequenceNumber + f.sequenceNumberOffset - f.lastSequenceNumber; diff != 0 && diff < 0x8000 {
+		f.sequenceNumberOffset--
+	}
+}
+
+// rewriteVP8 returns a copy of a VP8 payload with the PictureID and TL0PICIDX continuing the
+// ones written before the source changed, reordered packets don't move them back
+func (f *Forwarder) rewriteVP8(payload []byte) []byte {
+	pictureIDOffset, pictureIDLength, tl0PicIdxOffset, ok := parseVP8PayloadDescriptor(payload)
+	if !ok || (pictureIDLength == 0 && tl0PicIdxOffset == 0) {
+		return payload
+	}
+	out := append([]byte{}, payload...)
+
+	switch pictureIDLength {
+	case 1:
+		pictureID := uint16(out[pictureIDOffset] & 0x7F)
+		if f.pictureIDChanged {
+			f.pictureIDOffset, f.pictureIDChanged = f.lastPictureID+1-pictureID, false
+		}
+		pictureID = (pictureID + f.pictureIDOffset) & 0x7F
+		if !f.havePictureID || (pictureID-f.lastPictureID)&0x7F < 0x40 {
+			f.lastPictureID, f.havePictureID = pictureID, true
+		}
+		out[pictureIDOffset] = byte(pictureID)
+	case 2:
+		pictureID := uint16(out[pictureIDOffset]&0x7F)<<8 | uint16(out[pictureIDOffset+1])
+		if f.pictureIDChanged {
+			f.pictureIDOffset, f.pictureIDChanged = f.lastPictureID+1-pictureID, false
+		}
+		pictureID = (pictureID + f.pictureIDOffset) & 0x7FFF
+		if !f.havePictureID || (pictureID-f.lastPictureID)&0x7FFF < 0x4000 {
+			f.lastPictureID, f.havePictureID = pictureID, true
+		}
+		out[pictureIDOffset] = 0x80 | byte(pictureID>>8)
+		out[pictureIDOffset+1] = byte(pictureID)
+	}
+
+	if tl0PicIdxOffset != 0 {
+		tl0PicIdx := out[tl0PicIdxOffset]
+		if f.tl0PicIdxChanged {
+			f.tl0PicIdxOffset, f.tl0PicIdxChanged = f.lastTL0PicIdx+1-tl0PicIdx, false
+		}
+		tl0PicIdx += f.tl0PicIdxOffset
+		if !f.haveTL0PicIdx || tl0PicIdx-f.lastTL0PicIdx < 0x80 {
+			f.lastTL0PicIdx, f.haveTL0PicIdx = tl0PicIdx, true
+		}
+		out[tl0PicIdxOffset] = tl0PicIdx
+	}
+	return out
+}
+
+// parseVP8PayloadDescriptor locates the PictureID and TL0PICIDX of the VP8 payload descriptor
+// of RFC 7741 section 4.2. The length and offsets of absent fields are 0
+func parseVP8PayloadDescriptor(payload []byte) (pictureIDOffset, pictureIDLength, tl0PicIdxOffset int, ok bool) {
+	if len(payload) < 1 {
+		return 0, 0, 0, false
+	} else if payload[0]&0x80 == 0 {
+		return 0, 0, 0, true
+	} else if len(payload) < 2 {
+		return 0, 0, 0, false
+	}
+
+	i := 2
+	if payload[1]&0x80 != 0 {
+		if len(payload) <= i {
+			return 0, 0, 0, false
+		}
+
+		pictureIDOffset, pictureIDLength = i, 1
+		if payload[i]&0x80 != 0 {
+			pictureIDLength = 2
+		}
+		i += pictureIDLength
+	}
+
+	if payload[1]&0x40 != 0 {
+		if len(payload) <= i {
+			return 0, 0, 0, false
+		}
+		tl0PicIdxOffset = i
+		i++
+	}
+	return pictureIDOffset, pictureIDLength, tl0PicIdxOffset, len(payload) >= i
+}
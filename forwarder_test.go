@@ -0,0 +1,95 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForwarder(t *testing.T) {
+	track, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+	f, err := NewForwarder(track)
+	assert.NoError(t, err)
+	assert.Equal(t, track, f.Track())
+
+	// X, I and L are set, with a 15 bit PictureID and a TL0PICIDX
+	vp8Packet := func(ssrc uint32, sequenceNumber uint16, timestamp uint32, pictureID uint16, tl0PicIdx uint8) *rtp.Packet {
+		return &rtp.Packet{
+			Header:  rtp.Header{Version: 2, SSRC: ssrc, PayloadType: 100, SequenceNumber: sequenceNumber, Timestamp: timestamp},
+			Payload: []byte{0x90, 0xC0, 0x80 | byte(pictureID>>8), byte(pictureID), tl0PicIdx, 0xAA},
+		}
+	}
+	assertPacket := func(p *rtp.Packet, sequenceNumber uint16, timestamp uint32, pictureID uint16, tl0PicIdx uint8) {
+		assert.Equal(t, uint32(1234), p.SSRC)
+		assert.Equal(t, uint8(DefaultPayloadTypeVP8), p.PayloadType)
+		assert.Equal(t, sequenceNumber, p.SequenceNumber)
+		assert.Equal(t, timestamp, p.Timestamp)
+		assert.Equal(t, []byte{0x90, 0xC0, 0x80 | byte(pictureID>>8), byte(pictureID), tl0PicIdx, 0xAA}, p.Payload)
+	}
+
+	// The first source keeps its numbering
+	now := time.Now()
+	assertPacket(f.rewrite(vp8Packet(1, 100, 9000, 300, 20), now), 100, 9000, 300, 20)
+	assertPacket(f.rewrite(vp8Packet(1, 101, 12000, 301, 21), now), 101, 12000, 301, 21)
+
+	// A reordered packet is rewritten without moving the stream back
+	assertPacket(f.rewrite(vp8Packet(1, 99, 6000, 299, 19), now), 99, 6000, 299, 19)
+
+	// The second source continues the stream, 10ms later
+	source := vp8Packet(2, 5000, 70000, 0x7FFF, 200)
+	now = now.Add(10 * time.Millisecond)
+	assertPacket(f.rewrite(source, now), 102, 12900, 302, 22)
+	assertPacket(f.rewrite(vp8Packet(2, 5001, 73000, 0, 201), now), 103, 15900, 303, 23)
+	assert.Equal(t, uint16(5000), source.SequenceNumber, "the packet of the source isn't modified")
+	assert.Equal(t, byte(0xFF), source.Payload[2])
+
+	// A reconnected source with the same SSRC
+	f.SourceChanged()
+	assertPacket(f.rewrite(vp8Packet(2, 10, 100, 50, 5), now), 104, 15901, 304, 24)
+}
+
+func TestForwarder_NotVP8(t *testing.T) {
+	track, err := NewTrack(DefaultPayloadTypeOpus, 1234, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	assert.NoError(t, err)
+	f, err := NewForwarder(track)
+	assert.NoError(t, err)
+
+	payload := []byte{0x90, 0xC0, 0x80, 0x01, 0x02}
+	p := f.rewrite(&rtp.Packet{Header: rtp.Header{SSRC: 1, SequenceNumber: 7}, Payload: payload}, time.Now())
+	assert.Equal(t, payload, p.Payload)
+	assert.Equal(t, uint16(7), p.SequenceNumber)
+
+	_, err = NewForwarder(nil)
+	assert.Error(t, err)
+	_, err = NewForwarder(&Track{receiver: &RTPReceiver{}})
+	assert.Error(t, err)
+}
+
+func TestParseVP8PayloadDescriptor(t *testing.T) {
+	for _, test := range []struct {
+		payload                                            []byte
+		pictureIDOffset, pictureIDLength, tl0PicIdxOffset int
+		ok                                                 bool
+	}{
+		{[]byte{}, 0, 0, 0, false},
+		{[]byte{0x10, 0xAA}, 0, 0, 0, true},
+		{[]byte{0x90}, 0, 0, 0, false},
+		{[]byte{0x90, 0x80, 0x05, 0xAA}, 2, 1, 0, true},
+		{[]byte{0x90, 0x80, 0x85}, 2, 2, 0, false},
+		{[]byte{0x90, 0x40, 0x05, 0xAA}, 0, 0, 2, true},
+		{[]byte{0x90, 0xE0, 0x85, 0x01, 0x05, 0x20, 0xAA}, 2, 2, 4, true},
+	} {
+		pictureIDOffset, pictureIDLength, tl0PicIdxOffset, ok := parseVP8PayloadDescriptor(test.payload)
+		assert.Equal(t, test.ok, ok, "%x", test.payload)
+		if ok {
+			assert.Equal(t, test.pictureIDOffset, pictureIDOffset, "%x", test.payload)
+			assert.Equal(t, test.pictureIDLength, pictureIDLength, "%x", test.payload)
+			assert.Equal(t, test.tl0PicIdxOffset, tl0PicIdxOffset, "%x", test.payload)
+		}
+	}
+}
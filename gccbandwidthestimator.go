@@ -0,0 +1,364 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+const (
+	// Bitrates used by the BandwidthEstimator of an API that doesn't configure one
+	gccDefaultInitialBitrate = 300000
+	gccDefaultMinBitrate     = 30000
+	gccDefaultMaxBitrate     = 10000000
+
+	// Packets sent within this interval form a group, delay variation is measured between groups
+	gccBurstInterval = 5 * time.Millisecond
+	// Smoothing factor of the accumulated delay variation
+	gccSmoothing = 0.9
+	// Number of delay samples used to compute the trend
+	gccTrendWindow = 20
+	// The trend is scaled by the number of samples, up to this value
+	gccMaxTrendDeltas = 60
+	// Gain applied to the trend before comparing it with the threshold
+	gccTrendGain = 4
+	// Delay trend in milliseconds above which the network is considered overused
+	gccOveruseThreshold = 12.5
+
+	// Window used to measure the bitrate acknowledged by the remote
+	gccAckedBitrateWindow = 500 * time.Millisecond
+	// Multiplicative increase per second while the network isn't overused
+	gccIncreaseFactor = 1.08
+	// The estimate is reduced to this fraction of the acknowledged bitrate on overuse
+	gccDecreaseFactor = 0.85
+	// Minimum time between two decreases, to let the network react
+	gccDecreaseInterval = 200 * time.Millisecond
+
+	// Loss fractions below this let the loss based estimate grow, above gccHighLoss it is reduced
+	gccLowLoss  = 0.02
+	gccHighLoss = 0.1
+	// Multiplicative increase of the loss based estimate, at most once per gccLossIncreaseInterval
+	gccLossIncreaseFactor   = 1.05
+	gccLossIncreaseInterval = time.Second
+)
+
+type gccBandwidthUsage int
+
+const (
+	gccBandwidthUsageNormal gccBandwidthUsage = iota
+	gccBandwidthUsageOverusing
+	gccBandwidthUsageUnderusing
+)
+
+type gccSentPacket struct {
+	sentAt time.Time
+	size   int
+}
+
+type gccPacketGroup struct {
+	firstSent, lastSent time.Time
+	lastArrival         time.Duration
+}
+
+type gccAckedPacket struct {
+	arrival time.Duration
+	size    int
+}
+
+type gccTrendSample struct {
+	x, y float64
+}
+
+// gccBandwidthEstimator is a simplified Google Congestion Control estimator. The target bitrate
+// is the lowest of a delay based estimate computed from transport-cc feedback, a loss based
+// estimate computed from transport-cc feedback and Receiver Reports, and the last REMB.
+type gccBandwidthEstimator struct {
+	minBitrate, maxBitrate float64
+	delayBitrate           float64
+	lossBitrate            float64
+	rembBitrate            float64
+
+	sent map[uint16]gccSentPacket
+
+	// Delay based estimate
+	currentGroup, previousGroup *gccPacketGroup
+	accumulatedDelay            float64
+	smoothedDelay               float64
+	trendSamples                []gccTrendSample
+	numDeltas                   int
+	usage                       gccBandwidthUsage
+	acked                       []gccAckedPacket
+	lastUpdate, lastDecrease    time.Time
+
+	// Loss based estimate
+	lastLossIncrease time.Time
+}
+
+// NewGCCBandwidthEstimator creates the BandwidthEstimator used by default, a simplified
+// Google Congestion Control that starts at initialBitrate and stays between minBitrate and maxBitrate.
+// transport-cc feedback is only received if a codec was registered with the transport-cc RTCPFeedback.
+func NewGCCBandwidthEstimator(initialBitrate, minBitrate, maxBitrate uint64) BandwidthEstimator {
+	return &gccBandwidthEstimator{
+		minBitrate:   float64(minBitrate),
+		maxBitrate:   float64(maxBitrate),
+		delayBitrate: float64(initialBitrate),
+		lossBitrate:  float64(maxBitrate),
+		sent:         map[uint16]gccSentPacket{},
+	}
+}
+
+func newDefaultBandwidthEstimator() BandwidthEstimator {
+	return NewGCCBandwidthEstimator(gccDefaultInitialBitrate, gccDefaultMinBitrate, gccDefaultMaxBitrate)
+}
+
+// OnPacketSent stores when the packet was sent, to compare it with its arrival time
+func (g *gccBandwidthEstimator) OnPacketSent(transportSequenceNumber uint16, size int, now time.Time) {
+	g.sent[transportSequenceNumber] = gccSentPacket{sentAt: now, size: size}
+}
+
+// OnRTCP updates the estimates from transport-cc feedback, REMB and Receiver Reports. The
+// reception reports of the Receiver Reports are averaged into a single loss fraction
+func (g *gccBandwidthEstimator) OnRTCP(pkts []rtcp.Packet, now time.Time) {
+	fractionLost, reports := 0.0, 0
+	for _, pkt := range pkts {
+		switch p := pkt.(type) {
+		case *rtcp.TransportLayerCC:
+			g.onTransportCC(p, now)
+		case *rtcp.ReceiverEstimatedMaximumBitrate:
+			g.rembBitrate = float64(p.Bitrate)
+		case *rtcp.ReceiverReport:
+			for _, report := range p.Reports {
+				fractionLost += float64(report.FractionLost) / 256
+				reports++
+			}
+		}
+	}
+
+	if reports > 0 {
+		g.onLoss(fractionLost/float64(reports), now)
+	}
+}
+
+// TargetBitrate returns the lowest of the estimates
+func (g *gccBandwidthEstimator) TargetBitrate() uint64 {
+	return uint64(g.targetBitrate())
+}
+
+func (g *gccBandwidthEstimator) targetBitrate() float64 {
+	target := math.Min(g.delayBitrate, g.lossBitrate)
+	if g.rembBitrate > 0 {
+		target = math.Min(target, g.rembBitrate)
+	}
+	return math.Max(g.minBitrate, math.Min(g.maxBitrate, target))
+}
+
+func (g *gccBandwidthEstimator) onTransportCC(feedback *rtcp.TransportLayerCC, now time.Time) {
+	arrival := time.Duration(feedback.ReferenceTime) * 64 * time.Millisecond
+	sequenceNumber := feedback.BaseSequenceNumber
+	deltaIndex, lost, total := 0, 0, 0
+
+	for _, status := range transportCCStatuses(feedback) {
+		sent, ok := g.sent[sequenceNumber]
+		delete(g.sent, sequenceNumber)
+
+		if status == rtcp.TypeTCCPacketNotReceived {
+			if ok {
+				lost++
+				total++
+			}
+		} else if deltaIndex < len(feedback.RecvDeltas) {
+			arrival += time.Duration(feedback.RecvDeltas[deltaIndex].Delta) * time.Microsecond
+			deltaIndex++
+
+			if ok {
+				total++
+				g.onPacketArrival(sent, arrival)
+			}
+		}
+		sequenceNumber++
+	}
+
+	if total > 0 {
+		g.onLoss(float64(lost)/float64(total), now)
+	}
+	g.updateDelayBitrate(now)
+}
+
+// transportCCStatuses returns the status of every packet described by the feedback
+func transportCCStatuses(feedback *rtcp.TransportLayerCC) []uint16 {
+	statuses := []uint16{}
+	for _, chunk := range feedback.PacketChunks {
+		switch c := chunk.(type) {
+		case *rtcp.RunLengthChunk:
+			for i := uint16(0); i < c.RunLength; i++ {
+				statuses = append(statuses, c.PacketStatusSymbol)
+			}
+		case *rtcp.StatusVectorChunk:
+			statuses = append(statuses, c.SymbolList...)
+		}
+	}
+
+	if len(statuses) > int(feedback.PacketStatusCount) {
+		statuses = statuses[:feedback.PacketStatusCount]
+	}
+	return statuses
+}
+
+// onPacketArrival groups the packets sent in a burst, and measures the delay variation between groups
+func (g *gccBandwidthEstimator) onPacketArrival(sent gccSentPacket, arrival time.Duration) {
+	g.acked = append(g.acked, gccAckedPacket{arrival: arrival, size: sent.size})
+	for len(g.acked) > 0 && arrival-g.acked[0].arrival > gccAckedBitrateWindow {
+		g.acked = g.acked[1:]
+	}
+
+	switch {
+	case g.currentGroup == nil:
+		g.currentGroup = &gccPacketGroup{firstSent: sent.sentAt, lastSent: sent.sentAt, lastArrival: arrival}
+		return
+	case sent.sentAt.Sub(g.currentGroup.firstSent) <= gccBurstInterval:
+		if sent.sentAt.After(g.currentGroup.lastSent) {
+			g.currentGroup.lastSent = sent.sentAt
+		}
+		if arrival > g.currentGroup.lastArrival {
+			g.currentGroup.lastArrival = arrival
+		}
+		return
+	case sent.sentAt.Before(g.currentGroup.firstSent):
+		return // Reordered into a previous group
+	}
+
+	if g.previousGroup != nil {
+		g.onGroupDelta(g.currentGroup, g.previousGroup)
+	}
+	g.previousGroup = g.currentGroup
+	g.currentGroup = &gccPacketGroup{firstSent: sent.sentAt, lastSent: sent.sentAt, lastArrival: arrival}
+}
+
+// onGroupDelta updates the trend of the delay variation and detects overuse
+func (g *gccBandwidthEstimator) onGroupDelta(current, previous *gccPacketGroup) {
+	arrivalDelta := float64(current.lastArrival-previous.lastArrival) / float64(time.Millisecond)
+	sendDelta := float64(current.lastSent.Sub(previous.lastSent)) / float64(time.Millisecond)
+
+	g.accumulatedDelay += arrivalDelta - sendDelta
+	g.smoothedDelay = gccSmoothing*g.smoothedDelay + (1-gccSmoothing)*g.accumulatedDelay
+	if g.numDeltas < gccMaxTrendDeltas {
+		g.numDeltas++
+	}
+
+	g.trendSamples = append(g.trendSamples, gccTrendSample{
+		x: float64(current.lastArrival) / float64(time.Millisecond),
+		y: g.smoothedDelay,
+	})
+	if len(g.trendSamples) > gccTrendWindow {
+		g.trendSamples = g.trendSamples[1:]
+	}
+
+	trend := linearFitSlope(g.trendSamples) * float64(g.numDeltas) * gccTrendGain
+	switch {
+	case trend > gccOveruseThreshold:
+		g.usage = gccBandwidthUsageOverusing
+	case trend < -gccOveruseThreshold:
+		g.usage = gccBandwidthUsageUnderusing
+	default:
+		g.usage = gccBandwidthUsageNormal
+	}
+}
+
+// linearFitSlope returns the slope of the least squares line through the samples
+func linearFitSlope(samples []gccTrendSample) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+
+	var sumX, sumY float64
+	for _, s := range samples {
+		sumX += s.x
+		sumY += s.y
+	}
+	avgX, avgY := sumX/float64(len(samples)), sumY/float64(len(samples))
+
+	var numerator, denominator float64
+	for _, s := range samples {
+		numerator += (s.x - avgX) * (s.y - avgY)
+		denominator += (s.x - avgX) * (s.x - avgX)
+	}
+	if denominator == 0 {
+		return 0
+	}
+	return numerator / denominator
+}
+
+// ackedBitrate returns the bitrate the remote received during the last gccAckedBitrateWindow
+func (g *gccBandwidthEstimator) ackedBitrate() float64 {
+	if len(g.acked) < 2 {
+		return 0
+	}
+
+	duration := g.acked[len(g.acked)-1].arrival - g.acked[0].arrival
+	if duration <= 0 {
+		return 0
+	}
+
+	size := 0
+	for _, p := range g.acked {
+		size += p.size
+	}
+	return float64(size*8) / duration.Seconds()
+}
+
+// updateDelayBitrate decreases the delay based estimate on overuse, and increases it otherwise
+func (g *gccBandwidthEstimator) updateDelayBitrate(now time.Time) {
+	elapsed := time.Duration(0)
+	if !g.lastUpdate.IsZero() {
+		elapsed = now.Sub(g.lastUpdate)
+	}
+	g.lastUpdate = now
+	ackedBitrate := g.ackedBitrate()
+
+	switch g.usage {
+	case gccBandwidthUsageOverusing:
+		if now.Sub(g.lastDecrease) < gccDecreaseInterval {
+			return
+		}
+
+		base := g.delayBitrate
+		if ackedBitrate > 0 {
+			base = math.Min(base, ackedBitrate)
+		}
+		g.delayBitrate = gccDecreaseFactor * base
+		g.lastDecrease = now
+	case gccBandwidthUsageNormal:
+		if elapsed > time.Second {
+			elapsed = time.Second
+		}
+		g.delayBitrate *= math.Pow(gccIncreaseFactor, elapsed.Seconds())
+
+		// Don't grow far above what is actually sent
+		if ackedBitrate > 0 {
+			g.delayBitrate = math.Min(g.delayBitrate, 1.5*ackedBitrate+10000)
+		}
+	case gccBandwidthUsageUnderusing:
+		// Hold while the queues drain
+	}
+
+	g.delayBitrate = math.Max(g.minBitrate, math.Min(g.maxBitrate, g.delayBitrate))
+}
+
+// onLoss reduces the loss based estimate when a lot of packets are lost, and lets it grow when few are
+func (g *gccBandwidthEstimator) onLoss(fraction float64, now time.Time) {
+	switch {
+	case fraction > gccHighLoss:
+		g.lossBitrate = g.targetBitrate() * (1 - 0.5*fraction)
+	case fraction < gccLowLoss:
+		if now.Sub(g.lastLossIncrease) < gccLossIncreaseInterval {
+			return
+		}
+		g.lossBitrate *= gccLossIncreaseFactor
+		g.lastLossIncrease = now
+	}
+
+	g.lossBitrate = math.Max(g.minBitrate, math.Min(g.maxBitrate, g.lossBitrate))
+}
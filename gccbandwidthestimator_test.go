@@ -0,0 +1,83 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// runGCC sends packets of 1000 bytes every 10ms for duration, and returns the feedback with
+// the arrival times delayed by delay(i) for the i-th packet
+func runGCC(t *testing.T, g BandwidthEstimator, duration time.Duration, delay func(i int) time.Duration) {
+	recorder := newTWCCRecorder(0, nil)
+	recorder.started = true
+	now := recorder.startTime
+
+	for i := 0; time.Duration(i)*10*time.Millisecond < duration; i++ {
+		sentAt := now.Add(time.Duration(i) * 10 * time.Millisecond)
+		g.OnPacketSent(uint16(i), 1000, sentAt)
+		recorder.record(1234, uint16(i), sentAt.Add(delay(i)))
+
+		// Feedback every 100ms
+		if i%10 == 9 {
+			for _, pkt := range recorder.buildFeedback() {
+				g.OnRTCP([]rtcp.Packet{unmarshalTWCC(t, pkt)}, sentAt.Add(delay(i)))
+			}
+		}
+	}
+}
+
+func TestGCCBandwidthEstimator(t *testing.T) {
+	t.Run("Increase", func(t *testing.T) {
+		g := NewGCCBandwidthEstimator(300000, 30000, 2000000)
+		runGCC(t, g, 5*time.Second, func(int) time.Duration { return 20 * time.Millisecond })
+
+		assert.Greater(t, g.TargetBitrate(), uint64(300000))
+		assert.LessOrEqual(t, g.TargetBitrate(), uint64(2000000))
+	})
+
+	t.Run("Overuse", func(t *testing.T) {
+		g := NewGCCBandwidthEstimator(2000000, 30000, 2000000)
+
+		// The queue grows by 2ms for every packet
+		runGCC(t, g, 2*time.Second, func(i int) time.Duration { return time.Duration(i) * 2 * time.Millisecond })
+
+		// Decreased below the 800kbps that were sent
+		assert.Less(t, g.TargetBitrate(), uint64(800000))
+		assert.GreaterOrEqual(t, g.TargetBitrate(), uint64(30000))
+	})
+
+	t.Run("Loss", func(t *testing.T) {
+		g := NewGCCBandwidthEstimator(1000000, 30000, 2000000)
+		now := time.Now()
+
+		g.OnRTCP([]rtcp.Packet{&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: 1234, FractionLost: 128}}}}, now)
+		assert.Equal(t, uint64(750000), g.TargetBitrate())
+
+		// Low loss lets the estimate grow again, at most once per gccLossIncreaseInterval
+		g.OnRTCP([]rtcp.Packet{&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: 1234}}}}, now)
+		g.OnRTCP([]rtcp.Packet{&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: 1234}}}}, now)
+		assert.Equal(t, uint64(787500), g.TargetBitrate())
+
+		// The reports of a compound packet are a single loss fraction, here of 12.5%
+		g.OnRTCP([]rtcp.Packet{
+			&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: 1234, FractionLost: 64}, {SSRC: 5678}}},
+			&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: 9012, FractionLost: 32}}},
+		}, now)
+		assert.Equal(t, uint64(738281), g.TargetBitrate())
+	})
+
+	t.Run("REMB", func(t *testing.T) {
+		g := NewGCCBandwidthEstimator(1000000, 30000, 2000000)
+
+		g.OnRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 500000, SSRCs: []uint32{1234}}}, time.Now())
+		assert.Equal(t, uint64(500000), g.TargetBitrate())
+
+		g.OnRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 10, SSRCs: []uint32{1234}}}, time.Now())
+		assert.Equal(t, uint64(30000), g.TargetBitrate(), "the estimate must not go below the minimum")
+	})
+}
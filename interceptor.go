@@ -0,0 +1,173 @@
+// +build !js
+
+package webrtc
+
+import (
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// StreamInfo describes the RTP stream of a RTPSender encoding or of a remote Track
+// an Interceptor is bound to. It must not be modified by the Interceptor.
+type StreamInfo struct {
+	ID               string
+	SSRC             uint32
+	RID              string
+	Kind             RTPCodecType
+	PayloadType      uint8
+	Codec            *RTPCodec
+	HeaderExtensions []RTPHeaderExtensionParameter
+}
+
+// RTPWriter writes a RTP packet of a local stream
+type RTPWriter interface {
+	WriteRTP(header *rtp.Header, payload []byte) (int, error)
+}
+
+// RTPReader reads a marshaled RTP packet of a remote stream
+type RTPReader interface {
+	Read(b []byte) (int, error)
+}
+
+// RTCPWriter writes RTCP packets to the remote
+type RTCPWriter interface {
+	WriteRTCP(pkts []rtcp.Packet) error
+}
+
+// RTCPReader reads marshaled RTCP packets received for a stream
+type RTCPReader interface {
+	Read(b []byte) (int, error)
+}
+
+// RTPWriterFunc is an adapter for RTPWriter interface
+type RTPWriterFunc func(header *rtp.Header, payload []byte) (int, error)
+
+// WriteRTP calls f(header, payload)
+func (f RTPWriterFunc) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	return f(header, payload)
+}
+
+// RTPReaderFunc is an adapter for RTPReader interface
+type RTPReaderFunc func(b []byte) (int, error)
+
+// Read calls f(b)
+func (f RTPReaderFunc) Read(b []byte) (int, error) {
+	return f(b)
+}
+
+// RTCPWriterFunc is an adapter for RTCPWriter interface
+type RTCPWriterFunc func(pkts []rtcp.Packet) error
+
+// WriteRTCP calls f(pkts)
+func (f RTCPWriterFunc) WriteRTCP(pkts []rtcp.Packet) error {
+	return f(pkts)
+}
+
+// RTCPReaderFunc is an adapter for RTCPReader interface
+type RTCPReaderFunc func(b []byte) (int, error)
+
+// Read calls f(b)
+func (f RTCPReaderFunc) Read(b []byte) (int, error) {
+	return f(b)
+}
+
+// Interceptor can observe and modify the RTP and RTCP of every RTPSender, RTPReceiver and
+// DTLSTransport created by an API. Each Bind method returns the writer or reader that is
+// used instead of the one it is given, usually one that wraps it.
+// An Interceptor is shared by all the streams of the API, its methods may be called concurrently.
+type Interceptor interface {
+	// BindRTCPWriter is called once for every DTLSTransport, the writer is used for all the
+	// RTCP sent on the transport
+	BindRTCPWriter(writer RTCPWriter) RTCPWriter
+
+	// BindRTCPReader is called for every local and remote stream, the reader returns the RTCP
+	// read from the RTPSender or RTPReceiver of the stream
+	BindRTCPReader(info *StreamInfo, reader RTCPReader) RTCPReader
+
+	// BindLocalStream is called for every encoding of a RTPSender when Send is called
+	BindLocalStream(info *StreamInfo, writer RTPWriter) RTPWriter
+
+	// UnbindLocalStream is called when the RTPSender of the stream is stopped
+	UnbindLocalStream(info *StreamInfo)
+
+	// BindRemoteStream is called for every Track of a RTPReceiver once its codec is known.
+	// The packet read to determine the PayloadType isn't passed to the reader
+	BindRemoteStream(info *StreamInfo, reader RTPReader) RTPReader
+
+	// UnbindRemoteStream is called when the RTPReceiver of the stream is stopped
+	UnbindRemoteStream(info *StreamInfo)
+}
+
+// NoOpInterceptor is an Interceptor that doesn't modify anything, it can be embedded
+// to implement only some of the methods of Interceptor
+type NoOpInterceptor struct{}
+
+// BindRTCPWriter returns writer
+func (NoOpInterceptor) BindRTCPWriter(writer RTCPWriter) RTCPWriter {
+	return writer
+}
+
+// BindRTCPReader returns reader
+func (NoOpInterceptor) BindRTCPReader(info *StreamInfo, reader RTCPReader) RTCPReader {
+	return reader
+}
+
+// BindLocalStream returns writer
+func (NoOpInterceptor) BindLocalStream(info *StreamInfo, writer RTPWriter) RTPWriter {
+	return writer
+}
+
+// UnbindLocalStream does nothing
+func (NoOpInterceptor) UnbindLocalStream(info *StreamInfo) {}
+
+// BindRemoteStream returns reader
+func (NoOpInterceptor) BindRemoteStream(info *StreamInfo, reader RTPReader) RTPReader {
+	return reader
+}
+
+// UnbindRemoteStream does nothing
+func (NoOpInterceptor) UnbindRemoteStream(info *StreamInfo) {}
+
+// interceptorChain binds a list of Interceptors so that the first one is the closest
+// to the application: it sees outbound packets first and inbound packets last
+type interceptorChain []Interceptor
+
+func (c interceptorChain) BindRTCPWriter(writer RTCPWriter) RTCPWriter {
+	for i := len(c) - 1; i >= 0; i-- {
+		writer = c[i].BindRTCPWriter(writer)
+	}
+	return writer
+}
+
+func (c interceptorChain) BindRTCPReader(info *StreamInfo, reader RTCPReader) RTCPReader {
+	for i := len(c) - 1; i >= 0; i-- {
+		reader = c[i].BindRTCPReader(info, reader)
+	}
+	return reader
+}
+
+func (c interceptorChain) BindLocalStream(info *StreamInfo, writer RTPWriter) RTPWriter {
+	for i := len(c) - 1; i >= 0; i-- {
+		writer = c[i].BindLocalStream(info, writer)
+	}
+	return writer
+}
+
+func (c interceptorChain) UnbindLocalStream(info *StreamInfo) {
+	for _, interceptor := range c {
+		interceptor.UnbindLocalStream(info)
+	}
+}
+
+func (c interceptorChain) BindRemoteStream(info *StreamInfo, reader RTPReader) RTPReader {
+	for i := len(c) - 1; i >= 0; i-- {
+		reader = c[i].BindRemoteStream(info, reader)
+	}
+	return reader
+}
+
+func (c interceptorChain) UnbindRemoteStream(info *StreamInfo) {
+	for _, interceptor := range c {
+		interceptor.UnbindRemoteStream(info)
+	}
+}
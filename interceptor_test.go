@@ -0,0 +1,93 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+// orderInterceptor appends its name to calls for every packet that goes through it
+type orderInterceptor struct {
+	NoOpInterceptor
+	name  string
+	calls *[]string
+}
+
+func (o *orderInterceptor) BindRTCPWriter(writer RTCPWriter) RTCPWriter {
+	return RTCPWriterFunc(func(pkts []rtcp.Packet) error {
+		*o.calls = append(*o.calls, o.name)
+		return writer.WriteRTCP(pkts)
+	})
+}
+
+func (o *orderInterceptor) BindLocalStream(info *StreamInfo, writer RTPWriter) RTPWriter {
+	return RTPWriterFunc(func(header *rtp.Header, payload []byte) (int, error) {
+		*o.calls = append(*o.calls, o.name)
+		return writer.WriteRTP(header, payload)
+	})
+}
+
+func (o *orderInterceptor) BindRemoteStream(info *StreamInfo, reader RTPReader) RTPReader {
+	return RTPReaderFunc(func(b []byte) (int, error) {
+		n, err := reader.Read(b)
+		*o.calls = append(*o.calls, o.name)
+		return n, err
+	})
+}
+
+func (o *orderInterceptor) UnbindRemoteStream(info *StreamInfo) {
+	*o.calls = append(*o.calls, o.name)
+}
+
+func TestInterceptorChain(t *testing.T) {
+	calls := []string{}
+	chain := interceptorChain{
+		&orderInterceptor{name: "first", calls: &calls},
+		&orderInterceptor{name: "second", calls: &calls},
+	}
+	info := &StreamInfo{SSRC: 1234}
+
+	t.Run("Outbound", func(t *testing.T) {
+		calls = calls[:0]
+		writer := chain.BindLocalStream(info, RTPWriterFunc(func(header *rtp.Header, payload []byte) (int, error) {
+			calls = append(calls, "transport")
+			return len(payload), nil
+		}))
+
+		n, err := writer.WriteRTP(&rtp.Header{SSRC: 1234}, []byte{0x00, 0x01})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.Equal(t, []string{"first", "second", "transport"}, calls)
+
+		calls = calls[:0]
+		rtcpWriter := chain.BindRTCPWriter(RTCPWriterFunc(func([]rtcp.Packet) error {
+			calls = append(calls, "transport")
+			return nil
+		}))
+		assert.NoError(t, rtcpWriter.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: 1234}}))
+		assert.Equal(t, []string{"first", "second", "transport"}, calls)
+	})
+
+	t.Run("Inbound", func(t *testing.T) {
+		calls = calls[:0]
+		reader := chain.BindRemoteStream(info, RTPReaderFunc(func(b []byte) (int, error) {
+			calls = append(calls, "transport")
+			return copy(b, []byte{0x80}), nil
+		}))
+
+		n, err := reader.Read(make([]byte, 1))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, []string{"transport", "second", "first"}, calls)
+	})
+
+	t.Run("Unbind", func(t *testing.T) {
+		calls = calls[:0]
+		chain.UnbindRemoteStream(info)
+		assert.Equal(t, []string{"first", "second"}, calls)
+	})
+}
@@ -0,0 +1,52 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// Keyframe requests for a Track within this interval of the previous one are merged into it
+const keyframeRequestInterval = 500 * time.Millisecond
+
+// keyframeRequester builds the PLI or FIR packets that request a keyframe of a remote Track
+type keyframeRequester struct {
+	mu sync.Mutex
+
+	// Incremented with every FIR, the remote ignores a FIR with the sequence number of the previous one
+	firSequenceNumber uint8
+	lastRequest       time.Time
+}
+
+// request returns the packet requesting a keyframe of ssrc, or nil if a keyframe was requested
+// less than keyframeRequestInterval ago
+func (k *keyframeRequester) request(ssrc uint32, fir bool, now time.Time) rtcp.Packet {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if !k.lastRequest.IsZero() && now.Sub(k.lastRequest) < keyframeRequestInterval {
+		return nil
+	}
+	k.lastRequest = now
+
+	if !fir {
+		return &rtcp.PictureLossIndication{MediaSSRC: ssrc}
+	}
+
+	k.firSequenceNumber++
+	return &rtcp.FullIntraRequest{
+		MediaSSRC: ssrc,
+		FIR:       []rtcp.FIREntry{{SSRC: ssrc, SequenceNumber: k.firSequenceNumber}},
+	}
+}
+
+// useFIR tells if keyframes of codec are requested with FIR, this is only the case if the
+// ccm fir RTCPFeedback was negotiated without nack pli
+func useFIR(codec *RTPCodec) bool {
+	return codec != nil &&
+		hasRTCPFeedback(codec.RTCPFeedback, TypeRTCPFBCCM, "fir") &&
+		!hasRTCPFeedback(codec.RTCPFeedback, TypeRTCPFBNACK, "pli")
+}
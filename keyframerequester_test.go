@@ -0,0 +1,40 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyframeRequester(t *testing.T) {
+	k := &keyframeRequester{}
+	now := time.Now()
+
+	assert.Equal(t, &rtcp.PictureLossIndication{MediaSSRC: 1234}, k.request(1234, false, now))
+	assert.Nil(t, k.request(1234, false, now.Add(keyframeRequestInterval/2)), "requests are merged")
+
+	now = now.Add(keyframeRequestInterval)
+	assert.Equal(t, &rtcp.FullIntraRequest{
+		MediaSSRC: 1234,
+		FIR:       []rtcp.FIREntry{{SSRC: 1234, SequenceNumber: 1}},
+	}, k.request(1234, true, now))
+
+	now = now.Add(keyframeRequestInterval)
+	fir, ok := k.request(1234, true, now).(*rtcp.FullIntraRequest)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(2), fir.FIR[0].SequenceNumber)
+}
+
+func TestUseFIR(t *testing.T) {
+	fir := RTCPFeedback{Type: TypeRTCPFBCCM, Parameter: "fir"}
+	pli := RTCPFeedback{Type: TypeRTCPFBNACK, Parameter: "pli"}
+
+	assert.False(t, useFIR(nil))
+	assert.False(t, useFIR(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)))
+	assert.True(t, useFIR(NewRTPVP8CodecExt(DefaultPayloadTypeVP8, 90000, []RTCPFeedback{fir}, "")))
+	assert.False(t, useFIR(NewRTPVP8CodecExt(DefaultPayloadTypeVP8, 90000, []RTCPFeedback{fir, pli}, "")))
+}
@@ -0,0 +1,433 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// maxVideoLayers is the number of spatial and temporal layers a VP9 payload descriptor can signal
+	maxVideoLayers = 8
+
+	// layerBitrateInterval is how often the bitrates of the layers of a source are measured, a
+	// source without packets for as long is no longer selected
+	layerBitrateInterval = time.Second
+)
+
+// LayerSelector forwards a VP8 or VP9 video received as Simulcast streams, with temporal or
+// spatial layers, to a local Track. It keeps the stream and layers that fit the target bitrate
+// of the subscriber, as given by SetTargetBitrate which is usually fed from the
+// OnTargetBitrateChange of the PeerConnection of the subscriber. Higher temporal and spatial
+// layers are dropped, and the Simulcast stream is switched, as are VP9 spatial layers going up,
+// only on a keyframe of the new one. Packets are written through a Forwarder so the Track is a
+// single continuous stream.
+type LayerSelector struct {
+	mu        sync.Mutex
+	forwarder *Forwarder
+	vp9       bool
+
+	streams       []*layerSelectorStream
+	targetBitrate uint64
+
+	// The stream being forwarded and the highest layers forwarded of it
+	current                     *layerSelectorStream
+	spatialLayer, temporalLayer uint8
+
+	// The stream and layers to switch to once possible
+	target                                  *layerSelectorStream
+	targetSpatialLayer, targetTemporalLayer uint8
+
+	lastKeyframeRequest   time.Time
+	onKeyframeRequestHdlr func(*Track)
+}
+
+// layerSelectorStream measures the bitrates of the layers of a source
+type layerSelectorStream struct {
+	source *Track
+	ssrc   uint32
+
+	// Indexed by spatial and then temporal layer
+	bytes, bitrates [maxVideoLayers][maxVideoLayers]uint64
+	measureStart    time.Time
+	measured        bool
+	lastPacket      time.Time
+
+	maxSpatialLayer, maxTemporalLayer uint8
+}
+
+// videoLayerInfo is what a LayerSelector needs of the payload descriptor of a packet
+type videoLayerInfo struct {
+	spatialLayer, temporalLayer uint8
+
+	// pictureStart is set on the first packet of a picture, frameEnd on the last packet of a
+	// VP9 layer frame
+	pictureStart, frameEnd bool
+	keyframe               bool
+}
+
+// NewLayerSelector creates a LayerSelector writing to track, which must be a local VP8 or VP9 Track
+func NewLayerSelector(track *Track) (*LayerSelector, error) {
+	forwarder, err := NewForwarder(track)
+	if err != nil {
+		return nil, err
+	}
+
+	codec := track.Codec()
+	if codec == nil || (!strings.EqualFold(codec.Name, VP8) && !strings.EqualFold(codec.Name, VP9)) {
+		return nil, fmt.Errorf("LayerSelector requires a VP8 or VP9 track")
+	}
+
+	return &LayerSelector{
+		forwarder:           forwarder,
+		vp9:                 strings.EqualFold(codec.Name, VP9),
+		spatialLayer:        maxVideoLayers - 1,
+		temporalLayer:       maxVideoLayers - 1,
+		targetSpatialLayer:  maxVideoLayers - 1,
+		targetTemporalLayer: maxVideoLayers - 1,
+	}, nil
+}
+
+// Track returns the Track the LayerSelector writes to
+func (s *LayerSelector) Track() *Track {
+	return s.forwarder.Track()
+}
+
+// OnKeyframeRequest sets an event handler which is called when a keyframe of source is needed
+// to switch to it, as with RTPReceiver.RequestKeyframeSimulcast(source.RID())
+func (s *LayerSelector) OnKeyframeRequest(f func(source *Track)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onKeyframeRequestHdlr = f
+}
+
+// SetTargetBitrate sets the bitrate in bits per second the forwarded layers must fit in,
+// 0 forwards the highest ones
+func (s *LayerSelector) SetTargetBitrate(bitrate uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.targetBitrate = bitrate
+	s.selectLayers()
+}
+
+// Layer returns the source being forwarded and its highest spatial and temporal layers
+// forwarded, source is nil until a keyframe was forwarded
+func (s *LayerSelector) Layer() (source *Track, spatialLayer, temporalLayer uint8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		return nil, 0, 0
+	}
+
+	spatialLayer, temporalLayer = s.spatialLayer, s.temporalLayer
+	if spatialLayer > s.current.maxSpatialLayer {
+		spatialLayer = s.current.maxSpatialLayer
+	}
+	if temporalLayer > s.current.maxTemporalLayer {
+		temporalLayer = s.current.maxTemporalLayer
+	}
+	return s.current.source, spatialLayer, temporalLayer
+}
+
+// WriteRTP forwards a RTP packet of source, a remote Track of one of the Simulcast streams, if
+// its stream and layers are selected. p isn't modified
+func (s *LayerSelector) WriteRTP(source *Track, p *rtp.Packet) error {
+	s.mu.Lock()
+	out, keyframeSource := s.write(source, p, time.Now())
+
+	// The lock is held while writing so packets are sent in the order they were rewritten
+	var err error
+	if out != nil {
+		err = s.forwarder.track.WriteRTP(out)
+	}
+	onKeyframeRequestHdlr := s.onKeyframeRequestHdlr
+	s.mu.Unlock()
+
+	if keyframeSource != nil && onKeyframeRequestHdlr != nil {
+		onKeyframeRequestHdlr(keyframeSource)
+	}
+	return err
+}
+
+// write returns the rewritten packet to forward, nil if it is dropped, and the source a
+// keyframe should be requested from
+func (s *LayerSelector) write(source *Track, p *rtp.Packet, now time.Time) (*rtp.Packet, *Track) {
+	var info videoLayerInfo
+	var ok bool
+	if s.vp9 {
+		info, ok = parseVP9LayerInfo(p.Payload)
+	} else {
+		info, ok = parseVP8LayerInfo(p.Payload)
+	}
+
+	stream := s.stream(source)
+	measured := stream.count(info, p.Header.MarshalSize()+len(p.Payload), now)
+	for _, other := range s.streams {
+		if other.expire(now) {
+			measured = true
+		}
+	}
+	if measured {
+		s.selectLayers()
+	}
+
+	switch {
+	case !ok:
+		if stream == s.current {
+			s.forwarder.skip(p)
+		}
+		return nil, s.keyframeRequest(now)
+	case stream != s.current:
+		// Until layers are selected the first stream with a keyframe is forwarded
+		if !info.keyframe || (stream != s.target && (s.target != nil || s.current != nil)) {
+			return nil, s.keyframeRequest(now)
+		}
+		s.current, s.target = stream, stream
+		s.spatialLayer, s.temporalLayer = s.targetSpatialLayer, s.targetTemporalLayer
+	case info.pictureStart:
+		// Lower spatial layers never depend on higher ones, so they can be dropped at any picture
+		if s.targetSpatialLayer < s.spatialLayer || info.keyframe {
+			s.spatialLayer = s.targetSpatialLayer
+		}
+		if info.temporalLayer == 0 {
+			s.temporalLayer = s.targetTemporalLayer
+		}
+	}
+
+	if info.spatialLayer > s.spatialLayer || info.temporalLayer > s.temporalLayer {
+		s.forwarder.skip(p)
+		return nil, s.keyframeRequest(now)
+	}
+
+	// The marker ends a VP9 picture, it is on its highest spatial layer which may be dropped
+	if s.vp9 && info.frameEnd && info.spatialLayer == s.spatialLayer && !p.Marker {
+		marked := *p
+		marked.Marker = true
+		p = &marked
+	}
+	return s.forwarder.rewrite(p, now), s.keyframeRequest(now)
+}
+
+// stream returns the layerSelectorStream of source, adding it if it is new
+func (s *LayerSelector) stream(source *Track) *layerSelectorStream {
+	ssrc := source.SSRC()
+	for _, stream := range s.streams {
+		if stream.ssrc == ssrc {
+			return stream
+		}
+	}
+
+	stream := &layerSelectorStream{source: source, ssrc: ssrc}
+	s.streams = append(s.streams, stream)
+	return stream
+}
+
+// selectLayers sets the target to the stream and layers with the highest bitrate that fits
+// the target bitrate, or the lowest ones if none does
+func (s *LayerSelector) selectLayers() {
+	var best, lowest *layerSelectorStream
+	var bestSpatialLayer, bestTemporalLayer, lowestSpatialLayer, lowestTemporalLayer uint8
+	var bestBitrate, lowestBitrate uint64
+
+	for _, stream := range s.streams {
+		if !stream.measured {
+			continue
+		}
+
+		for spatialLayer := uint8(0); spatialLayer <= stream.maxSpatialLayer; spatialLayer++ {
+			for temporalLayer := uint8(0); temporalLayer <= stream.maxTemporalLayer; temporalLayer++ {
+				bitrate := stream.bitrate(spatialLayer, temporalLayer)
+				if lowest == nil || bitrate < lowestBitrate {
+					lowest, lowestSpatialLayer, lowestTemporalLayer, lowestBitrate = stream, spatialLayer, temporalLayer, bitrate
+				}
+
+				if s.targetBitrate != 0 && bitrate > s.targetBitrate {
+					continue
+				}
+				if best == nil || bitrate > bestBitrate {
+					best, bestSpatialLayer, bestTemporalLayer, bestBitrate = stream, spatialLayer, temporalLayer, bitrate
+				}
+			}
+		}
+	}
+
+	if best == nil {
+		best, bestSpatialLayer, bestTemporalLayer = lowest, lowestSpatialLayer, lowestTemporalLayer
+	}
+	if best == nil {
+		return
+	}
+	s.target, s.targetSpatialLayer, s.targetTemporalLayer = best, bestSpatialLayer, bestTemporalLayer
+}
+
+// keyframeRequest returns the source a keyframe is needed from to complete a switch, at most
+// once every keyframeRequestInterval
+func (s *LayerSelector) keyframeRequest(now time.Time) *Track {
+	var stream *layerSelectorStream
+	switch {
+	case s.target != nil && s.target != s.current:
+		stream = s.target
+	case s.current != nil && s.targetSpatialLayer > s.spatialLayer:
+		stream = s.current
+	default:
+		return nil
+	}
+
+	if now.Sub(s.lastKeyframeRequest) < keyframeRequestInterval {
+		return nil
+	}
+	s.lastKeyframeRequest = now
+	return stream.source
+}
+
+// count accounts for a packet of size bytes, it returns true when the bitrates were measured again
+func (l *layerSelectorStream) count(info videoLayerInfo, size int, now time.Time) bool {
+	l.lastPacket = now
+	l.bytes[info.spatialLayer][info.temporalLayer] += uint64(size)
+	if info.spatialLayer > l.maxSpatialLayer {
+		l.maxSpatialLayer = info.spatialLayer
+	}
+	if info.temporalLayer > l.maxTemporalLayer {
+		l.maxTemporalLayer = info.temporalLayer
+	}
+
+	if l.measureStart.IsZero() {
+		l.measureStart = now
+		return false
+	}
+
+	elapsed := now.Sub(l.measureStart)
+	if elapsed < layerBitrateInterval {
+		return false
+	}
+
+	for i := range l.bytes {
+		for j := range l.bytes[i] {
+			l.bitrates[i][j] = uint64(float64(l.bytes[i][j]*8) / elapsed.Seconds())
+			l.bytes[i][j] = 0
+		}
+	}
+	l.measureStart, l.measured = now, true
+	return true
+}
+
+// expire forgets the bitrates of a source without packets for layerBitrateInterval, they are
+// measured again once it resumes. It returns true if the bitrates were forgotten
+func (l *layerSelectorStream) expire(now time.Time) bool {
+	if !l.measured || now.Sub(l.lastPacket) < layerBitrateInterval {
+		return false
+	}
+
+	l.bytes, l.bitrates = [maxVideoLayers][maxVideoLayers]uint64{}, [maxVideoLayers][maxVideoLayers]uint64{}
+	l.measureStart, l.measured = time.Time{}, false
+	return true
+}
+
+// bitrate returns the bitrate of the layers up to spatialLayer and temporalLayer
+func (l *layerSelectorStream) bitrate(spatialLayer, temporalLayer uint8) uint64 {
+	bitrate := uint64(0)
+	for i := uint8(0); i <= spatialLayer; i++ {
+		for j := uint8(0); j <= temporalLayer; j++ {
+			bitrate += l.bitrates[i][j]
+		}
+	}
+	return bitrate
+}
+
+// parseVP8LayerInfo reads the VP8 payload descriptor of RFC 7741 section 4.2 and the keyframe
+// bit of the VP8 payload header that follows it
+func parseVP8LayerInfo(payload []byte) (info videoLayerInfo, ok bool) {
+	if len(payload) < 1 {
+		return info, false
+	}
+
+	i := 1
+	if payload[0]&0x80 != 0 {
+		if len(payload) < 2 {
+			return info, false
+		}
+
+		i = 2
+		if payload[1]&0x80 != 0 {
+			if len(payload) <= i {
+				return info, false
+			}
+			if payload[i]&0x80 != 0 {
+				i += 2
+			} else {
+				i++
+			}
+		}
+		if payload[1]&0x40 != 0 {
+			i++
+		}
+		if payload[1]&0x30 != 0 {
+			if len(payload) <= i {
+				return info, false
+			}
+			if payload[1]&0x20 != 0 {
+				info.temporalLayer = payload[i] >> 6
+			}
+			i++
+		}
+	}
+
+	if len(payload) <= i {
+		return info, false
+	}
+
+	// The payload header is only in the first partition of a frame, a 0 P bit marks a keyframe
+	info.pictureStart = payload[0]&0x10 != 0 && payload[0]&0x07 == 0
+	info.keyframe = info.pictureStart && payload[i]&0x01 == 0
+	return info, true
+}
+
+// parseVP9LayerInfo reads the VP9 payload descriptor of draft-ietf-payload-vp9 section 4.2
+func parseVP9LayerInfo(payload []byte) (info videoLayerInfo, ok bool) {
+	if len(payload) < 1 {
+		return info, false
+	}
+
+	i := 1
+	if payload[0]&0x80 != 0 {
+		if len(payload) <= i {
+			return info, false
+		}
+		if payload[i]&0x80 != 0 {
+			i += 2
+		} else {
+			i++
+		}
+	}
+
+	if payload[0]&0x20 != 0 {
+		if len(payload) <= i {
+			return info, false
+		}
+		info.temporalLayer = payload[i] >> 5
+		info.spatialLayer = (payload[i] >> 1) & 0x07
+		i++
+
+		// TL0PICIDX is present in non-flexible mode
+		if payload[0]&0x10 == 0 {
+			i++
+		}
+	}
+
+	if len(payload) < i {
+		return info, false
+	}
+
+	beginning, interPicturePredicted := payload[0]&0x08 != 0, payload[0]&0x40 != 0
+	info.pictureStart = beginning && info.spatialLayer == 0
+	info.frameEnd = payload[0]&0x04 != 0
+	info.keyframe = info.pictureStart && !interPicturePredicted
+	return info, true
+}
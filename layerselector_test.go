@@ -0,0 +1,213 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayerSelector_VP8(t *testing.T) {
+	track, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+	s, err := NewLayerSelector(track)
+	assert.NoError(t, err)
+	assert.Equal(t, track, s.Track())
+
+	low, err := NewTrackWithRID(DefaultPayloadTypeVP8, 1, "video", "pion", "l", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+	high, err := NewTrackWithRID(DefaultPayloadTypeVP8, 2, "video", "pion", "h", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+
+	// X, S and T are set, the packets are 100 bytes for the low stream and 1000 for the high one
+	vp8Packet := func(source *Track, sequenceNumber uint16, temporalLayer uint8, keyframe bool) *rtp.Packet {
+		size := 100
+		if source == high {
+			size = 1000
+		}
+		payload := make([]byte, size-12)
+		payload[0], payload[1], payload[2], payload[3] = 0x90, 0x20, temporalLayer<<6, 0x01
+		if keyframe {
+			payload[3] = 0x00
+		}
+		return &rtp.Packet{Header: rtp.Header{Version: 2, SSRC: source.SSRC(), SequenceNumber: sequenceNumber}, Payload: payload}
+	}
+	write := func(source *Track, sequenceNumber uint16, temporalLayer uint8, keyframe bool, now time.Time) (*rtp.Packet, *Track) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.write(source, vp8Packet(source, sequenceNumber, temporalLayer, keyframe), now)
+	}
+	assertWritten := func(sequenceNumber uint16) func(*rtp.Packet, *Track) {
+		return func(p *rtp.Packet, keyframeSource *Track) {
+			if assert.NotNil(t, p) {
+				assert.Equal(t, uint32(1234), p.SSRC)
+				assert.Equal(t, sequenceNumber, p.SequenceNumber)
+			}
+			assert.Nil(t, keyframeSource)
+		}
+	}
+	assertDropped := func(keyframeSource *Track) func(*rtp.Packet, *Track) {
+		return func(p *rtp.Packet, requested *Track) {
+			assert.Nil(t, p)
+			assert.Equal(t, keyframeSource, requested)
+		}
+	}
+
+	// Until the bitrates are measured the first stream with a keyframe is forwarded
+	start := time.Now()
+	assertDropped(nil)(write(high, 1, 0, false, start))
+	assertWritten(1)(write(low, 1, 0, true, start))
+	source, _, _ := s.Layer()
+	assert.Equal(t, low, source)
+	assertWritten(2)(write(low, 2, 1, false, start.Add(500*time.Millisecond)))
+	assertDropped(nil)(write(high, 2, 1, false, start.Add(500*time.Millisecond)))
+
+	// With the low stream measured at 1600 and 2400 bps, and the high one at 16000 and 24000 bps,
+	// the high stream is selected and a keyframe requested from it
+	assertWritten(3)(write(low, 3, 0, false, start.Add(time.Second)))
+	assertDropped(high)(write(high, 3, 0, false, start.Add(time.Second)))
+	assertWritten(4)(write(high, 4, 0, true, start.Add(1100*time.Millisecond)))
+	source, spatialLayer, temporalLayer := s.Layer()
+	assert.Equal(t, high, source)
+	assert.Equal(t, uint8(0), spatialLayer)
+	assert.Equal(t, uint8(1), temporalLayer)
+
+	// The higher temporal layer is dropped from the next base layer picture
+	s.SetTargetBitrate(20000)
+	assertWritten(5)(write(high, 5, 1, false, start.Add(1100*time.Millisecond)))
+	assertWritten(6)(write(high, 6, 0, false, start.Add(1100*time.Millisecond)))
+	assertDropped(nil)(write(high, 7, 1, false, start.Add(1100*time.Millisecond)))
+	assertWritten(7)(write(high, 8, 0, false, start.Add(1100*time.Millisecond)))
+	_, _, temporalLayer = s.Layer()
+	assert.Equal(t, uint8(0), temporalLayer)
+
+	// The high stream is forwarded until the low one has a keyframe
+	s.SetTargetBitrate(2000)
+	assertWritten(8)(write(high, 9, 0, false, start.Add(1200*time.Millisecond)))
+	assertDropped(low)(write(low, 4, 0, false, start.Add(1700*time.Millisecond)))
+	assertWritten(9)(write(low, 5, 0, true, start.Add(1700*time.Millisecond)))
+	assertDropped(nil)(write(low, 6, 1, false, start.Add(1700*time.Millisecond)))
+	assertDropped(nil)(write(high, 10, 0, true, start.Add(1700*time.Millisecond)))
+	source, _, temporalLayer = s.Layer()
+	assert.Equal(t, low, source)
+	assert.Equal(t, uint8(0), temporalLayer)
+
+	// The high stream is selected again, until it has no packets for a second
+	s.SetTargetBitrate(0)
+	p, keyframeSource := write(low, 7, 0, false, start.Add(2600*time.Millisecond))
+	assert.NotNil(t, p)
+	assert.Equal(t, high, keyframeSource)
+	assertWritten(11)(write(low, 8, 0, false, start.Add(3200*time.Millisecond)))
+	assertWritten(12)(write(low, 9, 1, false, start.Add(3200*time.Millisecond)))
+	source, _, temporalLayer = s.Layer()
+	assert.Equal(t, low, source)
+	assert.Equal(t, uint8(1), temporalLayer)
+}
+
+func TestLayerSelector_VP9(t *testing.T) {
+	track, err := NewTrack(DefaultPayloadTypeVP9, 1234, "video", "pion", NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000))
+	assert.NoError(t, err)
+	s, err := NewLayerSelector(track)
+	assert.NoError(t, err)
+	source, err := NewTrack(DefaultPayloadTypeVP9, 1, "video", "pion", NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000))
+	assert.NoError(t, err)
+
+	// L, B and E are set, every layer frame is a single packet of 100 bytes for the spatial
+	// layer 0 and 300 for the spatial layer 1, which ends the picture
+	write := func(sequenceNumber uint16, spatialLayer uint8, keyframe bool, now time.Time) (*rtp.Packet, *Track) {
+		size := 100 + 200*int(spatialLayer)
+		payload := make([]byte, size-12)
+		payload[0], payload[1] = 0x2C, spatialLayer<<1
+		if !keyframe {
+			payload[0] |= 0x40
+		}
+		p := &rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 1, SequenceNumber: sequenceNumber, Marker: spatialLayer == 1}, Payload: payload}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.write(source, p, now)
+	}
+	assertWritten := func(sequenceNumber uint16, marker bool) func(*rtp.Packet, *Track) {
+		return func(p *rtp.Packet, keyframeSource *Track) {
+			if assert.NotNil(t, p) {
+				assert.Equal(t, sequenceNumber, p.SequenceNumber)
+				assert.Equal(t, marker, p.Marker)
+			}
+			assert.Nil(t, keyframeSource)
+		}
+	}
+
+	s.SetTargetBitrate(3000)
+	start := time.Now()
+	assertWritten(1, false)(write(1, 0, true, start))
+	assertWritten(2, true)(write(2, 1, true, start))
+
+	// With the spatial layer 1 measured at 2400 bps on top of 1600 bps, only the spatial layer 0
+	// fits and ends the pictures
+	assertWritten(3, true)(write(3, 0, false, start.Add(time.Second)))
+	assertWritten(4, true)(write(4, 0, false, start.Add(time.Second)))
+	assertDropped := func(p *rtp.Packet, _ *Track) { assert.Nil(t, p) }
+	assertDropped(write(5, 1, false, start.Add(time.Second)))
+	_, spatialLayer, _ := s.Layer()
+	assert.Equal(t, uint8(0), spatialLayer)
+
+	// Going up a spatial layer waits for a keyframe
+	s.SetTargetBitrate(0)
+	p, keyframeSource := write(6, 0, false, start.Add(time.Second))
+	assert.NotNil(t, p)
+	assert.Equal(t, source, keyframeSource)
+	assertDropped(write(7, 1, false, start.Add(time.Second)))
+	assertWritten(6, false)(write(8, 0, true, start.Add(time.Second)))
+	assertWritten(7, true)(write(9, 1, true, start.Add(time.Second)))
+	_, spatialLayer, _ = s.Layer()
+	assert.Equal(t, uint8(1), spatialLayer)
+}
+
+func TestNewLayerSelector(t *testing.T) {
+	track, err := NewTrack(DefaultPayloadTypeOpus, 1234, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	assert.NoError(t, err)
+	_, err = NewLayerSelector(track)
+	assert.Error(t, err)
+
+	_, err = NewLayerSelector(nil)
+	assert.Error(t, err)
+}
+
+func TestParseLayerInfo(t *testing.T) {
+	for _, test := range []struct {
+		vp9     bool
+		payload []byte
+		info    videoLayerInfo
+		ok      bool
+	}{
+		{false, []byte{}, videoLayerInfo{}, false},
+		{false, []byte{0x10, 0x00}, videoLayerInfo{pictureStart: true, keyframe: true}, true},
+		{false, []byte{0x10, 0x01}, videoLayerInfo{pictureStart: true}, true},
+		{false, []byte{0x00, 0x00}, videoLayerInfo{}, true},
+		// A 15 bit PictureID, TL0PICIDX, TID and KEYIDX
+		{false, []byte{0x90, 0xF0, 0x81, 0x02, 0x03, 0x80, 0x01}, videoLayerInfo{temporalLayer: 2, pictureStart: true}, true},
+		{false, []byte{0x90, 0xF0, 0x81, 0x02, 0x03, 0x80}, videoLayerInfo{temporalLayer: 2}, false},
+		{false, []byte{0x90, 0x80}, videoLayerInfo{}, false},
+		{true, []byte{}, videoLayerInfo{}, false},
+		// A 7 bit PictureID and the layer indices with TL0PICIDX
+		{true, []byte{0xAC, 0x01, 0x45, 0x02, 0xAA}, videoLayerInfo{temporalLayer: 2, spatialLayer: 2, frameEnd: true}, true},
+		{true, []byte{0x2C, 0x00, 0x02, 0xAA}, videoLayerInfo{pictureStart: true, frameEnd: true, keyframe: true}, true},
+		{true, []byte{0x38, 0x20}, videoLayerInfo{temporalLayer: 1, pictureStart: true, keyframe: true}, true},
+		{true, []byte{0x20}, videoLayerInfo{}, false},
+	} {
+		var info videoLayerInfo
+		var ok bool
+		if test.vp9 {
+			info, ok = parseVP9LayerInfo(test.payload)
+		} else {
+			info, ok = parseVP8LayerInfo(test.payload)
+		}
+		assert.Equal(t, test.ok, ok, "%x", test.payload)
+		if ok {
+			assert.Equal(t, test.info, info, "%x", test.payload)
+		}
+	}
+}
@@ -22,49 +22,249 @@ const (
 	DefaultPayloadTypeVP9  = 98
 	DefaultPayloadTypeH264 = 102
 
+	// telephone-event is registered with the clock rates of the default audio codecs
+	DefaultPayloadTypeTelephoneEvent8k  = 126
+	DefaultPayloadTypeTelephoneEvent48k = 110
+
 	mediaNameAudio = "audio"
 	mediaNameVideo = "video"
+
+	// RFC 8285 one-byte header extensions can use the IDs 1-14
+	headerExtensionIDMin = 1
+	headerExtensionIDMax = 14
+
+	// Range of the dynamic PayloadTypes given to codecs registered without one
+	dynamicPayloadTypeMin = 96
+	dynamicPayloadTypeMax = 127
 )
 
+// defaultHeaderExtensionIDs are the IDs used when offering well known RTP header extensions.
+// Any other extension is given the lowest ID not used by these
+var defaultHeaderExtensionIDs = map[string]int{
+	sdp.ABSSendTimeURI:     sdp.DefExtMapValueABSSendTime,
+	sdp.TransportCCURI:     sdp.DefExtMapValueTransportCC,
+	sdp.SDESMidURI:         sdp.DefExtMapValueSDESMid,
+	sdp.SDESRTPStreamIDURI: sdp.DefExtMapValueSDESRTPStreamID,
+}
+
 // A MediaEngine defines the codecs supported by a PeerConnection.
 // MediaEngines populated using RegisterCodec (and RegisterDefaultCodecs)
 // may be set up once and reused, including concurrently,
 // as long as no other codecs are added subsequently.
 // MediaEngines populated using PopulateFromSDP should be used
 // only for that session.
+// Every PeerConnection uses a copy of the MediaEngine of its API taken when it is
+// created, codecs added to the MediaEngine later are not used by it.
 type MediaEngine struct {
-	codecs []*RTPCodec
+	codecs           []*RTPCodec
+	headerExtensions []mediaEngineHeaderExtension
+
+	// Factories registered by mime type, lower cased
+	payloaders    map[string]PayloaderFactory
+	depacketizers map[string]DepacketizerFactory
+
+	// PayloadTypes of the codecs already negotiated, these keep their PayloadType
+	negotiatedPayloadTypes map[uint8]bool
 }
 
-// RegisterCodec adds codec to m.
+// mediaEngineHeaderExtension is a RTP header extension registered with a MediaEngine
+type mediaEngineHeaderExtension struct {
+	uri               string
+	id                int
+	isAudio, isVideo  bool
+	allowedDirections []RTPTransceiverDirection
+}
+
+// RegisterCodec adds codec to m and returns its PayloadType. A codec registered with a
+// PayloadType of 0 is given the lowest free PayloadType of the dynamic range 96-127,
+// except PCMU whose static PayloadType is 0.
+// ErrPayloadTypeInUse is returned if another codec has the PayloadType. Registering a
+// codec again only adds its RTCPFeedback. codec itself is left unchanged.
+// The PeerConnections already created with m don't use codec.
 // RegisterCodec is not safe for concurrent use.
-func (m *MediaEngine) RegisterCodec(codec *RTPCodec) uint8 {
-	// TODO: dynamically generate a payload type in the range 96-127 if one wasn't provided.
-	// See https://github.com/pion/webrtc/issues/43
+func (m *MediaEngine) RegisterCodec(codec *RTPCodec) (uint8, error) {
+	// Codecs are copied before they are changed, they may be shared with other MediaEngines
+	if codec.PayloadType == 0 && !strings.EqualFold(codec.Name, PCMU) {
+		payloadType, err := m.nextPayloadType()
+		if err != nil {
+			return 0, err
+		}
+
+		assigned := *codec
+		assigned.PayloadType = payloadType
+		codec = &assigned
+	} else {
+		for i, existing := range m.codecs {
+			if existing.PayloadType != codec.PayloadType {
+				continue
+			} else if !existing.matches(codec) {
+				return 0, ErrPayloadTypeInUse
+			}
+
+			updated := *existing
+			updated.RTCPFeedback = append([]RTCPFeedback{}, existing.RTCPFeedback...)
+			for _, feedback := range codec.RTCPFeedback {
+				if !hasRTCPFeedback(updated.RTCPFeedback, feedback.Type, feedback.Parameter) {
+					updated.RTCPFeedback = append(updated.RTCPFeedback, feedback)
+				}
+			}
+			m.codecs[i] = &updated
+			return codec.PayloadType, nil
+		}
+	}
+
 	m.codecs = append(m.codecs, codec)
-	return codec.PayloadType
+	return codec.PayloadType, nil
+}
+
+// nextPayloadType returns the lowest PayloadType of the dynamic range that isn't used
+func (m *MediaEngine) nextPayloadType() (uint8, error) {
+	for payloadType := dynamicPayloadTypeMin; payloadType <= dynamicPayloadTypeMax; payloadType++ {
+		if _, err := m.getCodec(uint8(payloadType)); err != nil {
+			return uint8(payloadType), nil
+		}
+	}
+	return 0, ErrPayloadTypesExhausted
+}
+
+// RegisterHeaderExtension adds a RFC 8285 RTP header extension to m for codecs of kind kind.
+// The extension is only negotiated for RTPTransceivers with one of allowedDirections, or any
+// direction if none are given. The negotiated IDs are available from RTPSender.GetParameters
+// and RTPReceiver.GetParameters.
+// RegisterHeaderExtension is not safe for concurrent use.
+func (m *MediaEngine) RegisterHeaderExtension(uri string, kind RTPCodecType, allowedDirections ...RTPTransceiverDirection) error {
+	if kind != RTPCodecTypeAudio && kind != RTPCodecTypeVideo {
+		return ErrUnknownType
+	}
+
+	var extension *mediaEngineHeaderExtension
+	for i := range m.headerExtensions {
+		if m.headerExtensions[i].uri == uri {
+			extension = &m.headerExtensions[i]
+			break
+		}
+	}
+
+	if extension == nil {
+		id, err := m.nextHeaderExtensionID(uri)
+		if err != nil {
+			return err
+		}
+
+		m.headerExtensions = append(m.headerExtensions, mediaEngineHeaderExtension{uri: uri, id: id})
+		extension = &m.headerExtensions[len(m.headerExtensions)-1]
+	}
+
+	if kind == RTPCodecTypeAudio {
+		extension.isAudio = true
+	} else {
+		extension.isVideo = true
+	}
+	extension.allowedDirections = append(extension.allowedDirections, allowedDirections...)
+
+	return nil
+}
+
+// nextHeaderExtensionID returns the ID to offer a newly registered RTP header extension with
+func (m *MediaEngine) nextHeaderExtensionID(uri string) (int, error) {
+	if id, ok := defaultHeaderExtensionIDs[uri]; ok {
+		return id, nil
+	}
+
+	usedIDs := map[int]bool{}
+	for _, id := range defaultHeaderExtensionIDs {
+		usedIDs[id] = true
+	}
+	for _, e := range m.headerExtensions {
+		usedIDs[e.id] = true
+	}
+
+	for id := headerExtensionIDMin; id <= headerExtensionIDMax; id++ {
+		if !usedIDs[id] {
+			return id, nil
+		}
+	}
+	return 0, ErrHeaderExtensionIDsExhausted
+}
+
+// getHeaderExtensionID returns the ID a RTP header extension is offered with
+func (m *MediaEngine) getHeaderExtensionID(uri string) int {
+	for _, e := range m.headerExtensions {
+		if e.uri == uri {
+			return e.id
+		}
+	}
+	return defaultHeaderExtensionIDs[uri]
+}
+
+// getHeaderExtensionURIs returns the RTP header extensions to negotiate for a RTPTransceiver
+// of kind kind with direction direction. transport-cc is negotiated if any codec uses it
+func (m *MediaEngine) getHeaderExtensionURIs(kind RTPCodecType, direction RTPTransceiverDirection) []string {
+	uris := []string{}
+	for _, e := range m.headerExtensions {
+		if (kind == RTPCodecTypeAudio && !e.isAudio) || (kind == RTPCodecTypeVideo && !e.isVideo) {
+			continue
+		}
+
+		allowed := len(e.allowedDirections) == 0
+		for _, d := range e.allowedDirections {
+			if d == direction {
+				allowed = true
+				break
+			}
+		}
+		if allowed {
+			uris = append(uris, e.uri)
+		}
+	}
+
+	for _, codec := range m.GetCodecsByKind(kind) {
+		for _, feedback := range codec.RTCPFeedback {
+			if feedback.Type != TypeRTCPFBTransportCC {
+				continue
+			}
+
+			for _, uri := range uris {
+				if uri == sdp.TransportCCURI {
+					return uris
+				}
+			}
+			return append(uris, sdp.TransportCCURI)
+		}
+	}
+
+	return uris
 }
 
 // RegisterDefaultCodecs registers the default codecs supported by Pion WebRTC.
+// Default codecs whose PayloadType is used by another codec are skipped.
 // RegisterDefaultCodecs is not safe for concurrent use.
 func (m *MediaEngine) RegisterDefaultCodecs() {
-	// Audio Codecs in descending order of preference
-	m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
-	m.RegisterCodec(NewRTPPCMUCodec(DefaultPayloadTypePCMU, 8000))
-	m.RegisterCodec(NewRTPPCMACodec(DefaultPayloadTypePCMA, 8000))
-	m.RegisterCodec(NewRTPG722Codec(DefaultPayloadTypeG722, 8000))
-
-	// Video Codecs in descending order of preference
-	m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
-	m.RegisterCodec(NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000))
-	m.RegisterCodec(NewRTPH264Codec(DefaultPayloadTypeH264, 90000))
+	for _, codec := range []*RTPCodec{
+		// Audio Codecs in descending order of preference
+		NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000),
+		NewRTPPCMUCodec(DefaultPayloadTypePCMU, 8000),
+		NewRTPPCMACodec(DefaultPayloadTypePCMA, 8000),
+		NewRTPG722Codec(DefaultPayloadTypeG722, 8000),
+		NewRTPTelephoneEventCodec(DefaultPayloadTypeTelephoneEvent48k, 48000),
+		NewRTPTelephoneEventCodec(DefaultPayloadTypeTelephoneEvent8k, 8000),
+
+		// Video Codecs in descending order of preference
+		NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000),
+		NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000),
+		NewRTPH264Codec(DefaultPayloadTypeH264, 90000),
+	} {
+		_, _ = m.RegisterCodec(codec)
+	}
 }
 
 // PopulateFromSDP finds all codecs in sd and adds them to m, using the dynamic
 // payload types and parameters from sd.
-// PopulateFromSDP is intended for use when answering a request.
-// The offerer sets the PayloadTypes for the connection.
-// PopulateFromSDP allows an answerer to properly match the PayloadTypes from the offerer.
+// A PeerConnection answering an offer already uses the PayloadTypes of the offer for
+// the codecs registered with its MediaEngine, PopulateFromSDP is only needed to
+// accept every codec of the offer.
+// Codecs without built-in support are added with their name, clock rate, channels, fmtp
+// and rtcp-fb, their payloads can be packetized with the factories of RegisterPayloader.
 // A MediaEngine populated by PopulateFromSDP should be used only for a single session.
 func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 	sdp := sdp.SessionDescription{}
@@ -105,18 +305,127 @@ func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 				codec = NewRTPVP9Codec(payloadType, payloadCodec.ClockRate)
 			case strings.EqualFold(payloadCodec.Name, H264):
 				codec = NewRTPH264Codec(payloadType, payloadCodec.ClockRate)
+			case strings.EqualFold(payloadCodec.Name, RTX):
+				codec = NewRTPCodec(NewRTPCodecType(md.MediaName.Media), RTX, payloadCodec.ClockRate, 0, payloadCodec.Fmtp, payloadType, nil)
 			default:
-				// ignoring other codecs
-				continue
+				codec = newRTPCodecFromSDP(NewRTPCodecType(md.MediaName.Media), payloadCodec)
 			}
 
 			codec.SDPFmtpLine = payloadCodec.Fmtp
-			m.RegisterCodec(codec)
+			if _, err := m.RegisterCodec(codec); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+// newRTPCodecFromSDP returns a codec PopulateFromSDP doesn't know. It has no Payloader,
+// PeerConnection.NewTrack uses the one registered for its mime type
+func newRTPCodecFromSDP(kind RTPCodecType, sdpCodec sdp.Codec) *RTPCodec {
+	channels, err := strconv.ParseUint(sdpCodec.EncodingParameters, 10, 16)
+	if err != nil {
+		channels = 0
+	}
+
+	rtcpFeedback := []RTCPFeedback{}
+	for _, feedback := range sdpCodec.RTCPFeedback {
+		split := strings.SplitN(feedback, " ", 2)
+		if len(split) == 2 {
+			rtcpFeedback = append(rtcpFeedback, RTCPFeedback{Type: split[0], Parameter: split[1]})
+		} else {
+			rtcpFeedback = append(rtcpFeedback, RTCPFeedback{Type: split[0]})
+		}
+	}
+
+	return NewRTPCodecExt(kind, sdpCodec.Name, sdpCodec.ClockRate, uint16(channels), sdpCodec.Fmtp, sdpCodec.PayloadType, rtcpFeedback, nil)
+}
+
+// copy returns a MediaEngine with the codecs, header extensions and factories of m, it is given
+// to every PeerConnection so it can adopt the PayloadTypes of the remote. The codecs are shared
+// until their PayloadType changes
+func (m *MediaEngine) copy() *MediaEngine {
+	c := &MediaEngine{
+		codecs:           append([]*RTPCodec{}, m.codecs...),
+		headerExtensions: append([]mediaEngineHeaderExtension{}, m.headerExtensions...),
+	}
+	for mimeType, factory := range m.payloaders {
+		c.RegisterPayloader(mimeType, factory)
+	}
+	for mimeType, factory := range m.depacketizers {
+		c.RegisterDepacketizer(mimeType, factory)
+	}
+	return c
+}
+
+// updateFromRemoteDescription gives the codecs of m that are in the media sections of
+// desc the PayloadType desc uses for them. Codecs that had these PayloadTypes take the
+// previous ones, the RTX codecs follow the codecs they are associated with. Codecs that
+// were already negotiated keep their PayloadType, and an answer never changes them since
+// it uses the PayloadTypes of our offer.
+func (m *MediaEngine) updateFromRemoteDescription(desc *sdp.SessionDescription, isOffer bool) {
+	// A codec only takes the first PayloadType it matches
+	assigned := map[uint8]bool{}
+	for payloadType := range m.negotiatedPayloadTypes {
+		assigned[payloadType] = true
+	}
+
+	for _, md := range desc.MediaDescriptions {
+		if md.MediaName.Media != mediaNameAudio && md.MediaName.Media != mediaNameVideo {
+			continue
+		}
+
+		remoteCodecs := codecsFromMediaDescription(desc, md)
+
+		// The RTX codecs are matched once the codecs they retransmit have their PayloadType
+		for _, rtx := range []bool{false, true} {
+			for _, remoteCodec := range remoteCodecs {
+				if strings.EqualFold(remoteCodec.Name, RTX) != rtx || assigned[remoteCodec.PayloadType] {
+					continue
+				}
+
+				codec, err := m.getCodecSDP(remoteCodec)
+				if err != nil || assigned[codec.PayloadType] {
+					continue
+				}
+
+				if codec.PayloadType != remoteCodec.PayloadType {
+					if !isOffer {
+						continue
+					}
+					m.setPayloadType(codec, remoteCodec.PayloadType)
+				}
+				assigned[remoteCodec.PayloadType] = true
+			}
+		}
+	}
+	m.negotiatedPayloadTypes = assigned
+}
+
+// setPayloadType changes the PayloadType of codec, swapping it with the codec that had payloadType
+func (m *MediaEngine) setPayloadType(codec *RTPCodec, payloadType uint8) {
+	swapped := map[uint8]uint8{codec.PayloadType: payloadType, payloadType: codec.PayloadType}
+
+	// Codecs are copied before they are changed, they may be shared with other MediaEngines
+	for i, c := range m.codecs {
+		newPayloadType, changed := swapped[c.PayloadType]
+		apt, isRTX := c.associatedPayloadType()
+		newApt, aptChanged := swapped[apt]
+		if !changed && !(isRTX && aptChanged) {
+			continue
+		}
+
+		updated := *c
+		if changed {
+			updated.PayloadType = newPayloadType
+		}
+		if isRTX && aptChanged {
+			updated.SDPFmtpLine = fmt.Sprintf("apt=%d", newApt)
+		}
+		m.codecs[i] = &updated
+	}
+}
+
 func (m *MediaEngine) getCodec(payloadType uint8) (*RTPCodec, error) {
 	for _, codec := range m.codecs {
 		if codec.PayloadType == payloadType {
@@ -128,17 +437,37 @@ func (m *MediaEngine) getCodec(payloadType uint8) (*RTPCodec, error) {
 
 func (m *MediaEngine) getCodecSDP(sdpCodec sdp.Codec) (*RTPCodec, error) {
 	for _, codec := range m.codecs {
-		if codec.Name == sdpCodec.Name &&
-			codec.ClockRate == sdpCodec.ClockRate &&
-			(sdpCodec.EncodingParameters == "" ||
-				strconv.Itoa(int(codec.Channels)) == sdpCodec.EncodingParameters) &&
-			codec.SDPFmtpLine == sdpCodec.Fmtp { // pion/webrtc#43
+		if codec.matchesSDP(sdpCodec) {
 			return codec, nil
 		}
 	}
 	return nil, ErrCodecNotFound
 }
 
+// getCapabilities returns the codecs of kind kind and the header extensions a RTPTransceiver
+// with direction direction can negotiate
+func (m *MediaEngine) getCapabilities(kind RTPCodecType, direction RTPTransceiverDirection) RTPCapabilities {
+	capabilities := RTPCapabilities{
+		Codecs:           []RTPCodecCapability{},
+		HeaderExtensions: []RTPHeaderExtensionCapability{},
+	}
+	if kind != RTPCodecTypeAudio && kind != RTPCodecTypeVideo {
+		return capabilities
+	}
+
+	for _, codec := range m.GetCodecsByKind(kind) {
+		capability := codec.RTPCodecCapability
+		capability.MimeType = codec.mimeType()
+		capability.RTCPFeedback = append([]RTCPFeedback{}, codec.RTCPFeedback...)
+		capabilities.Codecs = append(capabilities.Codecs, capability)
+	}
+
+	for _, uri := range m.getHeaderExtensionURIs(kind, direction) {
+		capabilities.HeaderExtensions = append(capabilities.HeaderExtensions, RTPHeaderExtensionCapability{URI: uri})
+	}
+	return capabilities
+}
+
 // GetCodecsByKind returns all codecs of kind kind that are supported by m.
 // The returned codecs should not be modified.
 func (m *MediaEngine) GetCodecsByKind(kind RTPCodecType) []*RTPCodec {
@@ -161,6 +490,10 @@ const (
 	VP8  = "VP8"
 	VP9  = "VP9"
 	H264 = "H264"
+	RTX  = "rtx"
+
+	// TelephoneEvent is the RFC 4733 codec sending DTMF tones in an audio stream
+	TelephoneEvent = "telephone-event"
 )
 
 // NewRTPPCMUCodec is a helper to create a PCMU codec
@@ -286,6 +619,58 @@ func NewRTPH264CodecExt(payloadType uint8, clockrate uint32, rtcpfb []RTCPFeedba
 	return c
 }
 
+// NewRTPRTXCodec is a helper to create a RFC 4588 RTX codec, used to retransmit
+// packets of the video codec with PayloadType associatedPayloadType
+func NewRTPRTXCodec(payloadType uint8, clockrate uint32, associatedPayloadType uint8) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeVideo,
+		RTX,
+		clockrate,
+		0,
+		fmt.Sprintf("apt=%d", associatedPayloadType),
+		payloadType,
+		nil)
+	return c
+}
+
+// NewRTPTelephoneEventCodec is a helper to create a RFC 4733 telephone-event codec for the
+// DTMF events 0-15. Its clock rate must be the one of the audio codec it is sent with
+func NewRTPTelephoneEventCodec(payloadType uint8, clockrate uint32) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		TelephoneEvent,
+		clockrate,
+		0,
+		"0-15",
+		payloadType,
+		nil)
+	return c
+}
+
+// getTelephoneEventCodec returns the telephone-event codec with the given clock rate
+func (m *MediaEngine) getTelephoneEventCodec(clockRate uint32) (*RTPCodec, bool) {
+	for _, codec := range m.codecs {
+		if strings.EqualFold(codec.Name, TelephoneEvent) && codec.ClockRate == clockRate {
+			return codec, true
+		}
+	}
+	return nil, false
+}
+
+// isTelephoneEvent tells if payloadType is the PayloadType of a telephone-event codec
+func (m *MediaEngine) isTelephoneEvent(payloadType uint8) bool {
+	codec, err := m.getCodec(payloadType)
+	return err == nil && strings.EqualFold(codec.Name, TelephoneEvent)
+}
+
+// getRTXPayloadType returns the PayloadType of the RTX codec that retransmits payloadType
+func (m *MediaEngine) getRTXPayloadType(payloadType uint8) (uint8, bool) {
+	for _, codec := range m.codecs {
+		if apt, ok := codec.associatedPayloadType(); ok && apt == payloadType {
+			return codec.PayloadType, true
+		}
+	}
+	return 0, false
+}
+
 // RTPCodecType determines the type of a codec
 type RTPCodecType int
 
@@ -380,6 +765,179 @@ func NewRTPCodecExt(
 	}
 }
 
+// matches tells if c and codec are the same codec, regardless of their PayloadType and RTCPFeedback
+func (c *RTPCodec) matches(codec *RTPCodec) bool {
+	return c.Type == codec.Type &&
+		strings.EqualFold(c.Name, codec.Name) &&
+		c.ClockRate == codec.ClockRate &&
+		c.Channels == codec.Channels &&
+		c.SDPFmtpLine == codec.SDPFmtpLine
+}
+
+// mimeType returns the mime type of c, e.g. "video/VP8"
+func (c *RTPCodec) mimeType() string {
+	return c.Type.String() + "/" + c.Name
+}
+
+// matchesSDP tells if sdpCodec describes a format of c
+func (c *RTPCodec) matchesSDP(sdpCodec sdp.Codec) bool {
+	return strings.EqualFold(c.Name, sdpCodec.Name) &&
+		c.ClockRate == sdpCodec.ClockRate &&
+		(sdpCodec.EncodingParameters == "" ||
+			strconv.Itoa(int(c.Channels)) == sdpCodec.EncodingParameters) &&
+		fmtpMatches(c.Name, c.SDPFmtpLine, sdpCodec.Fmtp)
+}
+
+// matchesCapability tells if capability describes c. RTX codecs match any RTX capability
+// of their clock rate, their apt depends on the negotiated PayloadTypes
+func (c *RTPCodec) matchesCapability(capability RTPCodecCapability) bool {
+	return strings.EqualFold(capability.MimeType, c.mimeType()) &&
+		c.ClockRate == capability.ClockRate &&
+		c.Channels == capability.Channels &&
+		(strings.EqualFold(c.Name, RTX) || fmtpEqual(c.SDPFmtpLine, capability.SDPFmtpLine))
+}
+
+// getCodecMatching returns the codec of m that is the same codec as codec
+func (m *MediaEngine) getCodecMatching(codec *RTPCodec) (*RTPCodec, error) {
+	for _, c := range m.codecs {
+		if c.matches(codec) {
+			return c, nil
+		}
+	}
+	return nil, ErrCodecNotFound
+}
+
+// associatedPayloadType returns the apt of a RTX codec
+func (c *RTPCodec) associatedPayloadType() (uint8, bool) {
+	if !strings.EqualFold(c.Name, RTX) {
+		return 0, false
+	}
+
+	for _, parameter := range strings.Split(c.SDPFmtpLine, ";") {
+		split := strings.SplitN(strings.TrimSpace(parameter), "=", 2)
+		if len(split) != 2 || split[0] != "apt" {
+			continue
+		}
+
+		apt, err := strconv.ParseUint(split[1], 10, 8)
+		if err != nil {
+			return 0, false
+		}
+		return uint8(apt), true
+	}
+	return 0, false
+}
+
+// fmtpParameters parses the parameters of a fmtp line, the names are lower cased
+func fmtpParameters(fmtp string) map[string]string {
+	parameters := map[string]string{}
+	for _, parameter := range strings.Split(fmtp, ";") {
+		split := strings.SplitN(strings.TrimSpace(parameter), "=", 2)
+		if split[0] == "" {
+			continue
+		}
+
+		value := ""
+		if len(split) == 2 {
+			value = strings.TrimSpace(split[1])
+		}
+		parameters[strings.ToLower(split[0])] = value
+	}
+	return parameters
+}
+
+// fmtpMatches tells if the fmtp lines a and b of a codec named name describe the same format.
+// Only the parameters that change the RTP payload format or the bitstream are compared, the
+// others like the H264 level describe what the application encodes and decodes
+func fmtpMatches(name, a, b string) bool {
+	parametersA, parametersB := fmtpParameters(a), fmtpParameters(b)
+	value := func(parameters map[string]string, parameter, defaultValue string) string {
+		if v, ok := parameters[parameter]; ok {
+			return v
+		}
+		return defaultValue
+	}
+	equal := func(parameter, defaultValue string) bool {
+		return value(parametersA, parameter, defaultValue) == value(parametersB, parameter, defaultValue)
+	}
+
+	switch {
+	case strings.EqualFold(name, H264):
+		profileA, okA := h264Profile(value(parametersA, "profile-level-id", h264DefaultProfileLevelID))
+		profileB, okB := h264Profile(value(parametersB, "profile-level-id", h264DefaultProfileLevelID))
+		return equal("packetization-mode", "0") && okA && okB && profileA == profileB
+	case strings.EqualFold(name, RTX):
+		return equal("apt", "")
+	default:
+		return true
+	}
+}
+
+// The profile-level-id of H264 when the fmtp line has none, Constrained Baseline level 3.1
+const h264DefaultProfileLevelID = "42e01f"
+
+// The H264 profiles, identified by the profile_idc and the constraint flags of profile_iop in a
+// profile-level-id. The bits of profileIOP are matched most significant first, x matches any.
+var h264ProfilePatterns = []struct {
+	profileIDC byte
+	profileIOP string
+	profile    string
+}{
+	{0x42, "x1xx0000", "constrained-baseline"},
+	{0x4D, "1xxx0000", "constrained-baseline"},
+	{0x58, "11xx0000", "constrained-baseline"},
+	{0x42, "x0xx0000", "baseline"},
+	{0x58, "10xx0000", "baseline"},
+	{0x4D, "0x0x0000", "main"},
+	{0x64, "00000000", "high"},
+	{0x64, "00001100", "constrained-high"},
+	{0xF4, "00000000", "predictive-high-444"},
+}
+
+// h264Profile returns the profile of a H264 profile-level-id, ok is false if it is malformed or
+// of an unknown profile
+func h264Profile(profileLevelID string) (profile string, ok bool) {
+	if len(profileLevelID) != 6 {
+		return "", false
+	}
+	parsed, err := strconv.ParseUint(profileLevelID, 16, 32)
+	if err != nil {
+		return "", false
+	}
+
+	profileIDC, profileIOP := byte(parsed>>16), byte(parsed>>8)
+	for _, pattern := range h264ProfilePatterns {
+		if pattern.profileIDC != profileIDC {
+			continue
+		}
+
+		matches := true
+		for i := 0; i < len(pattern.profileIOP) && matches; i++ {
+			bit := profileIOP >> uint(7-i) & 1
+			matches = pattern.profileIOP[i] == 'x' || pattern.profileIOP[i]-'0' == bit
+		}
+		if matches {
+			return pattern.profile, true
+		}
+	}
+	return "", false
+}
+
+// fmtpEqual tells if the fmtp lines a and b have the same parameters, in any order
+func fmtpEqual(a, b string) bool {
+	parametersA, parametersB := fmtpParameters(a), fmtpParameters(b)
+	if len(parametersA) != len(parametersB) {
+		return false
+	}
+
+	for parameter, value := range parametersA {
+		if other, ok := parametersB[parameter]; !ok || !strings.EqualFold(value, other) {
+			return false
+		}
+	}
+	return true
+}
+
 // RTPCodecCapability provides information about codec capabilities.
 type RTPCodecCapability struct {
 	MimeType     string
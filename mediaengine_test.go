@@ -3,10 +3,14 @@
 package webrtc
 
 import (
+	"fmt"
 	"regexp"
 	"testing"
 
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
 	"github.com/pion/sdp/v2"
+	"github.com/pion/webrtc/v2/pkg/media"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -94,6 +98,32 @@ a=sctpmap:5000 webrtc-datachannel 1024
 	assertCodecWithPayloadType(VP9, 135)
 }
 
+func TestPopulateFromSDP_RTX(t *testing.T) {
+	const sdpValue = `v=0
+o=- 884433216 1576829404 IN IP4 0.0.0.0
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 105 106
+c=IN IP4 0.0.0.0
+a=mid:0
+a=rtpmap:105 VP8/90000
+a=rtpmap:106 rtx/90000
+a=fmtp:106 apt=105
+`
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpValue}))
+
+	rtx, err := m.getCodec(106)
+	if assert.NoError(t, err) {
+		assert.Equal(t, RTX, rtx.Name)
+	}
+
+	rtxPayloadType, ok := m.getRTXPayloadType(105)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(106), rtxPayloadType)
+}
+
 // pion/webrtc#1078
 func TestOpusCase(t *testing.T) {
 	pc, err := NewPeerConnection(Configuration{})
@@ -108,3 +138,360 @@ func TestOpusCase(t *testing.T) {
 	assert.True(t, regexp.MustCompile(`(?m)^a=rtpmap:\d+ opus/48000/2`).MatchString(offer.SDP))
 	assert.NoError(t, pc.Close())
 }
+
+func TestRegisterHeaderExtension(t *testing.T) {
+	const audioLevelURI = "urn:ietf:params:rtp-hdrext:ssrc-audio-level"
+
+	m := MediaEngine{}
+	assert.NoError(t, m.RegisterHeaderExtension(sdp.ABSSendTimeURI, RTPCodecTypeVideo))
+	assert.NoError(t, m.RegisterHeaderExtension(audioLevelURI, RTPCodecTypeAudio, RTPTransceiverDirectionSendrecv))
+	assert.NoError(t, m.RegisterHeaderExtension(sdp.ABSSendTimeURI, RTPCodecTypeAudio))
+	assert.Equal(t, ErrUnknownType, m.RegisterHeaderExtension(audioLevelURI, RTPCodecType(0)))
+
+	assert.Equal(t, sdp.DefExtMapValueABSSendTime, m.getHeaderExtensionID(sdp.ABSSendTimeURI))
+	assert.Equal(t, 5, m.getHeaderExtensionID(audioLevelURI))
+	assert.Equal(t, sdp.DefExtMapValueSDESMid, m.getHeaderExtensionID(sdp.SDESMidURI))
+
+	assert.Equal(t, []string{sdp.ABSSendTimeURI}, m.getHeaderExtensionURIs(RTPCodecTypeVideo, RTPTransceiverDirectionSendrecv))
+	assert.Equal(t, []string{sdp.ABSSendTimeURI, audioLevelURI}, m.getHeaderExtensionURIs(RTPCodecTypeAudio, RTPTransceiverDirectionSendrecv))
+	assert.Equal(t, []string{sdp.ABSSendTimeURI}, m.getHeaderExtensionURIs(RTPCodecTypeAudio, RTPTransceiverDirectionRecvonly))
+
+	m.RegisterCodec(NewRTPVP8CodecExt(DefaultPayloadTypeVP8, 90000, []RTCPFeedback{{Type: TypeRTCPFBTransportCC}}, ""))
+	assert.Equal(t, []string{sdp.ABSSendTimeURI, sdp.TransportCCURI}, m.getHeaderExtensionURIs(RTPCodecTypeVideo, RTPTransceiverDirectionSendrecv))
+
+	for i := 0; i < 9; i++ {
+		assert.NoError(t, m.RegisterHeaderExtension(fmt.Sprintf("urn:test:%d", i), RTPCodecTypeVideo))
+	}
+	assert.Equal(t, ErrHeaderExtensionIDsExhausted, m.RegisterHeaderExtension("urn:test:exhausted", RTPCodecTypeVideo))
+}
+
+func TestRegisterCodecPayloadType(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+
+	// Codecs without a PayloadType get the lowest free dynamic PayloadType
+	payloadType, err := m.RegisterCodec(NewRTPG722Codec(0, 16000))
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(97), payloadType)
+
+	// The registered codec is a copy, the one of the caller is left unchanged
+	vp8Codec := NewRTPVP8Codec(0, 48000)
+	payloadType, err = m.RegisterCodec(vp8Codec)
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(99), payloadType)
+	assert.Equal(t, uint8(0), vp8Codec.PayloadType)
+
+	// PCMU keeps its static PayloadType
+	payloadType, err = m.RegisterCodec(NewRTPPCMUCodec(DefaultPayloadTypePCMU, 8000))
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(DefaultPayloadTypePCMU), payloadType)
+	assert.Len(t, m.GetCodecsByKind(RTPCodecTypeAudio), 7)
+
+	_, err = m.RegisterCodec(NewRTPVP9Codec(DefaultPayloadTypeVP8, 90000))
+	assert.Equal(t, ErrPayloadTypeInUse, err)
+
+	// Registering a codec again adds its RTCPFeedback, without changing the codecs shared with
+	// copies of the MediaEngine
+	shared := m
+	shared.codecs = append([]*RTPCodec{}, m.codecs...)
+	sharedVP8, err := shared.getCodec(DefaultPayloadTypeVP8)
+	assert.NoError(t, err)
+
+	payloadType, err = m.RegisterCodec(NewRTPVP8CodecExt(DefaultPayloadTypeVP8, 90000, []RTCPFeedback{
+		{Type: TypeRTCPFBNACK},
+		{Type: TypeRTCPFBTransportCC},
+	}, ""))
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(DefaultPayloadTypeVP8), payloadType)
+
+	vp8, err := m.getCodec(DefaultPayloadTypeVP8)
+	assert.NoError(t, err)
+	assert.Len(t, m.GetCodecsByKind(RTPCodecTypeVideo), 4)
+	assert.True(t, hasRTCPFeedback(vp8.RTCPFeedback, TypeRTCPFBNACK, ""))
+	assert.True(t, hasRTCPFeedback(vp8.RTCPFeedback, TypeRTCPFBTransportCC, ""))
+	assert.False(t, hasRTCPFeedback(sharedVP8.RTCPFeedback, TypeRTCPFBTransportCC, ""))
+
+	for {
+		if _, err = m.RegisterCodec(NewRTPOpusCodec(0, 48000)); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, ErrPayloadTypesExhausted, err)
+}
+
+func TestUpdateFromRemoteDescription(t *testing.T) {
+	const remoteSDP = `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 98 99
+c=IN IP4 0.0.0.0
+a=mid:0
+a=rtpmap:98 VP8/90000
+a=rtpmap:99 rtx/90000
+a=fmtp:99 apt=98
+`
+	desc := sdp.SessionDescription{}
+	assert.NoError(t, desc.Unmarshal([]byte(remoteSDP)))
+
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	_, err := m.RegisterCodec(NewRTPRTXCodec(0, 90000, DefaultPayloadTypeVP8))
+	assert.NoError(t, err)
+
+	local := m.copy()
+	local.updateFromRemoteDescription(&desc, true)
+
+	assertCodec := func(m *MediaEngine, payloadType uint8, name, fmtp string) {
+		codec, err := m.getCodec(payloadType)
+		if assert.NoError(t, err) {
+			assert.Equal(t, name, codec.Name)
+			assert.Equal(t, fmtp, codec.SDPFmtpLine)
+		}
+	}
+
+	// VP8 takes the PayloadType of VP9, the RTX codec follows it
+	assertCodec(local, 98, VP8, "")
+	assertCodec(local, DefaultPayloadTypeVP8, VP9, "")
+	assertCodec(local, 99, RTX, "apt=98")
+	_, err = local.getCodec(97)
+	assert.Equal(t, ErrCodecNotFound, err)
+
+	// The MediaEngine that was copied is unchanged
+	assertCodec(&m, DefaultPayloadTypeVP8, VP8, "")
+	assertCodec(&m, DefaultPayloadTypeVP9, VP9, "")
+	assertCodec(&m, 97, RTX, "apt=96")
+}
+
+func TestFmtpMatches(t *testing.T) {
+	for _, test := range []struct {
+		name, a, b string
+		matches    bool
+	}{
+		{VP8, "", "", true},
+		{Opus, "minptime=10;useinbandfec=1", "minptime=10", true},
+		{H264, "packetization-mode=1;profile-level-id=42001f", "profile-level-id=42001f; packetization-mode=1", true},
+		{H264, "packetization-mode=1;profile-level-id=42001f", "profile-level-id=42001f", false},
+		{H264, "packetization-mode=0", "", true},
+		// Only the profile is compared, not the level
+		{H264, "packetization-mode=1;profile-level-id=42e01f", "packetization-mode=1;profile-level-id=42e034", true},
+		{H264, "packetization-mode=1;profile-level-id=42001f", "packetization-mode=1;profile-level-id=640c1f", false},
+		{H264, "packetization-mode=1;profile-level-id=42001f", "packetization-mode=1;profile-level-id=42e01f", false},
+		// Constrained Baseline has different profile_idc with the constraint flags
+		{H264, "profile-level-id=42e01f", "profile-level-id=4d801f", true},
+		{H264, "profile-level-id=42e01f", "", true},
+		{H264, "profile-level-id=640c1f", "profile-level-id=640c34", true},
+		{H264, "profile-level-id=640c1f", "profile-level-id=64001f", false},
+		{H264, "profile-level-id=4d001f", "profile-level-id=4d001f", true},
+		{H264, "profile-level-id=zz001f", "profile-level-id=zz001f", false},
+		{H264, "profile-level-id=6e001f", "profile-level-id=6e001f", false},
+		{RTX, "apt=96", "apt=96", true},
+		{RTX, "apt=96", "apt=98", false},
+	} {
+		assert.Equal(t, test.matches, fmtpMatches(test.name, test.a, test.b), "%s %q %q", test.name, test.a, test.b)
+	}
+
+	assert.True(t, fmtpEqual("packetization-mode=1;profile-level-id=42001f", "profile-level-id=42001F;packetization-mode=1"))
+	assert.False(t, fmtpEqual("packetization-mode=1;profile-level-id=42001f", "packetization-mode=1"))
+}
+
+func TestGetCapabilities(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	_, err := m.RegisterCodec(NewRTPRTXCodec(0, 90000, DefaultPayloadTypeVP8))
+	assert.NoError(t, err)
+	assert.NoError(t, m.RegisterHeaderExtension(sdp.ABSSendTimeURI, RTPCodecTypeVideo))
+	assert.NoError(t, m.RegisterHeaderExtension(AudioLevelURI, RTPCodecTypeAudio, RTPTransceiverDirectionRecvonly))
+
+	api := NewAPI(WithMediaEngine(m))
+	pc, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	transceiver, err := pc.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	sender, receiver := transceiver.Sender(), transceiver.Receiver()
+
+	mimeTypes := func(capabilities RTPCapabilities) []string {
+		mimeTypes := []string{}
+		for _, codec := range capabilities.Codecs {
+			mimeTypes = append(mimeTypes, codec.MimeType)
+		}
+		return mimeTypes
+	}
+
+	// The capabilities are known before any RTPSender or RTPReceiver is created
+	video := api.GetSenderCapabilities(RTPCodecTypeVideo)
+	assert.Equal(t, video, sender.GetCapabilities(RTPCodecTypeVideo))
+	assert.Equal(t, []string{"video/VP8", "video/VP9", "video/H264", "video/rtx"}, mimeTypes(video))
+	assert.Equal(t, "apt=96", video.Codecs[3].SDPFmtpLine)
+	assert.Equal(t, []RTPHeaderExtensionCapability{{URI: sdp.ABSSendTimeURI}}, video.HeaderExtensions)
+	assert.Equal(t, video, receiver.GetCapabilities(RTPCodecTypeVideo))
+
+	// Only the receiver can negotiate the audio level extension
+	audio := api.GetReceiverCapabilities(RTPCodecTypeAudio)
+	assert.Equal(t, audio, receiver.GetCapabilities(RTPCodecTypeAudio))
+	assert.Equal(t, []string{"audio/opus", "audio/PCMU", "audio/PCMA", "audio/G722", "audio/telephone-event", "audio/telephone-event"}, mimeTypes(audio))
+	assert.Equal(t, uint16(2), audio.Codecs[0].Channels)
+	assert.Equal(t, []RTPHeaderExtensionCapability{{URI: AudioLevelURI}}, audio.HeaderExtensions)
+	assert.Empty(t, sender.GetCapabilities(RTPCodecTypeAudio).HeaderExtensions)
+
+	assert.Empty(t, sender.GetCapabilities(RTPCodecType(0)).Codecs)
+
+	// Capabilities can be used as codec preferences
+	assert.NoError(t, transceiver.SetCodecPreferences(video.Codecs[2:]))
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestPopulateFromSDPGenericCodecs(t *testing.T) {
+	const sdpValue = `v=0
+o=- 6476616870435111971 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0 1
+m=audio 9 UDP/TLS/RTP/SAVPF 111 63 126 110
+c=IN IP4 0.0.0.0
+a=ice-ufrag:sRIG
+a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT
+a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:46:FD:A8:2A:F3:13:94:6E:E6:43:23:CB:D7
+a=setup:actpass
+a=mid:0
+a=sendrecv
+a=rtpmap:111 opus/48000/2
+a=fmtp:111 minptime=10;useinbandfec=1
+a=rtpmap:63 red/48000/2
+a=fmtp:63 111/111
+a=rtpmap:126 multiopus/48000/6
+a=fmtp:126 channel_mapping=0,4,1,2,3,5;coupled_streams=2;num_streams=4
+a=rtpmap:110 telephone-event/48000
+m=video 9 UDP/TLS/RTP/SAVPF 45 49
+c=IN IP4 0.0.0.0
+a=ice-ufrag:sRIG
+a=ice-pwd:yZb5ZMsBlPoK577sGhjvEUtT
+a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:46:FD:A8:2A:F3:13:94:6E:E6:43:23:CB:D7
+a=setup:actpass
+a=mid:1
+a=sendrecv
+a=rtpmap:45 AV1/90000
+a=rtcp-fb:45 nack
+a=rtcp-fb:45 nack pli
+a=rtpmap:49 H265/90000
+a=fmtp:49 level-id=93;profile-id=1;tier-flag=0
+`
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpValue}))
+
+	multiopus, err := m.getCodec(126)
+	assert.NoError(t, err)
+	assert.Equal(t, RTPCodecTypeAudio, multiopus.Type)
+	assert.Equal(t, "multiopus", multiopus.Name)
+	assert.Equal(t, uint32(48000), multiopus.ClockRate)
+	assert.Equal(t, uint16(6), multiopus.Channels)
+	assert.Equal(t, "channel_mapping=0,4,1,2,3,5;coupled_streams=2;num_streams=4", multiopus.SDPFmtpLine)
+
+	av1, err := m.getCodec(45)
+	assert.NoError(t, err)
+	assert.Equal(t, RTPCodecTypeVideo, av1.Type)
+	assert.Equal(t, []RTCPFeedback{{Type: TypeRTCPFBNACK}, {Type: TypeRTCPFBNACK, Parameter: "pli"}}, av1.RTCPFeedback)
+	assert.Nil(t, av1.Payloader)
+
+	for _, payloadType := range []uint8{63, 110, 49} {
+		_, err = m.getCodec(payloadType)
+		assert.NoError(t, err)
+	}
+
+	// Payloaders and Depacketizers are created from the factories of their mime type
+	_, err = m.NewPayloader(av1)
+	assert.Equal(t, ErrNoPayloader, err)
+	_, err = m.NewDepacketizer(av1)
+	assert.Equal(t, ErrNoDepacketizer, err)
+
+	m.RegisterPayloader("video/av1", func(codec *RTPCodec) rtp.Payloader {
+		return &codecs.VP8Payloader{}
+	})
+	payloader, err := m.NewPayloader(av1)
+	assert.NoError(t, err)
+	assert.IsType(t, &codecs.VP8Payloader{}, payloader)
+
+	opus, err := m.getCodec(111)
+	assert.NoError(t, err)
+	depacketizer, err := m.NewDepacketizer(opus)
+	assert.NoError(t, err)
+	assert.IsType(t, &codecs.OpusPacket{}, depacketizer)
+
+	// Tracks of codecs without Payloader only accept RTP packets
+	pc, err := NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	av1Track, err := pc.NewTrack(45, 1234, "video", "pion")
+	assert.NoError(t, err)
+	assert.NotNil(t, av1Track.Packetizer())
+
+	h265Track, err := pc.NewTrack(49, 5678, "video", "pion")
+	assert.NoError(t, err)
+	assert.Equal(t, ErrNoPayloader, h265Track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+
+	// The answer accepts every codec of the offer
+	assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: sdpValue}))
+	answer, err := pc.CreateAnswer(nil)
+	assert.NoError(t, err)
+	for _, rtpmap := range []string{"opus/48000/2", "red/48000/2", "multiopus/48000/6", "telephone-event/48000", "AV1/90000", "H265/90000"} {
+		assert.Contains(t, answer.SDP, rtpmap)
+	}
+	assert.Contains(t, answer.SDP, "a=rtcp-fb:45 nack pli")
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestRegisterPayloader_BuiltIn(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	m.RegisterPayloader("video/VP8", func(*RTPCodec) rtp.Payloader {
+		return &codecs.H264Payloader{}
+	})
+
+	pc, err := NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	// The registered Payloader replaces the one of the codec, other codecs keep theirs
+	vp8Track, err := pc.NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	assert.NoError(t, err)
+	assert.IsType(t, &codecs.H264Payloader{}, vp8Track.Codec().Payloader)
+
+	vp9Track, err := pc.NewTrack(DefaultPayloadTypeVP9, 5678, "video", "pion")
+	assert.NoError(t, err)
+	assert.IsType(t, &codecs.VP9Payloader{}, vp9Track.Codec().Payloader)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestNewPeerConnection_MediaEngineCopy(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	api := NewAPI(WithMediaEngine(m))
+
+	pc, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	payloadType, err := api.mediaEngine.RegisterCodec(NewRTPCodec(RTPCodecTypeVideo, "AV1", 90000, 0, "", 0, nil))
+	assert.NoError(t, err)
+	api.mediaEngine.RegisterPayloader("video/VP8", func(*RTPCodec) rtp.Payloader {
+		return &codecs.H264Payloader{}
+	})
+
+	// The PeerConnection keeps the codecs and factories it was created with
+	_, err = pc.api.mediaEngine.getCodec(payloadType)
+	assert.Error(t, err)
+	vp8Track, err := pc.NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	assert.NoError(t, err)
+	assert.IsType(t, &codecs.VP8Payloader{}, vp8Track.Codec().Payloader)
+
+	// PeerConnections created afterwards use them
+	pcAfter, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = pcAfter.api.mediaEngine.getCodec(payloadType)
+	assert.NoError(t, err)
+
+	assert.NoError(t, pc.Close())
+	assert.NoError(t, pcAfter.Close())
+}
@@ -0,0 +1,155 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+const (
+	// How often missing packets are checked and NACKed
+	nackInterval = 20 * time.Millisecond
+	// How long a missing packet may be reordered before it is NACKed
+	nackReorderDelay = 10 * time.Millisecond
+	// How long to wait for a retransmission before NACKing a packet again
+	nackRetryInterval = 100 * time.Millisecond
+	// How many times a packet is NACKed before it is considered lost
+	nackMaxRetries = 10
+	// How many packets behind the highest received packet a packet is still NACKed
+	nackMaxAge = 512
+)
+
+// NACKGeneratorStats contains the counters of the NACK generator of a remote Track
+type NACKGeneratorStats struct {
+	// PacketsLost is the number of packets that never arrived, even after being NACKed
+	PacketsLost uint64
+
+	// PacketsReordered is the number of packets that arrived out of order before they were NACKed
+	PacketsReordered uint64
+
+	// PacketsRecovered is the number of packets that arrived after they were NACKed
+	PacketsRecovered uint64
+
+	// NACKCount is the number of NACK packets sent
+	NACKCount uint64
+}
+
+type missingPacket struct {
+	detected, lastNACK time.Time
+	retries            int
+}
+
+// nackGenerator tracks the sequence numbers of a single incoming RTP stream, and
+// decides which missing packets should be NACKed
+type nackGenerator struct {
+	mu sync.Mutex
+
+	started               bool
+	highestSequenceNumber uint16
+	missing               map[uint16]*missingPacket
+	stats                 NACKGeneratorStats
+}
+
+func newNACKGenerator() *nackGenerator {
+	return &nackGenerator{missing: map[uint16]*missingPacket{}}
+}
+
+// update records the arrival of a packet
+func (g *nackGenerator) update(sequenceNumber uint16, now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.started {
+		g.started = true
+		g.highestSequenceNumber = sequenceNumber
+		return
+	}
+
+	diff := sequenceNumber - g.highestSequenceNumber
+	switch {
+	case diff == 0:
+		return
+	case diff < 0x8000:
+		if diff > nackMaxAge {
+			// The stream jumped, there is no point in NACKing everything in between
+			g.missing = map[uint16]*missingPacket{}
+		} else {
+			for s := g.highestSequenceNumber + 1; s != sequenceNumber; s++ {
+				g.missing[s] = &missingPacket{detected: now}
+			}
+		}
+		g.highestSequenceNumber = sequenceNumber
+	default:
+		m, ok := g.missing[sequenceNumber]
+		if !ok {
+			return
+		}
+
+		if m.retries == 0 {
+			g.stats.PacketsReordered++
+		} else {
+			g.stats.PacketsRecovered++
+		}
+		delete(g.missing, sequenceNumber)
+	}
+}
+
+// nack returns the sequence numbers that should be NACKed now, and gives up on packets
+// that are too old or have been NACKed too often
+func (g *nackGenerator) nack(now time.Time) []uint16 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	sequenceNumbers := []uint16{}
+	for s, m := range g.missing {
+		switch {
+		case g.highestSequenceNumber-s > nackMaxAge:
+			fallthrough
+		case m.retries >= nackMaxRetries && now.Sub(m.lastNACK) >= nackRetryInterval:
+			g.stats.PacketsLost++
+			delete(g.missing, s)
+		case m.retries == 0 && now.Sub(m.detected) >= nackReorderDelay:
+			fallthrough
+		case m.retries > 0 && m.retries < nackMaxRetries && now.Sub(m.lastNACK) >= nackRetryInterval:
+			m.retries++
+			m.lastNACK = now
+			sequenceNumbers = append(sequenceNumbers, s)
+		}
+	}
+
+	if len(sequenceNumbers) != 0 {
+		g.stats.NACKCount++
+	}
+
+	// Order by distance from the highest sequence number so wrapping is handled
+	sort.Slice(sequenceNumbers, func(i, j int) bool {
+		return g.highestSequenceNumber-sequenceNumbers[i] > g.highestSequenceNumber-sequenceNumbers[j]
+	})
+	return sequenceNumbers
+}
+
+func (g *nackGenerator) getStats() NACKGeneratorStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats
+}
+
+// nackPairsFromSequenceNumbers packs ordered sequence numbers into as few NackPairs as possible
+func nackPairsFromSequenceNumbers(sequenceNumbers []uint16) []rtcp.NackPair {
+	pairs := []rtcp.NackPair{}
+	for _, s := range sequenceNumbers {
+		if len(pairs) != 0 {
+			last := &pairs[len(pairs)-1]
+			if diff := s - last.PacketID; diff > 0 && diff <= 16 {
+				last.LostPackets |= 1 << (diff - 1)
+				continue
+			}
+		}
+		pairs = append(pairs, rtcp.NackPair{PacketID: s})
+	}
+	return pairs
+}
@@ -0,0 +1,71 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNACKGenerator(t *testing.T) {
+	t.Run("Reordered and Recovered", func(t *testing.T) {
+		g := newNACKGenerator()
+		now := time.Now()
+
+		for _, s := range []uint16{65533, 65534, 1, 2, 65535} {
+			g.update(s, now)
+		}
+		assert.Equal(t, []uint16{}, g.nack(now), "missing packets must not be NACKed before the reorder delay")
+
+		now = now.Add(nackReorderDelay)
+		assert.Equal(t, []uint16{0}, g.nack(now))
+		assert.Equal(t, []uint16{}, g.nack(now.Add(nackRetryInterval/2)))
+		assert.Equal(t, []uint16{0}, g.nack(now.Add(nackRetryInterval)))
+
+		g.update(0, now)
+		assert.Equal(t, NACKGeneratorStats{PacketsReordered: 1, PacketsRecovered: 1, NACKCount: 2}, g.getStats())
+	})
+
+	t.Run("Lost after retries", func(t *testing.T) {
+		g := newNACKGenerator()
+		now := time.Now()
+
+		g.update(10, now)
+		g.update(12, now)
+		for i := 0; i < nackMaxRetries; i++ {
+			now = now.Add(nackRetryInterval)
+			assert.Equal(t, []uint16{11}, g.nack(now))
+		}
+
+		now = now.Add(nackRetryInterval)
+		assert.Equal(t, []uint16{}, g.nack(now))
+		g.update(11, now)
+		assert.Equal(t, NACKGeneratorStats{PacketsLost: 1, NACKCount: nackMaxRetries}, g.getStats())
+	})
+
+	t.Run("Lost after age", func(t *testing.T) {
+		g := newNACKGenerator()
+		now := time.Now()
+
+		g.update(10, now)
+		g.update(12, now)
+		g.update(12+nackMaxAge, now)
+		assert.NotContains(t, g.nack(now.Add(nackReorderDelay)), uint16(11))
+		assert.Equal(t, uint64(1), g.getStats().PacketsLost)
+
+		// Jumps are not NACKed
+		g.update(12+nackMaxAge*3, now)
+		assert.Equal(t, []uint16{}, g.nack(now.Add(nackRetryInterval)))
+	})
+}
+
+func TestNACKPairsFromSequenceNumbers(t *testing.T) {
+	assert.Equal(t, []rtcp.NackPair{}, nackPairsFromSequenceNumbers([]uint16{}))
+	assert.Equal(t, []rtcp.NackPair{
+		{PacketID: 65535, LostPackets: 0x8003},
+		{PacketID: 17},
+	}, nackPairsFromSequenceNumbers([]uint16{65535, 0, 1, 15, 17}))
+}
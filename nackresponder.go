@@ -0,0 +1,71 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+// NACKResponderStats contains the counters of the NACK responder of a RTPSender
+type NACKResponderStats struct {
+	// PacketsRetransmitted is the number of packets sent again in response to a NACK
+	PacketsRetransmitted uint64
+
+	// PacketsUnavailable is the number of NACKed packets that were no longer buffered
+	PacketsUnavailable uint64
+}
+
+// nackResponder keeps the most recently sent packets of a single RTP stream, so they
+// can be retransmitted when the remote sends a NACK
+type nackResponder struct {
+	mu      sync.Mutex
+	packets []*rtp.Packet
+	stats   NACKResponderStats
+}
+
+func newNACKResponder(size uint16) (*nackResponder, error) {
+	if size == 0 || size&(size-1) != 0 {
+		return nil, fmt.Errorf("NACK responder buffer size must be a power of two, got %d", size)
+	}
+
+	return &nackResponder{packets: make([]*rtp.Packet, size)}, nil
+}
+
+// add stores a copy of a sent packet, replacing the packet sent size packets ago
+func (n *nackResponder) add(header *rtp.Header, payload []byte) {
+	p := &rtp.Packet{Header: *header}
+	p.Header.Extensions = append([]rtp.Extension{}, header.Extensions...)
+	p.Payload = append([]byte{}, payload...)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.packets[int(header.SequenceNumber)%len(n.packets)] = p
+}
+
+// get returns the sent packet with the given sequence number, or nil if it isn't buffered anymore
+func (n *nackResponder) get(sequenceNumber uint16) *rtp.Packet {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	p := n.packets[int(sequenceNumber)%len(n.packets)]
+	if p == nil || p.SequenceNumber != sequenceNumber {
+		n.stats.PacketsUnavailable++
+		return nil
+	}
+	return p
+}
+
+func (n *nackResponder) packetRetransmitted() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stats.PacketsRetransmitted++
+}
+
+func (n *nackResponder) getStats() NACKResponderStats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.stats
+}
@@ -0,0 +1,39 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNACKResponder(t *testing.T) {
+	_, err := newNACKResponder(0)
+	assert.Error(t, err)
+	_, err = newNACKResponder(100)
+	assert.Error(t, err)
+
+	n, err := newNACKResponder(4)
+	assert.NoError(t, err)
+
+	payload := []byte{0x01}
+	for _, sequenceNumber := range []uint16{65534, 65535, 0, 1, 2} {
+		n.add(&rtp.Header{SequenceNumber: sequenceNumber}, payload)
+	}
+	payload[0] = 0x02
+
+	assert.Nil(t, n.get(65534))
+	for _, sequenceNumber := range []uint16{65535, 0, 1, 2} {
+		p := n.get(sequenceNumber)
+		if assert.NotNil(t, p) {
+			assert.Equal(t, sequenceNumber, p.SequenceNumber)
+			assert.Equal(t, []byte{0x01}, p.Payload)
+		}
+	}
+	assert.Nil(t, n.get(3))
+
+	n.packetRetransmitted()
+	assert.Equal(t, NACKResponderStats{PacketsRetransmitted: 1, PacketsUnavailable: 2}, n.getStats())
+}
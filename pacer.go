@@ -0,0 +1,196 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// How often the pacer sends the packets its budget allows
+	pacerInterval = 5 * time.Millisecond
+	// The pacing rate is raised so the queued packets are sent within this time
+	pacerMaxQueueTime = 500 * time.Millisecond
+	// Packets are discarded instead of queued past this many bytes
+	pacerMaxQueueSize = 1 << 20
+	// The pacing rate is this many times the target bitrate when the SettingEngine doesn't configure it
+	defaultPacingFactor = 2.5
+)
+
+// pacedPacket is a packet queued by the pacer for owner, send writes it out
+type pacedPacket struct {
+	owner interface{}
+	size  int
+	send  func()
+}
+
+// pacer is a leaky bucket that spreads the RTP packets of the RTPSenders of a DTLSTransport at
+// the pacing rate, instead of sending the packets of a whole frame as a single burst. The
+// packets of the prioritized kind are sent first.
+type pacer struct {
+	mu sync.Mutex
+
+	// The pacing rate is rate if it isn't 0, factor times the target bitrate otherwise
+	rate          uint64
+	factor        float64
+	targetBitrate func() uint64
+	priority      RTPCodecType
+
+	// The packets of the prioritized kind are in queues[0]
+	queues      [2][]pacedPacket
+	queuedBytes int
+	budget      int
+	lastRefill  time.Time
+
+	started   bool
+	closed    chan interface{}
+	closeOnce sync.Once
+}
+
+func newPacer(rate uint64, factor float64, priority RTPCodecType, targetBitrate func() uint64) *pacer {
+	if factor <= 0 {
+		factor = defaultPacingFactor
+	}
+	if priority != RTPCodecTypeVideo {
+		priority = RTPCodecTypeAudio
+	}
+
+	return &pacer{
+		rate:          rate,
+		factor:        factor,
+		targetBitrate: targetBitrate,
+		priority:      priority,
+		closed:        make(chan interface{}),
+	}
+}
+
+// enqueue queues a packet of size bytes and kind for owner, send is called when the pacer sends it
+func (p *pacer) enqueue(owner interface{}, kind RTPCodecType, size int, send func()) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+
+	if p.queuedBytes+size > pacerMaxQueueSize {
+		return ErrPacerQueueFull
+	}
+
+	queue := 1
+	if kind == p.priority {
+		queue = 0
+	}
+	p.queues[queue] = append(p.queues[queue], pacedPacket{owner: owner, size: size, send: send})
+	p.queuedBytes += size
+
+	if !p.started {
+		p.started = true
+		go p.run()
+	}
+	return nil
+}
+
+func (p *pacer) run() {
+	ticker := time.NewTicker(pacerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.closed:
+			return
+		case now := <-ticker.C:
+			// Packets are sent without the lock, they are only sent from this goroutine so they stay in order
+			for _, packet := range p.next(now) {
+				packet.send()
+			}
+		}
+	}
+}
+
+// drop discards the queued packets of owner, they are never sent
+func (p *pacer) drop(owner interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range p.queues {
+		kept := []pacedPacket{}
+		for _, packet := range p.queues[i] {
+			if packet.owner == owner {
+				p.queuedBytes -= packet.size
+			} else {
+				kept = append(kept, packet)
+			}
+		}
+		p.queues[i] = kept
+	}
+}
+
+func (p *pacer) close() {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+	})
+}
+
+// next refills the budget and returns the packets it allows to send. The budget doesn't
+// accumulate over more than a pacerInterval, so there is no burst after being idle
+func (p *pacer) next(now time.Time) []pacedPacket {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	elapsed := now.Sub(p.lastRefill)
+	if p.lastRefill.IsZero() || elapsed > pacerInterval {
+		elapsed = pacerInterval
+	}
+	p.lastRefill = now
+
+	rate := p.pacingRate()
+	if rate == 0 {
+		// Without a rate the packets aren't paced
+		p.budget = p.queuedBytes
+	} else {
+		maxBudget := int(float64(rate) / 8 * pacerInterval.Seconds())
+		p.budget += int(float64(rate) / 8 * elapsed.Seconds())
+		if p.budget > maxBudget {
+			p.budget = maxBudget
+		}
+	}
+
+	// The budget can go below 0 with the last packet, it is paid back on the next refills
+	var packets []pacedPacket
+	for p.budget > 0 && p.queuedBytes > 0 {
+		queue := 0
+		if len(p.queues[0]) == 0 {
+			queue = 1
+		}
+
+		packet := p.queues[queue][0]
+		p.queues[queue][0] = pacedPacket{}
+		p.queues[queue] = p.queues[queue][1:]
+		p.queuedBytes -= packet.size
+		p.budget -= packet.size
+		packets = append(packets, packet)
+	}
+	return packets
+}
+
+// pacingRate returns the rate in bits per second the packets are sent at, raised to send the
+// queued packets within pacerMaxQueueTime. The caller must hold the lock
+func (p *pacer) pacingRate() uint64 {
+	rate := p.rate
+	if rate == 0 {
+		rate = uint64(p.factor * float64(p.targetBitrate()))
+		if rate == 0 {
+			return 0
+		}
+	}
+
+	if drainRate := uint64(float64(p.queuedBytes*8) / pacerMaxQueueTime.Seconds()); drainRate > rate {
+		rate = drainRate
+	}
+	return rate
+}
@@ -0,0 +1,97 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacer(t *testing.T) {
+	targetBitrate := uint64(0)
+	p := newPacer(0, 2, RTPCodecTypeAudio, func() uint64 { return targetBitrate })
+
+	var sent []string
+	enqueue := func(kind RTPCodecType, size int, name string) {
+		assert.NoError(t, p.enqueue(p, kind, size, func() { sent = append(sent, name) }))
+	}
+	send := func(now time.Time) []string {
+		sent = nil
+		for _, packet := range p.next(now) {
+			packet.send()
+		}
+		return sent
+	}
+
+	// Without a target bitrate the packets aren't paced
+	start := time.Now()
+	enqueue(RTPCodecTypeVideo, 1000, "v1")
+	enqueue(RTPCodecTypeVideo, 1000, "v2")
+	assert.Equal(t, []string{"v1", "v2"}, send(start))
+
+	// At twice 400kbps 500 bytes are sent every 5ms, audio first
+	targetBitrate = 400000
+	enqueue(RTPCodecTypeVideo, 1000, "v3")
+	enqueue(RTPCodecTypeVideo, 1000, "v4")
+	enqueue(RTPCodecTypeAudio, 100, "a1")
+	assert.Equal(t, []string{"a1", "v3"}, send(start.Add(5*time.Millisecond)))
+	assert.Empty(t, send(start.Add(10*time.Millisecond)))
+	assert.Equal(t, []string{"v4"}, send(start.Add(15*time.Millisecond)))
+
+	// The budget doesn't accumulate while idle
+	assert.Empty(t, send(start.Add(time.Second)))
+	assert.Empty(t, send(start.Add(2*time.Second)))
+	enqueue(RTPCodecTypeVideo, 400, "v5")
+	enqueue(RTPCodecTypeVideo, 400, "v6")
+	enqueue(RTPCodecTypeVideo, 400, "v7")
+	assert.Equal(t, []string{"v5", "v6"}, send(start.Add(2*time.Second+5*time.Millisecond)))
+	assert.Equal(t, []string{"v7"}, send(start.Add(2*time.Second+10*time.Millisecond)))
+
+	p.close()
+	assert.Equal(t, io.ErrClosedPipe, p.enqueue(p, RTPCodecTypeVideo, 100, func() {}))
+}
+
+func TestPacer_Rate(t *testing.T) {
+	p := newPacer(80000, 0, RTPCodecTypeVideo, func() uint64 { return 0 })
+	assert.Equal(t, defaultPacingFactor, p.factor)
+
+	// Video is sent first, 50 bytes every 5ms
+	assert.NoError(t, p.enqueue(p, RTPCodecTypeAudio, 10, func() {}))
+	assert.NoError(t, p.enqueue(p, RTPCodecTypeVideo, 20, func() {}))
+	packets := p.next(time.Now())
+	assert.Len(t, packets, 2)
+	assert.Equal(t, 20, packets[0].size)
+	assert.Equal(t, uint64(80000), p.pacingRate())
+
+	// The rate is raised to send the queue within pacerMaxQueueTime
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, p.enqueue(p, RTPCodecTypeVideo, 1000, func() {}))
+	}
+	assert.Equal(t, uint64(160000), p.pacingRate())
+	p.close()
+}
+
+func TestPacer_Drop(t *testing.T) {
+	p := newPacer(0, 0, RTPCodecTypeAudio, func() uint64 { return 0 })
+	stopped, running := new(int), new(int)
+
+	assert.NoError(t, p.enqueue(stopped, RTPCodecTypeVideo, 1000, func() {}))
+	assert.NoError(t, p.enqueue(running, RTPCodecTypeAudio, 100, func() {}))
+	assert.NoError(t, p.enqueue(stopped, RTPCodecTypeAudio, 100, func() {}))
+
+	// The packets of a stopped owner are never sent
+	p.drop(stopped)
+	assert.Equal(t, 100, p.queuedBytes)
+	packets := p.next(time.Now())
+	if assert.Len(t, packets, 1) {
+		assert.Equal(t, running, packets[0].owner)
+	}
+
+	// Packets are discarded once too many bytes are queued
+	assert.NoError(t, p.enqueue(running, RTPCodecTypeVideo, pacerMaxQueueSize, func() {}))
+	assert.Equal(t, ErrPacerQueueFull, p.enqueue(running, RTPCodecTypeVideo, 1, func() {}))
+	p.close()
+}
@@ -16,7 +16,9 @@ import (
 
 	"github.com/pion/logging"
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/sdp/v2"
+	"github.com/pion/srtp"
 
 	"github.com/pion/webrtc/v2/internal/util"
 	"github.com/pion/webrtc/v2/pkg/rtcerr"
@@ -86,8 +88,13 @@ func NewPeerConnection(configuration Configuration) (*PeerConnection, error) {
 	return api.NewPeerConnection(configuration)
 }
 
-// NewPeerConnection creates a new PeerConnection with the provided configuration against the received API object
+// NewPeerConnection creates a new PeerConnection with the provided configuration against the received API object.
+// The PeerConnection uses a copy of the MediaEngine of the API, codecs registered with it afterwards are not used.
 func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection, error) {
+	// Every PeerConnection has its own MediaEngine, so it can adopt the PayloadTypes of the remote
+	pcAPI := *api
+	pcAPI.mediaEngine = api.mediaEngine.copy()
+
 	// https://w3c.github.io/webrtc-pc/#constructor (Step #2)
 	// Some variables defined explicitly despite their implicit zero values to
 	// allow better readability to understand what is happening.
@@ -112,7 +119,7 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		iceConnectionState:           ICEConnectionStateNew,
 		connectionState:              PeerConnectionStateNew,
 
-		api: api,
+		api: &pcAPI,
 		log: api.settingEngine.LoggerFactory.NewLogger("pc"),
 	}
 
@@ -311,6 +318,13 @@ func (pc *PeerConnection) OnConnectionStateChange(f func(PeerConnectionState)) {
 	pc.onConnectionStateChangeHandler = f
 }
 
+// OnTargetBitrateChange sets an event handler which is called when the BandwidthEstimator
+// changes the bitrate, in bits per second, that can be sent to the remote. The estimate is
+// updated while RTCP is read from the RTPSenders, so encoders can adapt to it.
+func (pc *PeerConnection) OnTargetBitrateChange(f func(bitrate uint64)) {
+	pc.dtlsTransport.OnTargetBitrateChange(f)
+}
+
 // SetConfiguration updates the configuration of this PeerConnection object.
 func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-setconfiguration (step #2)
@@ -786,6 +800,11 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 		return err
 	}
 
+	// The codecs are sent and received with the PayloadTypes of the offer that negotiated them
+	if desc.Type == SDPTypeOffer || desc.Type == SDPTypeAnswer {
+		pc.api.mediaEngine.updateFromRemoteDescription(desc.parsed, desc.Type == SDPTypeOffer)
+	}
+
 	weOffer := desc.Type == SDPTypeAnswer
 
 	var t *RTPTransceiver
@@ -876,52 +895,77 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 }
 
 func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPReceiver) {
-	err := receiver.Receive(RTPReceiveParameters{
-		Encodings: RTPDecodingParameters{
-			RTPCodingParameters{SSRC: incoming.ssrc},
+	encodings := []RTPDecodingParameters{}
+	if incoming.ssrc != 0 {
+		encodings = append(encodings, RTPDecodingParameters{RTPCodingParameters{
+			SSRC: incoming.ssrc,
+			RTX:  RTPRtxParameters{SSRC: incoming.repairSSRC},
 		}})
-	if err != nil {
+	}
+	for _, rid := range incoming.rids {
+		encodings = append(encodings, RTPDecodingParameters{RTPCodingParameters{RID: rid}})
+	}
+
+	headerExtensions := pc.negotiatedHeaderExtensions(incoming.mid, incoming.kind)
+	if err := receiver.Receive(RTPReceiveParameters{Encodings: encodings, HeaderExtensions: headerExtensions}); err != nil {
 		pc.log.Warnf("RTPReceiver Receive failed %s", err)
 		return
 	}
 
 	// set track id and label early so they can be set as new track information
 	// is received from the SDP.
-	receiver.Track().mu.Lock()
-	receiver.Track().id = incoming.id
-	receiver.Track().label = incoming.label
-	receiver.Track().mu.Unlock()
+	for _, t := range receiver.Tracks() {
+		t.mu.Lock()
+		t.id = incoming.id
+		t.label = incoming.label
+		t.mu.Unlock()
+	}
+
+	// We can't block and wait for a single SSRC
+	if incoming.ssrc == 0 {
+		return
+	}
 
 	go func() {
-		if err = receiver.Track().determinePayloadType(); err != nil {
+		if err := receiver.Track().determinePayloadType(); err != nil {
 			pc.log.Warnf("Could not determine PayloadType for SSRC %d", receiver.Track().SSRC())
 			return
 		}
 
-		pc.mu.RLock()
-		defer pc.mu.RUnlock()
+		pc.onTrackWithCodec(receiver.Track(), receiver)
+	}()
+}
 
-		codec, err := pc.api.mediaEngine.getCodec(receiver.Track().PayloadType())
-		if err != nil {
-			pc.log.Warnf("no codec could be found for payloadType %d", receiver.Track().PayloadType())
-			return
-		}
+// onTrackWithCodec resolves the codec of a remote Track from its PayloadType and fires OnTrack
+func (pc *PeerConnection) onTrackWithCodec(track *Track, receiver *RTPReceiver) {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	codec, err := pc.api.mediaEngine.getCodec(track.PayloadType())
+	if err != nil {
+		pc.log.Warnf("no codec could be found for payloadType %d", track.PayloadType())
+		return
+	}
 
-		receiver.Track().mu.Lock()
-		receiver.Track().kind = codec.Type
-		receiver.Track().codec = codec
-		receiver.Track().mu.Unlock()
+	track.mu.Lock()
+	track.kind = codec.Type
+	track.codec = codec
+	track.mu.Unlock()
 
-		if pc.onTrackHandler != nil {
-			pc.onTrack(receiver.Track(), receiver)
-		} else {
-			pc.log.Warnf("OnTrack unset, unable to handle incoming media streams")
-		}
-	}()
+	receiver.bindInterceptors(track)
+	if hasRTCPFeedback(codec.RTCPFeedback, TypeRTCPFBNACK, "") {
+		receiver.startNACKGenerator(track)
+	}
+
+	if pc.onTrackHandler != nil {
+		pc.onTrack(track, receiver)
+	} else {
+		pc.log.Warnf("OnTrack unset, unable to handle incoming media streams")
+	}
 }
 
 // startRTPReceivers opens knows inbound SRTP streams from the RemoteDescription
-func (pc *PeerConnection) startRTPReceivers(incomingTracks map[uint32]trackDetails, currentTransceivers []*RTPTransceiver) {
+func (pc *PeerConnection) startRTPReceivers(incomingTracks []trackDetails, currentTransceivers []*RTPTransceiver) {
 	localTransceivers := append([]*RTPTransceiver{}, currentTransceivers...)
 
 	remoteIsPlanB := false
@@ -933,17 +977,23 @@ func (pc *PeerConnection) startRTPReceivers(incomingTracks map[uint32]trackDetai
 	}
 
 	// Ensure we haven't already started a transceiver for this ssrc
-	for ssrc := range incomingTracks {
+	for _, incoming := range append([]trackDetails{}, incomingTracks...) {
+		if incoming.ssrc == 0 {
+			continue
+		}
+
 		for i := range localTransceivers {
-			if t := localTransceivers[i]; (t.Receiver()) == nil || t.Receiver().Track() == nil || t.Receiver().Track().ssrc != ssrc {
+			if t := localTransceivers[i]; (t.Receiver()) == nil || t.Receiver().Track() == nil || t.Receiver().Track().ssrc != incoming.ssrc {
 				continue
 			}
 
-			delete(incomingTracks, ssrc)
+			incomingTracks = filterTrackWithSSRC(incomingTracks, incoming.ssrc)
 		}
 	}
 
-	for ssrc, incoming := range incomingTracks {
+	unhandledTracks := []trackDetails{}
+	for _, incoming := range incomingTracks {
+		trackHandled := false
 		for i := range localTransceivers {
 			t := localTransceivers[i]
 
@@ -951,27 +1001,31 @@ func (pc *PeerConnection) startRTPReceivers(incomingTracks map[uint32]trackDetai
 				continue
 			}
 
-			if (incomingTracks[ssrc].kind != t.kind) ||
+			if (incoming.kind != t.kind) ||
 				(t.Direction() != RTPTransceiverDirectionRecvonly && t.Direction() != RTPTransceiverDirectionSendrecv) ||
 				(t.Receiver()) == nil ||
 				(t.Receiver().haveReceived()) {
 				continue
 			}
 
-			delete(incomingTracks, ssrc)
 			localTransceivers = append(localTransceivers[:i], localTransceivers[i+1:]...)
 			pc.startReceiver(incoming, t.Receiver())
+			trackHandled = true
 			break
 		}
+
+		if !trackHandled {
+			unhandledTracks = append(unhandledTracks, incoming)
+		}
 	}
 
 	if remoteIsPlanB {
-		for ssrc, incoming := range incomingTracks {
+		for _, incoming := range unhandledTracks {
 			t, err := pc.AddTransceiverFromKind(incoming.kind, RtpTransceiverInit{
 				Direction: RTPTransceiverDirectionSendrecv,
 			})
 			if err != nil {
-				pc.log.Warnf("Could not add transceiver for remote SSRC %d: %s", ssrc, err)
+				pc.log.Warnf("Could not add transceiver for remote SSRC %d: %s", incoming.ssrc, err)
 				continue
 			}
 			pc.startReceiver(incoming, t.Receiver())
@@ -984,20 +1038,91 @@ func (pc *PeerConnection) startRTPSenders(currentTransceivers []*RTPTransceiver)
 	for _, transceiver := range currentTransceivers {
 		// TODO(sgotti) when in future we'll avoid replacing a transceiver sender just check the transceiver negotiation status
 		if transceiver.Sender() != nil && transceiver.Sender().isNegotiated() && !transceiver.Sender().hasSent() {
+			encodings := []RTPEncodingParameters{}
+			for _, track := range transceiver.Sender().Tracks() {
+				coding := transceiver.Sender().codingParameters(track)
+				if codec := track.Codec(); codec != nil {
+					if negotiated, err := pc.api.mediaEngine.getCodecMatching(codec); err == nil {
+						coding.PayloadType = negotiated.PayloadType
+					}
+				}
+				if pc.negotiatedRTX(transceiver.Mid(), transceiver.kind, coding.PayloadType) {
+					coding.RTX.SSRC = transceiver.Sender().rtxSSRC(track)
+				}
+				encodings = append(encodings, RTPEncodingParameters{RTPCodingParameters: coding})
+			}
+
 			err := transceiver.Sender().Send(RTPSendParameters{
-				Encodings: RTPEncodingParameters{
-					RTPCodingParameters{
-						SSRC:        transceiver.Sender().track.SSRC(),
-						PayloadType: transceiver.Sender().track.PayloadType(),
-					},
-				}})
+				Encodings:        encodings,
+				HeaderExtensions: pc.negotiatedHeaderExtensions(transceiver.Mid(), transceiver.kind),
+			})
 			if err != nil {
 				pc.log.Warnf("Failed to start Sender: %s", err)
+				continue
+			}
+
+			// DTMF tones can only be sent if the remote accepts telephone-event
+			if dtmf := transceiver.Sender().DTMF(); dtmf != nil {
+				if codec := dtmf.getCodec(); codec != nil && !pc.negotiatedCodec(transceiver.Mid(), transceiver.kind, codec) {
+					dtmf.setCodec(nil)
+				}
 			}
 		}
 	}
 }
 
+// negotiatedHeaderExtensions returns the RTP header extensions negotiated for the media section with the given mid
+func (pc *PeerConnection) negotiatedHeaderExtensions(mid string, kind RTPCodecType) []RTPHeaderExtensionParameter {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	localDescription := pc.pendingLocalDescription
+	if localDescription == nil {
+		localDescription = pc.currentLocalDescription
+	}
+	remoteDescription := pc.pendingRemoteDescription
+	if remoteDescription == nil {
+		remoteDescription = pc.currentRemoteDescription
+	}
+	if localDescription == nil || remoteDescription == nil {
+		return []RTPHeaderExtensionParameter{}
+	}
+
+	return headerExtensionsFromSDP(localDescription.parsed, remoteDescription.parsed, mid, kind)
+}
+
+// negotiatedRTX returns true if the remote accepts RTX retransmissions of payloadType
+func (pc *PeerConnection) negotiatedRTX(mid string, kind RTPCodecType, payloadType uint8) bool {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	remoteDescription := pc.pendingRemoteDescription
+	if remoteDescription == nil {
+		remoteDescription = pc.currentRemoteDescription
+	}
+	if remoteDescription == nil {
+		return false
+	}
+
+	return rtxFromSDP(remoteDescription.parsed, mid, kind, payloadType)
+}
+
+// negotiatedCodec returns true if the remote accepts codec in the media section with the given mid
+func (pc *PeerConnection) negotiatedCodec(mid string, kind RTPCodecType, codec *RTPCodec) bool {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	remoteDescription := pc.pendingRemoteDescription
+	if remoteDescription == nil {
+		remoteDescription = pc.currentRemoteDescription
+	}
+	if remoteDescription == nil {
+		return false
+	}
+
+	return codecFromSDP(remoteDescription.parsed, mid, kind, codec)
+}
+
 // Start SCTP subsystem
 func (pc *PeerConnection) startSCTP() {
 	// Start sctp
@@ -1035,42 +1160,149 @@ func (pc *PeerConnection) startSCTP() {
 	pc.sctpTransport.lock.Unlock()
 }
 
-// drainSRTP pulls and discards RTP/RTCP packets that don't match any a:ssrc lines
-// If the remote SDP was only one media section the ssrc doesn't have to be explicitly declared
-func (pc *PeerConnection) drainSRTP() {
-	handleUndeclaredSSRC := func(ssrc uint32) bool {
-		if remoteDescription := pc.RemoteDescription(); remoteDescription != nil {
-			if len(remoteDescription.parsed.MediaDescriptions) == 1 {
-				onlyMediaSection := remoteDescription.parsed.MediaDescriptions[0]
-				for _, a := range onlyMediaSection.Attributes {
-					if a.Key == ssrcStr {
-						return false
-					}
-				}
+// handleUndeclaredSSRC starts a Track for a SSRC that wasn't declared with a:ssrc lines.
+// This is possible if the remote SDP has only one media section, or if the remote is sending
+// Simulcast and announces each stream with the MID and RTP Stream ID header extensions.
+func (pc *PeerConnection) handleUndeclaredSSRC(rtpStream *srtp.ReadStreamSRTP, ssrc uint32) error {
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil {
+		return fmt.Errorf("remote description has not been set yet")
+	}
+
+	// If the remote SDP was only one media section the ssrc doesn't have to be explicitly declared
+	if len(remoteDescription.parsed.MediaDescriptions) == 1 {
+		onlyMediaSection := remoteDescription.parsed.MediaDescriptions[0]
+		for _, a := range onlyMediaSection.Attributes {
+			if a.Key == ssrcStr {
+				return fmt.Errorf("single media section has an explicit SSRC")
+			}
+		}
 
-				incoming := trackDetails{
-					ssrc: ssrc,
-					kind: RTPCodecTypeVideo,
-				}
-				if onlyMediaSection.MediaName.Media == RTPCodecTypeAudio.String() {
-					incoming.kind = RTPCodecTypeAudio
-				}
+		incoming := trackDetails{
+			ssrc: ssrc,
+			kind: RTPCodecTypeVideo,
+		}
+		if onlyMediaSection.MediaName.Media == RTPCodecTypeAudio.String() {
+			incoming.kind = RTPCodecTypeAudio
+		}
 
-				t, err := pc.AddTransceiverFromKind(incoming.kind, RtpTransceiverInit{
-					Direction: RTPTransceiverDirectionSendrecv,
-				})
-				if err != nil {
-					pc.log.Warnf("Could not add transceiver for remote SSRC %d: %s", ssrc, err)
-					return false
-				}
-				pc.startReceiver(incoming, t.Receiver())
-				return true
+		t, err := pc.AddTransceiverFromKind(incoming.kind, RtpTransceiverInit{
+			Direction: RTPTransceiverDirectionSendrecv,
+		})
+		if err != nil {
+			return fmt.Errorf("could not add transceiver for remote SSRC %d: %s", ssrc, err)
+		}
+		pc.startReceiver(incoming, t.Receiver())
+		return nil
+	}
+
+	extMaps := extMapsFromSDP(remoteDescription.parsed)
+	midExtension, haveMidExtension := extMaps[sdp.SDESMidURI]
+	streamIDExtension, haveStreamIDExtension := extMaps[sdp.SDESRTPStreamIDURI]
+	if !haveMidExtension || !haveStreamIDExtension {
+		return fmt.Errorf("SSRC is undeclared and the MID and RTP Stream ID header extensions have not been negotiated")
+	}
+
+	// The RTX streams of Simulcast carry the RID of the stream they repair instead
+	repairedStreamIDExtensionID := uint8(0)
+	if e, ok := extMaps[RepairedRTPStreamIDURI]; ok {
+		repairedStreamIDExtensionID = uint8(e.Value)
+	}
+
+	// The probed packets are read from the Track first, so it starts with the first packet
+	b := make([]byte, receiveMTU)
+	var mid, rid, repairedRid string
+	probed := [][]byte{}
+	for readCount := 0; readCount <= simulcastProbeCount; readCount++ {
+		i, err := rtpStream.Read(b)
+		if err != nil {
+			return err
+		}
+		probed = append(probed, append([]byte{}, b[:i]...))
+
+		maybeMid, maybeRid, payloadType, err := handleUnknownRTPPacket(b[:i], uint8(midExtension.Value), uint8(streamIDExtension.Value))
+		if err != nil {
+			return err
+		}
+
+		if maybeMid != "" {
+			mid = maybeMid
+		}
+		if maybeRid != "" {
+			rid = maybeRid
+		}
+		if maybeRepairedRid := repairedRTPStreamID(b[:i], repairedStreamIDExtensionID); maybeRepairedRid != "" {
+			repairedRid = maybeRepairedRid
+		}
+
+		if mid == "" || (rid == "" && repairedRid == "") {
+			continue
+		}
+
+		for _, t := range pc.GetTransceivers() {
+			if t.Mid() != mid || t.Receiver() == nil {
+				continue
+			}
+
+			if rid == "" {
+				return t.Receiver().receiveRTXForRID(repairedRid, ssrc)
+			}
+
+			track, err := t.Receiver().receiveForRID(rid, ssrc, probed)
+			if err != nil {
+				return err
 			}
+
+			track.mu.Lock()
+			track.payloadType = payloadType
+			track.mu.Unlock()
+
+			pc.onTrackWithCodec(track, t.Receiver())
+			return nil
 		}
 
-		return false
+		return fmt.Errorf("no transceiver found for MID %s", mid)
+	}
+
+	return fmt.Errorf("MID and RID were not found in the first %d packets", simulcastProbeCount)
+}
+
+// handleUnknownRTPPacket parses the MID and RTP Stream ID header extensions of a packet
+// belonging to an SSRC that isn't known yet
+func handleUnknownRTPPacket(buf []byte, midExtensionID, streamIDExtensionID uint8) (mid, rid string, payloadType uint8, err error) {
+	rp := &rtp.Packet{}
+	if err = rp.Unmarshal(buf); err != nil {
+		return
+	}
+
+	if !rp.Header.Extension {
+		return
+	}
+
+	payloadType = rp.PayloadType
+	if payload := rp.GetExtension(midExtensionID); payload != nil {
+		mid = string(payload)
 	}
 
+	if payload := rp.GetExtension(streamIDExtensionID); payload != nil {
+		rid = string(payload)
+	}
+
+	return
+}
+
+// repairedRTPStreamID returns the RID in the repaired RTP Stream ID header extension of a packet
+func repairedRTPStreamID(buf []byte, repairedStreamIDExtensionID uint8) string {
+	rp := &rtp.Packet{}
+	if repairedStreamIDExtensionID == 0 || rp.Unmarshal(buf) != nil || !rp.Header.Extension {
+		return ""
+	}
+	return string(rp.GetExtension(repairedStreamIDExtensionID))
+}
+
+// drainSRTP pulls and discards RTP/RTCP packets that don't match any a:ssrc lines
+// If the remote SDP was only one media section the ssrc doesn't have to be explicitly declared
+func (pc *PeerConnection) drainSRTP() {
 	go func() {
 		for {
 			srtpSession, err := pc.dtlsTransport.getSRTPSession()
@@ -1079,15 +1311,18 @@ func (pc *PeerConnection) drainSRTP() {
 				return
 			}
 
-			_, ssrc, err := srtpSession.AcceptStream()
+			rtpStream, ssrc, err := srtpSession.AcceptStream()
 			if err != nil {
 				pc.log.Warnf("Failed to accept RTP %v", err)
 				return
 			}
 
-			if !handleUndeclaredSSRC(ssrc) {
-				pc.log.Warnf("Incoming unhandled RTP ssrc(%d), OnTrack will not be fired", ssrc)
-			}
+			// Probing for the MID and RID blocks until packets arrive, don't hold up other streams
+			go func(rtpStream *srtp.ReadStreamSRTP, ssrc uint32) {
+				if err := pc.handleUndeclaredSSRC(rtpStream, ssrc); err != nil {
+					pc.log.Warnf("Incoming unhandled RTP ssrc(%d), OnTrack will not be fired. %v", ssrc, err)
+				}
+			}(rtpStream, ssrc)
 		}
 	}()
 
@@ -1304,10 +1539,16 @@ func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTrans
 	}
 
 	direction := RTPTransceiverDirectionSendrecv
+	var sendEncodings []RTPEncodingParameters
 	if len(init) > 1 {
 		return nil, fmt.Errorf("AddTransceiverFromTrack only accepts one RtpTransceiverInit")
 	} else if len(init) == 1 {
 		direction = init[0].Direction
+		sendEncodings = init[0].SendEncodings
+	}
+
+	if err := validateSendEncodings(track, sendEncodings); err != nil {
+		return nil, err
 	}
 
 	switch direction {
@@ -1322,12 +1563,14 @@ func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTrans
 			return nil, err
 		}
 
-		return pc.newRTPTransceiver(
+		t := pc.newRTPTransceiver(
 			receiver,
 			sender,
 			RTPTransceiverDirectionSendrecv,
 			track.Kind(),
-		), nil
+		)
+		t.sendEncodings = sendEncodings
+		return t, nil
 
 	case RTPTransceiverDirectionSendonly:
 		sender, err := pc.api.NewRTPSender(track, pc.dtlsTransport)
@@ -1335,17 +1578,48 @@ func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTrans
 			return nil, err
 		}
 
-		return pc.newRTPTransceiver(
+		t := pc.newRTPTransceiver(
 			nil,
 			sender,
 			RTPTransceiverDirectionSendonly,
 			track.Kind(),
-		), nil
+		)
+		t.sendEncodings = sendEncodings
+		return t, nil
 	default:
 		return nil, fmt.Errorf("AddTransceiverFromTrack currently only supports sendonly and sendrecv")
 	}
 }
 
+// validateSendEncodings checks the SendEncodings of a RtpTransceiverInit. When sending
+// Simulcast every encoding must have a unique RID, and the first one is sent by track.
+// The remaining encodings are added with RTPSender.AddEncoding
+func validateSendEncodings(track *Track, sendEncodings []RTPEncodingParameters) error {
+	if len(sendEncodings) == 0 {
+		return nil
+	} else if len(sendEncodings) == 1 && sendEncodings[0].RID == "" {
+		if track.RID() != "" {
+			return fmt.Errorf("SendEncodings has no RID but Track has RID %s", track.RID())
+		}
+		return nil
+	}
+
+	rids := map[string]bool{}
+	for _, encoding := range sendEncodings {
+		if encoding.RID == "" {
+			return fmt.Errorf("every entry of SendEncodings must have a RID when sending Simulcast")
+		} else if rids[encoding.RID] {
+			return fmt.Errorf("SendEncodings contains duplicate RID %s", encoding.RID)
+		}
+		rids[encoding.RID] = true
+	}
+
+	if track.RID() != sendEncodings[0].RID {
+		return fmt.Errorf("Track RID %q must match the first entry of SendEncodings %q", track.RID(), sendEncodings[0].RID)
+	}
+	return nil
+}
+
 // CreateDataChannel creates a new DataChannel object with the given label
 // and optional DataChannelInit used to configure properties of the
 // underlying channel such as data reliability.
@@ -1432,25 +1706,7 @@ func (pc *PeerConnection) SetIdentityProvider(provider string) error {
 // WriteRTCP sends a user provided RTCP packet to the connected peer
 // If no peer is connected the packet is discarded
 func (pc *PeerConnection) WriteRTCP(pkts []rtcp.Packet) error {
-	raw, err := rtcp.Marshal(pkts)
-	if err != nil {
-		return err
-	}
-
-	srtcpSession, err := pc.dtlsTransport.getSRTCPSession()
-	if err != nil {
-		return nil
-	}
-
-	writeStream, err := srtcpSession.OpenWriteStream()
-	if err != nil {
-		return fmt.Errorf("WriteRTCP failed to open WriteStream: %v", err)
-	}
-
-	if _, err := writeStream.Write(raw); err != nil {
-		return err
-	}
-	return nil
+	return pc.dtlsTransport.writeRTCP(pkts)
 }
 
 // Close ends the PeerConnection
@@ -1507,16 +1763,32 @@ func (pc *PeerConnection) Close() error {
 	return util.FlattenErrs(closeErrs)
 }
 
-// NewTrack Creates a new Track
+// NewTrack Creates a new Track. payloadType selects the codec among the ones of the PeerConnection,
+// once a remote offer was set these have the PayloadTypes of the offer. Packets are sent with the
+// PayloadType negotiated for the codec of the Track.
 func (pc *PeerConnection) NewTrack(payloadType uint8, ssrc uint32, id, label string) (*Track, error) {
+	return pc.NewTrackWithRID(payloadType, ssrc, id, label, "")
+}
+
+// NewTrackWithRID Creates a new Track that is sent as the Simulcast encoding identified by rid.
+// The Payloader registered with the MediaEngine for the mime type of the codec replaces its own,
+// a codec without either uses the built-in one. If there is none only RTP packets can be written
+// to the Track
+func (pc *PeerConnection) NewTrackWithRID(payloadType uint8, ssrc uint32, id, label, rid string) (*Track, error) {
 	codec, err := pc.api.mediaEngine.getCodec(payloadType)
 	if err != nil {
 		return nil, err
-	} else if codec.Payloader == nil {
-		return nil, fmt.Errorf("codec payloader not set")
 	}
 
-	return NewTrack(payloadType, ssrc, id, label, codec)
+	if _, registered := pc.api.mediaEngine.payloaders[strings.ToLower(codec.mimeType())]; registered || codec.Payloader == nil {
+		if payloader, err := pc.api.mediaEngine.NewPayloader(codec); err == nil {
+			withPayloader := *codec
+			withPayloader.Payloader = payloader
+			codec = &withPayloader
+		}
+	}
+
+	return NewTrackWithRID(payloadType, ssrc, id, label, rid, codec)
 }
 
 func (pc *PeerConnection) newRTPTransceiver(
@@ -1525,7 +1797,7 @@ func (pc *PeerConnection) newRTPTransceiver(
 	direction RTPTransceiverDirection,
 	kind RTPCodecType,
 ) *RTPTransceiver {
-	t := &RTPTransceiver{kind: kind}
+	t := &RTPTransceiver{kind: kind, api: pc.api}
 	t.setReceiver(receiver)
 	t.setSender(sender)
 	t.setDirection(direction)
@@ -1639,6 +1911,15 @@ func (pc *PeerConnection) GetStats() StatsReport {
 		pc.sctpTransport.collectStats(statsCollector)
 	}
 
+	for _, transceiver := range pc.rtpTransceivers {
+		if sender := transceiver.Sender(); sender != nil {
+			sender.collectStats(statsCollector)
+		}
+		if receiver := transceiver.Receiver(); receiver != nil {
+			receiver.collectStats(statsCollector)
+		}
+	}
+
 	stats := PeerConnectionStats{
 		Timestamp:             statsTimestampNow(),
 		Type:                  StatsTypePeerConnection,
@@ -1685,23 +1966,29 @@ func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, re
 
 func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDescription) {
 	currentTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
-	trackDetails := trackDetailsFromSDP(pc.log, remoteDesc.parsed)
+	incomingTracks := trackDetailsFromSDP(pc.log, remoteDesc.parsed)
 	if isRenegotiation {
 		for _, t := range currentTransceivers {
 			if t.Receiver() == nil || t.Receiver().Track() == nil {
 				continue
 			}
 
-			t.Receiver().Track().mu.Lock()
-			ssrc := t.Receiver().Track().ssrc
-			if _, ok := trackDetails[ssrc]; ok {
-				incoming := trackDetails[ssrc]
-				t.Receiver().Track().id = incoming.id
-				t.Receiver().Track().label = incoming.label
-				t.Receiver().Track().mu.Unlock()
+			var incoming *trackDetails
+			if rid := t.Receiver().Track().RID(); rid != "" {
+				incoming = trackDetailsForRID(incomingTracks, t.Mid(), rid)
+			} else {
+				incoming = trackDetailsForSSRC(incomingTracks, t.Receiver().Track().SSRC())
+			}
+
+			if incoming != nil {
+				for _, track := range t.Receiver().Tracks() {
+					track.mu.Lock()
+					track.id = incoming.id
+					track.label = incoming.label
+					track.mu.Unlock()
+				}
 				continue
 			}
-			t.Receiver().Track().mu.Unlock()
 
 			if err := t.Receiver().Stop(); err != nil {
 				pc.log.Warnf("Failed to stop RtpReceiver: %s", err)
@@ -1717,7 +2004,7 @@ func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDesc
 		}
 	}
 
-	pc.startRTPReceivers(trackDetails, currentTransceivers)
+	pc.startRTPReceivers(incomingTracks, currentTransceivers)
 	pc.startRTPSenders(currentTransceivers)
 
 	if !isRenegotiation {
@@ -1770,10 +2057,10 @@ func (pc *PeerConnection) generateUnmatchedSDP(useIdentity bool) (*sdp.SessionDe
 		}
 
 		if len(video) > 0 {
-			mediaSections = append(mediaSections, mediaSection{id: "video", transceivers: video})
+			mediaSections = append(mediaSections, mediaSection{id: "video", transceivers: video, extMaps: extMapsForMediaSection(pc.api.mediaEngine, video[0], nil, false, true), codecs: codecsForMediaSection(pc.api.mediaEngine, video[0], nil, true)})
 		}
 		if len(audio) > 0 {
-			mediaSections = append(mediaSections, mediaSection{id: "audio", transceivers: audio})
+			mediaSections = append(mediaSections, mediaSection{id: "audio", transceivers: audio, extMaps: extMapsForMediaSection(pc.api.mediaEngine, audio[0], nil, false, true), codecs: codecsForMediaSection(pc.api.mediaEngine, audio[0], nil, true)})
 		}
 		mediaSections = append(mediaSections, mediaSection{id: "data", data: true})
 	} else {
@@ -1781,12 +2068,12 @@ func (pc *PeerConnection) generateUnmatchedSDP(useIdentity bool) (*sdp.SessionDe
 			if t.Sender() != nil {
 				t.Sender().setNegotiated()
 			}
-			mediaSections = append(mediaSections, mediaSection{id: t.Mid(), transceivers: []*RTPTransceiver{t}})
+			mediaSections = append(mediaSections, mediaSection{id: t.Mid(), transceivers: []*RTPTransceiver{t}, extMaps: extMapsForMediaSection(pc.api.mediaEngine, t, nil, isSendingSimulcast(t), true), codecs: codecsForMediaSection(pc.api.mediaEngine, t, nil, true)})
 		}
 
 		mediaSections = append(mediaSections, mediaSection{id: strconv.Itoa(len(mediaSections)), data: true})
 	}
-	return populateSDP(d, isPlanB, pc.api.settingEngine.candidates.ICELite, pc.api.settingEngine.candidates.ICETrickle, pc.api.mediaEngine, connectionRoleFromDtlsRole(defaultDtlsRoleOffer), candidates, iceParams, mediaSections, pc.ICEGatheringState())
+	return populateSDP(d, isPlanB, pc.api.settingEngine.candidates.ICELite, pc.api.settingEngine.candidates.ICETrickle, connectionRoleFromDtlsRole(defaultDtlsRoleOffer), candidates, iceParams, mediaSections, pc.ICEGatheringState())
 }
 
 // generateMatchedSDP generates a SDP and takes the remote state into account
@@ -1810,6 +2097,7 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 	var t *RTPTransceiver
 	localTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
 	detectedPlanB := descriptionIsPlanB(pc.RemoteDescription())
+	remoteExtMaps := extMapsFromSDP(pc.RemoteDescription().parsed)
 	mediaSections := []mediaSection{}
 
 	for _, media := range pc.RemoteDescription().parsed.MediaDescriptions {
@@ -1829,6 +2117,13 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 			continue
 		}
 
+		// When answering only the extensions offered in this media section can be accepted
+		mediaExtMaps := remoteExtMaps
+		if !includeUnmatched {
+			mediaExtMaps = extMapsFromMediaDescription(pc.RemoteDescription().parsed, media)
+		}
+		remoteCodecs := codecsFromMediaDescription(pc.RemoteDescription().parsed, media)
+
 		sdpSemantics := pc.configuration.SDPSemantics
 
 		switch {
@@ -1855,7 +2150,7 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 				}
 				mediaTransceivers = append(mediaTransceivers, t)
 			}
-			mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: mediaTransceivers})
+			mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: mediaTransceivers, extMaps: extMapsForMediaSection(pc.api.mediaEngine, mediaTransceivers[0], mediaExtMaps, false, includeUnmatched), codecs: codecsForMediaSection(pc.api.mediaEngine, mediaTransceivers[0], remoteCodecs, includeUnmatched)})
 		case sdpSemantics == SDPSemanticsUnifiedPlan || sdpSemantics == SDPSemanticsUnifiedPlanWithFallback:
 			if detectedPlanB {
 				return nil, &rtcerr.TypeError{Err: ErrIncorrectSDPSemantics}
@@ -1868,7 +2163,9 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 				t.Sender().setNegotiated()
 			}
 			mediaTransceivers := []*RTPTransceiver{t}
-			mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: mediaTransceivers})
+			rids := getRids(media)
+			isSimulcast := len(rids) > 0 || isSendingSimulcast(t)
+			mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: mediaTransceivers, rids: rids, extMaps: extMapsForMediaSection(pc.api.mediaEngine, t, mediaExtMaps, isSimulcast, includeUnmatched), codecs: codecsForMediaSection(pc.api.mediaEngine, t, remoteCodecs, includeUnmatched)})
 		}
 	}
 
@@ -1878,7 +2175,7 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 			if t.Sender() != nil {
 				t.Sender().setNegotiated()
 			}
-			mediaSections = append(mediaSections, mediaSection{id: t.Mid(), transceivers: []*RTPTransceiver{t}})
+			mediaSections = append(mediaSections, mediaSection{id: t.Mid(), transceivers: []*RTPTransceiver{t}, extMaps: extMapsForMediaSection(pc.api.mediaEngine, t, remoteExtMaps, isSendingSimulcast(t), true), codecs: codecsForMediaSection(pc.api.mediaEngine, t, nil, true)})
 		}
 	}
 
@@ -1886,5 +2183,5 @@ func (pc *PeerConnection) generateMatchedSDP(useIdentity bool, includeUnmatched
 		pc.log.Info("Plan-B Offer detected; responding with Plan-B Answer")
 	}
 
-	return populateSDP(d, detectedPlanB, pc.api.settingEngine.candidates.ICELite, pc.api.settingEngine.candidates.ICETrickle, pc.api.mediaEngine, connectionRole, candidates, iceParams, mediaSections, pc.ICEGatheringState())
+	return populateSDP(d, detectedPlanB, pc.api.settingEngine.candidates.ICELite, pc.api.settingEngine.candidates.ICETrickle, connectionRole, candidates, iceParams, mediaSections, pc.ICEGatheringState())
 }
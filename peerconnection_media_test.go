@@ -5,6 +5,7 @@ package webrtc
 import (
 	"bufio"
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"math/rand"
@@ -15,9 +16,12 @@ import (
 	"time"
 
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/sdp/v2"
+	"github.com/pion/srtp"
 	"github.com/pion/transport/test"
 	"github.com/pion/webrtc/v2/pkg/media"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -770,12 +774,12 @@ func TestAddTransceiverAddTrack_Reuse(t *testing.T) {
 	track1, sender1 := addTrack()
 	assert.Equal(t, 1, len(pc.GetTransceivers()))
 	assert.Equal(t, sender1, tr.Sender())
-	assert.Equal(t, track1, tr.Sender().track)
+	assert.Equal(t, track1, tr.Sender().Track())
 	require.NoError(t, pc.RemoveTrack(sender1))
 
 	track2, _ := addTrack()
 	assert.Equal(t, 1, len(pc.GetTransceivers()))
-	assert.Equal(t, track2, tr.Sender().track)
+	assert.Equal(t, track2, tr.Sender().Track())
 
 	addTrack()
 	assert.Equal(t, 2, len(pc.GetTransceivers()))
@@ -963,7 +967,7 @@ a=setup:actpass
 a=mid:1
 a=sendrecv
 a=rtpmap:96 H264/90000
-a=fmtp:96 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=640c1f
+a=fmtp:96 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42001f
 `
 	lim := test.TimeOut(time.Second * 30)
 	defer lim.Stop()
@@ -1240,3 +1244,2085 @@ func TestPeerConnection_Start_Right_Receiver(t *testing.T) {
 	assert.NoError(t, pcOffer.Close())
 	assert.NoError(t, pcAnswer.Close())
 }
+
+func TestHandleUnknownRTPPacket(t *testing.T) {
+	const (
+		midExtensionID      = 3
+		streamIDExtensionID = 4
+	)
+
+	header := rtp.Header{
+		Version:     2,
+		PayloadType: DefaultPayloadTypeVP8,
+		SSRC:        5000,
+	}
+	assert.NoError(t, header.SetExtension(midExtensionID, []byte("0")))
+	assert.NoError(t, header.SetExtension(streamIDExtensionID, []byte("q")))
+
+	raw, err := (&rtp.Packet{Header: header, Payload: []byte{0x00}}).Marshal()
+	assert.NoError(t, err)
+
+	mid, rid, payloadType, err := handleUnknownRTPPacket(raw, midExtensionID, streamIDExtensionID)
+	assert.NoError(t, err)
+	assert.Equal(t, "0", mid)
+	assert.Equal(t, "q", rid)
+	assert.Equal(t, uint8(DefaultPayloadTypeVP8), payloadType)
+
+	raw, err = (&rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 5000}, Payload: []byte{0x00}}).Marshal()
+	assert.NoError(t, err)
+
+	mid, rid, _, err = handleUnknownRTPPacket(raw, midExtensionID, streamIDExtensionID)
+	assert.NoError(t, err)
+	assert.Equal(t, "", mid)
+	assert.Equal(t, "", rid)
+
+	_, _, _, err = handleUnknownRTPPacket([]byte{0x00}, midExtensionID, streamIDExtensionID)
+	assert.Error(t, err)
+}
+
+func TestRepairedRTPStreamID(t *testing.T) {
+	const repairedStreamIDExtensionID = 5
+
+	header := rtp.Header{Version: 2, PayloadType: 97, SSRC: 5001}
+	assert.NoError(t, header.SetExtension(repairedStreamIDExtensionID, []byte("q")))
+
+	raw, err := (&rtp.Packet{Header: header, Payload: []byte{0x00, 0x01}}).Marshal()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "q", repairedRTPStreamID(raw, repairedStreamIDExtensionID))
+	assert.Equal(t, "", repairedRTPStreamID(raw, 0), "extension wasn't negotiated")
+	assert.Equal(t, "", repairedRTPStreamID([]byte{0x00}, repairedStreamIDExtensionID))
+}
+
+func TestRTPReceiver_receiveRTXForRID(t *testing.T) {
+	r := &RTPReceiver{tracks: []trackStreams{{track: &Track{rid: "f"}}, {track: &Track{rid: "q"}}}}
+
+	// The stream it repairs hasn't arrived yet, so the RTX stream is received along with it
+	assert.NoError(t, r.receiveRTXForRID("q", 5001))
+	assert.Equal(t, uint32(5001), r.tracks[1].repairSSRC)
+	assert.Equal(t, uint32(0), r.tracks[0].repairSSRC)
+
+	assert.Error(t, r.receiveRTXForRID("q", 5002), "RTX stream is already being received")
+	assert.Error(t, r.receiveRTXForRID("h", 5003), "no Track with the RID")
+}
+
+func TestRTPReceiver_RepairedPacketJitter(t *testing.T) {
+	track := &Track{ssrc: 5000, codec: &RTPCodec{RTPCodecCapability: RTPCodecCapability{ClockRate: 90000}}}
+	stats := newReceptionStats()
+	packets := make(chan receivedPacket, 3)
+	r := &RTPReceiver{
+		closed:  make(chan interface{}),
+		sources: newRTPSources(),
+		tracks: []trackStreams{{
+			track:          track,
+			rtpReadStream:  &srtp.ReadStreamSRTP{},
+			receptionStats: stats,
+			packets:        packets,
+		}},
+	}
+
+	for i, repaired := range []bool{false, false, true} {
+		raw, err := (&rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: uint16(i), SSRC: 5000}}).Marshal()
+		assert.NoError(t, err)
+		packets <- receivedPacket{data: raw, repaired: repaired}
+	}
+
+	b := make([]byte, receiveMTU)
+	for i := 0; i < 2; i++ {
+		_, err := r.readStreamRTP(b, track)
+		assert.NoError(t, err)
+	}
+	jitter := stats.getStats().jitter
+
+	// The retransmission arrives late, it is counted but doesn't change the jitter
+	time.Sleep(20 * time.Millisecond)
+	_, err := r.readStreamRTP(b, track)
+	assert.NoError(t, err)
+	assert.Equal(t, jitter, stats.getStats().jitter)
+	assert.Equal(t, uint32(3), stats.getStats().packetsReceived)
+}
+
+func TestPeerConnection_Simulcast_AnswerAcceptsRIDs(t *testing.T) {
+	const simulcastOffer = `v=0
+o=- 4215775240449105457 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0
+a=msid-semantic: WMS
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=ice-ufrag:TmXu
+a=ice-pwd:tNLh6jLZJH9ZBu9unxRJfsRC
+a=fingerprint:sha-256 F9:20:9E:6A:F5:9E:B6:51:11:92:70:15:5D:1B:A9:11:F3:10:31:97:81:4D:CB:05:B8:93:D9:83:5F:0C:36:AD
+a=setup:actpass
+a=mid:0
+a=extmap:4 urn:ietf:params:rtp-hdrext:sdes:mid
+a=extmap:10 urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id
+a=extmap:11 urn:ietf:params:rtp-hdrext:sdes:repaired-rtp-stream-id
+a=sendonly
+a=msid:stream track
+a=rtcp-mux
+a=rtpmap:96 VP8/90000
+a=rid:f send
+a=rid:h send
+a=rid:q send
+a=simulcast:send f;h;q
+`
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pc, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: simulcastOffer}))
+
+	answer, err := pc.CreateAnswer(nil)
+	assert.NoError(t, err)
+
+	parsed := sdp.SessionDescription{}
+	assert.NoError(t, parsed.Unmarshal([]byte(answer.SDP)))
+	assert.Equal(t, 1, len(parsed.MediaDescriptions))
+
+	media := parsed.MediaDescriptions[0]
+	simulcast, ok := media.Attribute(sdpAttributeSimulcast)
+	assert.True(t, ok)
+	assert.Equal(t, "recv f;h;q", simulcast)
+
+	rids := []string{}
+	for _, a := range media.Attributes {
+		if a.Key == sdpAttributeRid {
+			rids = append(rids, a.Value)
+		}
+	}
+	assert.Equal(t, []string{"f recv", "h recv", "q recv"}, rids)
+
+	extMaps := extMapsFromSDP(&parsed)
+	assert.Equal(t, 4, extMaps[sdp.SDESMidURI].Value)
+	assert.Equal(t, 10, extMaps[sdp.SDESRTPStreamIDURI].Value)
+	assert.Equal(t, 11, extMaps[RepairedRTPStreamIDURI].Value)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestPeerConnection_Simulcast_Send(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	rids := []string{"f", "h", "q"}
+	sendEncodings := []RTPEncodingParameters{}
+	tracks := []*Track{}
+	for _, rid := range rids {
+		sendEncodings = append(sendEncodings, RTPEncodingParameters{RTPCodingParameters: RTPCodingParameters{RID: rid}})
+
+		track, trackErr := pcOffer.NewTrackWithRID(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion", rid)
+		assert.NoError(t, trackErr)
+		tracks = append(tracks, track)
+	}
+
+	transceiver, err := pcOffer.AddTransceiverFromTrack(tracks[0], RtpTransceiverInit{
+		Direction:     RTPTransceiverDirectionSendonly,
+		SendEncodings: sendEncodings,
+	})
+	assert.NoError(t, err)
+	for _, track := range tracks[1:] {
+		assert.NoError(t, transceiver.Sender().AddEncoding(track))
+	}
+
+	unrequested, err := pcOffer.NewTrackWithRID(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion", "x")
+	assert.NoError(t, err)
+	assert.Error(t, transceiver.Sender().AddEncoding(unrequested))
+
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	var ridMapLock sync.Mutex
+	ridMap := map[string]bool{}
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		ridMapLock.Lock()
+		defer ridMapLock.Unlock()
+		ridMap[track.RID()] = true
+	})
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=simulcast:send f;h;q")
+	assert.Contains(t, offer.SDP, "a=rid:h send")
+	assert.Contains(t, offer.SDP, "a=extmap:4 "+sdp.SDESRTPStreamIDURI)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	for sequenceNumber := uint16(0); ; sequenceNumber++ {
+		time.Sleep(20 * time.Millisecond)
+
+		for _, track := range tracks {
+			assert.NoError(t, track.WriteRTP(&rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					SequenceNumber: sequenceNumber,
+					PayloadType:    DefaultPayloadTypeVP8,
+					SSRC:           track.SSRC(),
+				},
+				Payload: []byte{0x00},
+			}))
+		}
+
+		ridMapLock.Lock()
+		received := len(ridMap)
+		ridMapLock.Unlock()
+		if received == len(rids) {
+			break
+		}
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// The packets probed for the RID of a Simulcast stream are read from its Track
+func TestPeerConnection_Simulcast_ProbedPackets(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	rids := []string{"f", "h"}
+	sendEncodings := []RTPEncodingParameters{}
+	tracks := []*Track{}
+	for _, rid := range rids {
+		sendEncodings = append(sendEncodings, RTPEncodingParameters{RTPCodingParameters: RTPCodingParameters{RID: rid}})
+
+		track, trackErr := pcOffer.NewTrackWithRID(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion", rid)
+		assert.NoError(t, trackErr)
+		tracks = append(tracks, track)
+	}
+
+	transceiver, err := pcOffer.AddTransceiverFromTrack(tracks[0], RtpTransceiverInit{
+		Direction:     RTPTransceiverDirectionSendonly,
+		SendEncodings: sendEncodings,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, transceiver.Sender().AddEncoding(tracks[1]))
+
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	onTrack := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		onTrack <- track
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// Every packet carries the RID, so the Track is received with the first packet that arrives
+	var remoteTrack *Track
+	for sequenceNumber := uint16(0); remoteTrack == nil; sequenceNumber++ {
+		assert.NoError(t, tracks[0].WriteRTP(&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				SequenceNumber: sequenceNumber,
+				PayloadType:    DefaultPayloadTypeVP8,
+				SSRC:           tracks[0].SSRC(),
+			},
+			Payload: []byte{0x00},
+		}))
+
+		select {
+		case remoteTrack = <-onTrack:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	// No packets are written anymore, the packet the RID was probed from is read
+	read := make(chan error, 1)
+	go func() {
+		_, readErr := remoteTrack.ReadRTP()
+		read <- readErr
+	}()
+	select {
+	case readErr := <-read:
+		assert.NoError(t, readErr)
+		assert.NoError(t, pcOffer.Close())
+		assert.NoError(t, pcAnswer.Close())
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the probed packet was not read from the Track")
+		assert.NoError(t, pcOffer.Close())
+		assert.NoError(t, pcAnswer.Close())
+		<-read
+	}
+}
+
+func TestPeerConnection_HeaderExtensions(t *testing.T) {
+	const audioLevelURI = "urn:ietf:params:rtp-hdrext:ssrc-audio-level"
+
+	t.Run("Negotiated", func(t *testing.T) {
+		lim := test.TimeOut(time.Second * 30)
+		defer lim.Stop()
+
+		report := test.CheckRoutines(t)
+		defer report()
+
+		offerMediaEngine := MediaEngine{}
+		offerMediaEngine.RegisterDefaultCodecs()
+		assert.NoError(t, offerMediaEngine.RegisterHeaderExtension(sdp.ABSSendTimeURI, RTPCodecTypeVideo))
+		assert.NoError(t, offerMediaEngine.RegisterHeaderExtension(audioLevelURI, RTPCodecTypeVideo))
+
+		answerMediaEngine := MediaEngine{}
+		answerMediaEngine.RegisterDefaultCodecs()
+		assert.NoError(t, answerMediaEngine.RegisterHeaderExtension(sdp.ABSSendTimeURI, RTPCodecTypeVideo))
+
+		pcOffer, err := NewAPI(WithMediaEngine(offerMediaEngine)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		pcAnswer, err := NewAPI(WithMediaEngine(answerMediaEngine)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+		assert.NoError(t, err)
+		sender, err := pcOffer.AddTrack(track)
+		assert.NoError(t, err)
+
+		_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+		assert.NoError(t, err)
+
+		offer, err := pcOffer.CreateOffer(nil)
+		assert.NoError(t, err)
+		assert.Contains(t, offer.SDP, "a=extmap:1 "+sdp.ABSSendTimeURI)
+		assert.Contains(t, offer.SDP, "a=extmap:5 "+audioLevelURI)
+
+		assert.NoError(t, signalPair(pcOffer, pcAnswer))
+		assert.NotContains(t, pcAnswer.LocalDescription().SDP, audioLevelURI)
+
+		pcOffer.ops.Done()
+		pcAnswer.ops.Done()
+
+		expected := []RTPHeaderExtensionParameter{{URI: sdp.ABSSendTimeURI, ID: 1}}
+		assert.Equal(t, expected, sender.GetParameters().HeaderExtensions)
+		assert.Equal(t, expected, pcAnswer.GetTransceivers()[0].Receiver().GetParameters().HeaderExtensions)
+
+		assert.NoError(t, pcOffer.Close())
+		assert.NoError(t, pcAnswer.Close())
+	})
+
+	t.Run("Answer uses remote IDs", func(t *testing.T) {
+		const offer = `v=0
+o=- 4215775240449105457 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0
+m=audio 9 UDP/TLS/RTP/SAVPF 111
+c=IN IP4 0.0.0.0
+a=ice-ufrag:TmXu
+a=ice-pwd:tNLh6jLZJH9ZBu9unxRJfsRC
+a=fingerprint:sha-256 F9:20:9E:6A:F5:9E:B6:51:11:92:70:15:5D:1B:A9:11:F3:10:31:97:81:4D:CB:05:B8:93:D9:83:5F:0C:36:AD
+a=setup:actpass
+a=mid:0
+a=extmap:1 urn:ietf:params:rtp-hdrext:ssrc-audio-level
+a=extmap:9 urn:ietf:params:rtp-hdrext:sdes:mid
+a=extmap:14 http://www.webrtc.org/experiments/rtp-hdrext/abs-send-time
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:111 opus/48000/2
+`
+
+		m := MediaEngine{}
+		m.RegisterDefaultCodecs()
+		assert.NoError(t, m.RegisterHeaderExtension(audioLevelURI, RTPCodecTypeAudio))
+		assert.NoError(t, m.RegisterHeaderExtension(sdp.SDESMidURI, RTPCodecTypeAudio))
+		assert.NoError(t, m.RegisterHeaderExtension(sdp.TransportCCURI, RTPCodecTypeAudio))
+
+		pc, err := NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: offer}))
+		answer, err := pc.CreateAnswer(nil)
+		assert.NoError(t, err)
+
+		parsed := sdp.SessionDescription{}
+		assert.NoError(t, parsed.Unmarshal([]byte(answer.SDP)))
+		extMaps := extMapsFromSDP(&parsed)
+		assert.Equal(t, 2, len(extMaps))
+		assert.Equal(t, 1, extMaps[audioLevelURI].Value)
+		assert.Equal(t, 9, extMaps[sdp.SDESMidURI].Value)
+
+		assert.NoError(t, pc.Close())
+	})
+}
+
+func TestPeerConnection_NACKResponder(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.Error(t, sender.EnableNACKResponder(100))
+	assert.NoError(t, sender.EnableNACKResponder(64))
+	assert.Error(t, sender.EnableNACKResponder(64))
+
+	go func() {
+		for {
+			if _, readErr := sender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	started, retransmitted := make(chan struct{}), make(chan struct{})
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		close(started)
+
+		var highest, missing uint16
+		for {
+			p, readErr := remoteTrack.ReadRTP()
+			if readErr != nil {
+				return
+			}
+
+			switch {
+			case missing != 0 && p.SequenceNumber == missing:
+				close(retransmitted)
+				return
+			case missing == 0 && highest != 0 && p.SequenceNumber > highest+1:
+				missing = highest + 1
+				assert.NoError(t, pcAnswer.WriteRTCP([]rtcp.Packet{&rtcp.TransportLayerNack{
+					MediaSSRC: remoteTrack.SSRC(),
+					Nacks:     []rtcp.NackPair{{PacketID: missing}, {PacketID: p.SequenceNumber + 1000}},
+				}}))
+			}
+			highest = p.SequenceNumber
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendWithLostPacket(t, track, sender, started, retransmitted)
+
+	for sender.NACKResponderStats() != (NACKResponderStats{PacketsRetransmitted: 1, PacketsUnavailable: 1}) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_NACKGenerator(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	newAPI := func() *API {
+		m := MediaEngine{}
+		m.RegisterCodec(NewRTPVP8CodecExt(DefaultPayloadTypeVP8, 90000, []RTCPFeedback{{Type: TypeRTCPFBNACK}}, ""))
+		return NewAPI(WithMediaEngine(m))
+	}
+
+	pcOffer, err := newAPI().NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := newAPI().NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.NoError(t, sender.EnableNACKResponder(64))
+
+	go func() {
+		for {
+			if _, readErr := sender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	started, recovered := make(chan struct{}), make(chan struct{})
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		close(started)
+		for {
+			if _, readErr := remoteTrack.ReadRTP(); readErr != nil {
+				return
+			}
+
+			if remoteTrack.NACKGeneratorStats().PacketsRecovered == 1 {
+				close(recovered)
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendWithLostPacket(t, track, sender, started, recovered)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_RTX(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	newAPI := func() *API {
+		m := MediaEngine{}
+		m.RegisterCodec(NewRTPVP8CodecExt(DefaultPayloadTypeVP8, 90000, []RTCPFeedback{{Type: TypeRTCPFBNACK}}, ""))
+		m.RegisterCodec(NewRTPRTXCodec(97, 90000, DefaultPayloadTypeVP8))
+		return NewAPI(WithMediaEngine(m))
+	}
+
+	pcOffer, err := newAPI().NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := newAPI().NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.NoError(t, sender.EnableNACKResponder(64))
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, fmt.Sprintf("a=ssrc-group:FID %d %d", track.SSRC(), sender.rtxSSRC(track)))
+
+	go func() {
+		for {
+			if _, readErr := sender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	started, recovered := make(chan struct{}), make(chan struct{})
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		assert.Equal(t, sender.rtxSSRC(track), r.GetParameters().Encodings[0].RTX.SSRC)
+
+		close(started)
+		for {
+			if _, readErr := remoteTrack.ReadRTP(); readErr != nil {
+				return
+			}
+
+			if remoteTrack.NACKGeneratorStats().PacketsRecovered == 1 {
+				close(recovered)
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendWithLostPacket(t, track, sender, started, recovered)
+
+	parameters := sender.GetParameters()
+	assert.Equal(t, sender.rtxSSRC(track), parameters.Encodings[0].RTX.SSRC)
+	assert.Equal(t, uint64(1), sender.NACKResponderStats().PacketsRetransmitted)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// A retransmission is read without waiting for the next packet of the primary stream
+func TestPeerConnection_RTX_Paused(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	newAPI := func() *API {
+		m := MediaEngine{}
+		m.RegisterCodec(NewRTPVP8CodecExt(DefaultPayloadTypeVP8, 90000, []RTCPFeedback{{Type: TypeRTCPFBNACK}}, ""))
+		m.RegisterCodec(NewRTPRTXCodec(97, 90000, DefaultPayloadTypeVP8))
+		return NewAPI(WithMediaEngine(m))
+	}
+
+	pcOffer, err := newAPI().NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := newAPI().NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.NoError(t, sender.EnableNACKResponder(64))
+
+	go func() {
+		for {
+			if _, readErr := sender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	started, recovered := make(chan struct{}), make(chan struct{})
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		close(started)
+		for {
+			if _, readErr := remoteTrack.ReadRTP(); readErr != nil {
+				return
+			}
+
+			if remoteTrack.NACKGeneratorStats().PacketsRecovered == 1 {
+				close(recovered)
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	sequenceNumber := uint16(0)
+	writePacket := func(lost bool) {
+		sequenceNumber++
+		p := &rtp.Packet{
+			Header:  rtp.Header{Version: 2, SequenceNumber: sequenceNumber, PayloadType: DefaultPayloadTypeVP8, SSRC: track.SSRC()},
+			Payload: []byte{0x00},
+		}
+		if lost {
+			sender.trackEncodings[0].nackResponder.add(&p.Header, p.Payload)
+			return
+		}
+		assert.NoError(t, track.WriteRTP(p))
+	}
+
+	func() {
+		for {
+			writePacket(false)
+			select {
+			case <-started:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}()
+
+	// The stream pauses after the packet following the lost one
+	for i := 0; i < 2; i++ {
+		time.Sleep(20 * time.Millisecond)
+		writePacket(false)
+	}
+	writePacket(true)
+	writePacket(false)
+
+	select {
+	case <-recovered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the retransmission wasn't read while the stream was paused")
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_TWCCFeedback(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	newAPI := func() *API {
+		m := MediaEngine{}
+		m.RegisterCodec(NewRTPVP8CodecExt(DefaultPayloadTypeVP8, 90000, []RTCPFeedback{{Type: TypeRTCPFBTransportCC}}, ""))
+
+		s := SettingEngine{}
+		s.SetTWCCFeedbackInterval(20 * time.Millisecond)
+		return NewAPI(WithMediaEngine(m), WithSettingEngine(s))
+	}
+
+	pcOffer, err := newAPI().NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := newAPI().NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		for {
+			if _, readErr := remoteTrack.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	feedbackReceived := make(chan *rtcp.TransportLayerCC)
+	go func() {
+		for {
+			pkts, readErr := sender.ReadRTCP()
+			if readErr != nil {
+				return
+			}
+
+			for _, pkt := range pkts {
+				if feedback, ok := pkt.(*rtcp.TransportLayerCC); ok {
+					feedbackReceived <- feedback
+					return
+				}
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	func() {
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			select {
+			case feedback := <-feedbackReceived:
+				assert.Equal(t, track.SSRC(), feedback.MediaSSRC)
+				assert.NotZero(t, feedback.PacketStatusCount)
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			// The header extensions are known once the RTPSender is started
+			transportCCExtensionID := uint8(0)
+			for _, e := range sender.GetParameters().HeaderExtensions {
+				if e.URI == sdp.TransportCCURI {
+					transportCCExtensionID = uint8(e.ID)
+				}
+			}
+			if transportCCExtensionID == 0 {
+				continue
+			}
+
+			transportSequenceNumber := make([]byte, 2)
+			binary.BigEndian.PutUint16(transportSequenceNumber, sequenceNumber)
+
+			p := &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					SequenceNumber: sequenceNumber,
+					PayloadType:    DefaultPayloadTypeVP8,
+					SSRC:           track.SSRC(),
+				},
+				Payload: []byte{0x00},
+			}
+			assert.NoError(t, p.SetExtension(transportCCExtensionID, transportSequenceNumber))
+			assert.NoError(t, track.WriteRTP(p))
+		}
+	}()
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestReportsForSSRC(t *testing.T) {
+	remb := &rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 500000, SSRCs: []uint32{1234, 5678}}
+	twcc := &rtcp.TransportLayerCC{SenderSSRC: 1, MediaSSRC: 1234}
+	pkts := []rtcp.Packet{
+		&rtcp.ReceiverReport{SSRC: 1, Reports: []rtcp.ReceptionReport{{SSRC: 1234, FractionLost: 64}, {SSRC: 5678}}},
+		&rtcp.SenderReport{SSRC: 1, Reports: []rtcp.ReceptionReport{{SSRC: 5678}}},
+		remb,
+		twcc,
+	}
+
+	assert.Equal(t, []rtcp.Packet{
+		&rtcp.ReceiverReport{SSRC: 1, Reports: []rtcp.ReceptionReport{{SSRC: 1234, FractionLost: 64}}},
+		&rtcp.SenderReport{SSRC: 1, Reports: []rtcp.ReceptionReport{}},
+		remb,
+		twcc,
+	}, reportsForSSRC(pkts, 1234))
+
+	assert.Equal(t, []rtcp.Packet{
+		&rtcp.ReceiverReport{SSRC: 1, Reports: []rtcp.ReceptionReport{{SSRC: 5678}}},
+		&rtcp.SenderReport{SSRC: 1, Reports: []rtcp.ReceptionReport{{SSRC: 5678}}},
+	}, reportsForSSRC(pkts, 5678))
+
+	// The packets read are left unchanged
+	assert.Len(t, pkts[0].(*rtcp.ReceiverReport).Reports, 2)
+}
+
+// countingBandwidthEstimator raises its target bitrate for every transport-cc feedback
+type countingBandwidthEstimator struct {
+	packetsSent, feedbacks int
+}
+
+func (c *countingBandwidthEstimator) OnPacketSent(uint16, int, time.Time) {
+	c.packetsSent++
+}
+
+func (c *countingBandwidthEstimator) OnRTCP(pkts []rtcp.Packet, _ time.Time) {
+	for _, pkt := range pkts {
+		if _, ok := pkt.(*rtcp.TransportLayerCC); ok && c.packetsSent > 0 {
+			c.feedbacks++
+		}
+	}
+}
+
+func (c *countingBandwidthEstimator) TargetBitrate() uint64 {
+	return uint64(c.feedbacks) * 1000
+}
+
+func TestPeerConnection_BandwidthEstimator(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPVP8CodecExt(DefaultPayloadTypeVP8, 90000, []RTCPFeedback{{Type: TypeRTCPFBTransportCC}}, ""))
+
+	pcOffer, err := NewAPI(WithMediaEngine(m), WithBandwidthEstimator(func() BandwidthEstimator {
+		return &countingBandwidthEstimator{}
+	})).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	targetBitrateChanged := make(chan uint64, 1)
+	pcOffer.OnTargetBitrateChange(func(bitrate uint64) {
+		select {
+		case targetBitrateChanged <- bitrate:
+		default:
+		}
+	})
+
+	go func() {
+		for {
+			if _, readErr := sender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		for {
+			if _, readErr := remoteTrack.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	func() {
+		for {
+			select {
+			case bitrate := <-targetBitrateChanged:
+				assert.Equal(t, uint64(1000), bitrate)
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+		}
+	}()
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_SenderReports(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	senderReportReceived := make(chan *rtcp.SenderReport, 1)
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		go func() {
+			for {
+				if _, readErr := remoteTrack.ReadRTP(); readErr != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			pkts, readErr := r.ReadRTCP()
+			if readErr != nil {
+				return
+			}
+
+			for _, pkt := range pkts {
+				if senderReport, ok := pkt.(*rtcp.SenderReport); ok {
+					senderReportReceived <- senderReport
+					return
+				}
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	func() {
+		for {
+			select {
+			case senderReport := <-senderReportReceived:
+				assert.Equal(t, track.SSRC(), senderReport.SSRC)
+				assert.NotZero(t, senderReport.PacketCount)
+				assert.NotZero(t, senderReport.NTPTime)
+
+				stats := sender.SenderReportStats()
+				assert.Len(t, stats, 1)
+				assert.Equal(t, senderReport.NTPTime, stats[0].NTPTime)
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+		}
+	}()
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_ReceiverReports(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		// The Sender Reports are read, so the Receiver Reports can echo them
+		go func() {
+			for {
+				if _, readErr := r.ReadRTCP(); readErr != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			if _, readErr := remoteTrack.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	receptionReportReceived := make(chan rtcp.ReceptionReport, 1)
+	go func() {
+		for {
+			pkts, readErr := sender.ReadRTCP()
+			if readErr != nil {
+				return
+			}
+
+			for _, pkt := range pkts {
+				if receiverReport, ok := pkt.(*rtcp.ReceiverReport); ok {
+					for _, receptionReport := range receiverReport.Reports {
+						if receptionReport.LastSenderReport != 0 {
+							receptionReportReceived <- receptionReport
+							return
+						}
+					}
+				}
+			}
+		}
+	}()
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	func() {
+		for {
+			select {
+			case receptionReport := <-receptionReportReceived:
+				assert.Equal(t, track.SSRC(), receptionReport.SSRC)
+				assert.Equal(t, uint32(0), receptionReport.TotalLost)
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+		}
+	}()
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// streamInterceptor prefixes the payload of the RTP it sends, and reports the streams and RTCP it sees
+type streamInterceptor struct {
+	NoOpInterceptor
+	localStreams, remoteStreams, unboundStreams chan *StreamInfo
+	rtcpRead, rtcpWritten                       chan struct{}
+}
+
+func newStreamInterceptor() *streamInterceptor {
+	return &streamInterceptor{
+		localStreams:   make(chan *StreamInfo, 1),
+		remoteStreams:  make(chan *StreamInfo, 1),
+		unboundStreams: make(chan *StreamInfo, 1),
+		rtcpRead:       make(chan struct{}, 1),
+		rtcpWritten:    make(chan struct{}, 1),
+	}
+}
+
+func (s *streamInterceptor) BindRTCPWriter(writer RTCPWriter) RTCPWriter {
+	return RTCPWriterFunc(func(pkts []rtcp.Packet) error {
+		select {
+		case s.rtcpWritten <- struct{}{}:
+		default:
+		}
+		return writer.WriteRTCP(pkts)
+	})
+}
+
+func (s *streamInterceptor) BindRTCPReader(info *StreamInfo, reader RTCPReader) RTCPReader {
+	return RTCPReaderFunc(func(b []byte) (int, error) {
+		n, err := reader.Read(b)
+		if err == nil {
+			select {
+			case s.rtcpRead <- struct{}{}:
+			default:
+			}
+		}
+		return n, err
+	})
+}
+
+func (s *streamInterceptor) BindLocalStream(info *StreamInfo, writer RTPWriter) RTPWriter {
+	s.localStreams <- info
+	return RTPWriterFunc(func(header *rtp.Header, payload []byte) (int, error) {
+		return writer.WriteRTP(header, append([]byte{0xAB}, payload...))
+	})
+}
+
+func (s *streamInterceptor) UnbindLocalStream(info *StreamInfo) {
+	s.unboundStreams <- info
+}
+
+func (s *streamInterceptor) BindRemoteStream(info *StreamInfo, reader RTPReader) RTPReader {
+	s.remoteStreams <- info
+	return reader
+}
+
+func TestPeerConnection_Interceptors(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerInterceptor, answerInterceptor := newStreamInterceptor(), newStreamInterceptor()
+
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	pcOffer, err := NewAPI(WithMediaEngine(m), WithInterceptors(offerInterceptor)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewAPI(WithMediaEngine(m), WithInterceptors(answerInterceptor)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	go func() {
+		for {
+			if _, readErr := sender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	prefixedPacketReceived := make(chan struct{}, 1)
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		for {
+			pkt, readErr := remoteTrack.ReadRTP()
+			if readErr != nil {
+				return
+			}
+
+			if len(pkt.Payload) != 0 && pkt.Payload[0] == 0xAB {
+				select {
+				case prefixedPacketReceived <- struct{}{}:
+				default:
+				}
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// Receiver Reports of the answerer go through its RTCP writer and the RTCP reader of the offerer
+	waiting := []chan struct{}{prefixedPacketReceived, answerInterceptor.rtcpWritten, offerInterceptor.rtcpRead}
+	for len(waiting) != 0 {
+		select {
+		case <-waiting[0]:
+			waiting = waiting[1:]
+		case <-time.After(20 * time.Millisecond):
+			assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+		}
+	}
+
+	localStream := <-offerInterceptor.localStreams
+	assert.Equal(t, track.SSRC(), localStream.SSRC)
+	assert.Equal(t, VP8, localStream.Codec.Name)
+
+	remoteStream := <-answerInterceptor.remoteStreams
+	assert.Equal(t, track.SSRC(), remoteStream.SSRC)
+	assert.Equal(t, "video", remoteStream.ID)
+	assert.Equal(t, uint8(DefaultPayloadTypeVP8), remoteStream.PayloadType)
+	assert.Equal(t, VP8, remoteStream.Codec.Name)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+
+	assert.Equal(t, localStream, <-offerInterceptor.unboundStreams)
+}
+
+func TestRTPSender_ReplaceTrack(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	trackA, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	trackB, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	audioTrack, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, rand.Uint32(), "audio", "pion")
+	assert.NoError(t, err)
+
+	sender, err := pcOffer.AddTrack(trackA)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrIncompatibleTrack, sender.ReplaceTrack(audioTrack))
+
+	packets := make(chan *rtp.Packet, 100)
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		for {
+			pkt, readErr := remoteTrack.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			packets <- pkt
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// sendUntilReceived writes samples of track until one of them is received, and returns the
+	// last packet received before it
+	var last *rtp.Packet
+	sendUntilReceived := func(track *Track, data byte) *rtp.Packet {
+		for {
+			select {
+			case pkt := <-packets:
+				if pkt.Payload[len(pkt.Payload)-1] == data {
+					previous := last
+					last = pkt
+					return previous
+				}
+				last = pkt
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{data}, Samples: 90}))
+			}
+		}
+	}
+
+	sendUntilReceived(trackA, 0xAA)
+	assert.NoError(t, sender.ReplaceTrack(trackB))
+	assert.Equal(t, io.ErrClosedPipe, trackA.WriteSample(media.Sample{Data: []byte{0xAA}, Samples: 90}))
+
+	// Packets of trackB continue the stream of trackA
+	previous := sendUntilReceived(trackB, 0xBB)
+	assert.Equal(t, trackA.SSRC(), last.SSRC)
+	assert.Equal(t, uint8(DefaultPayloadTypeVP8), last.PayloadType)
+	assert.Equal(t, previous.SequenceNumber+1, last.SequenceNumber)
+	assert.Greater(t, last.Timestamp-previous.Timestamp, uint32(0))
+	assert.Less(t, last.Timestamp-previous.Timestamp, uint32(90000))
+
+	assert.NoError(t, sender.ReplaceTrack(nil))
+	assert.Nil(t, sender.Track())
+	assert.Equal(t, io.ErrClosedPipe, trackB.WriteSample(media.Sample{Data: []byte{0xBB}, Samples: 90}))
+	assert.Equal(t, trackA.SSRC(), sender.GetParameters().Encodings[0].SSRC)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// newPacedPair creates two connected PeerConnections with default codecs, the offer paces
+// the packets it sends at bitrate
+func newPacedPair(t *testing.T, bitrate uint64) (*PeerConnection, *PeerConnection) {
+	s := SettingEngine{}
+	s.SetPacingRate(bitrate)
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+	return pcOffer, pcAnswer
+}
+
+// assertContiguousSequenceNumbers checks every packet received follows the previous one
+func assertContiguousSequenceNumbers(t *testing.T, packets []*rtp.Packet) {
+	for i := 1; i < len(packets); i++ {
+		assert.Equal(t, packets[i-1].SequenceNumber+1, packets[i].SequenceNumber, "packet %d", i)
+	}
+}
+
+func TestRTPSender_ReplaceTrack_Pacing(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// A packet of 1000 bytes is sent every 20ms
+	pcOffer, pcAnswer := newPacedPair(t, 400000)
+
+	trackA, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	trackB, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(trackA)
+	assert.NoError(t, err)
+
+	packets := make(chan *rtp.Packet, 100)
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		for {
+			pkt, readErr := remoteTrack.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			packets <- pkt
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	var received []*rtp.Packet
+	for len(received) == 0 {
+		select {
+		case pkt := <-packets:
+			received = append(received, pkt)
+		case <-time.After(20 * time.Millisecond):
+			assert.NoError(t, trackA.WriteSample(media.Sample{Data: []byte{0xAA}, Samples: 90}))
+		}
+	}
+
+	// The Track is replaced while packets of the previous one are still queued
+	const burstSize = 5
+	sample := func(data byte) media.Sample {
+		payload := make([]byte, 1000)
+		payload[len(payload)-1] = data
+		return media.Sample{Data: payload, Samples: 90}
+	}
+	for i := 0; i < burstSize; i++ {
+		assert.NoError(t, trackA.WriteSample(sample(0xAA)))
+	}
+	assert.NoError(t, sender.ReplaceTrack(trackB))
+	for i := 0; i < burstSize; i++ {
+		assert.NoError(t, trackB.WriteSample(sample(0xBB)))
+	}
+
+	for receivedB := 0; receivedB < burstSize; {
+		select {
+		case pkt := <-packets:
+			received = append(received, pkt)
+			if pkt.Payload[len(pkt.Payload)-1] == 0xBB {
+				receivedB++
+			}
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "packets of the replacement Track were lost")
+			receivedB = burstSize
+		}
+	}
+	assertContiguousSequenceNumbers(t, received)
+	for i := 1; i < len(received); i++ {
+		assert.True(t, received[i].Timestamp-received[i-1].Timestamp < 0x80000000, "timestamps don't go backwards")
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRTPSender_SetParameters(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	packets := make(chan *rtp.Packet, 100)
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		for {
+			pkt, readErr := remoteTrack.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			packets <- pkt
+		}
+	})
+
+	changedParameters := make(chan RTPSendParameters, 1)
+	sender.OnParametersChange(func(parameters RTPSendParameters) {
+		changedParameters <- parameters
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// receive writes samples until a packet is received
+	receive := func() *rtp.Packet {
+		for {
+			select {
+			case pkt := <-packets:
+				return pkt
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 90}))
+			}
+		}
+	}
+	last := receive()
+
+	stale := sender.GetParameters()
+	parameters := sender.GetParameters()
+	assert.NotEqual(t, stale.TransactionID, parameters.TransactionID)
+	assert.Equal(t, 1, len(parameters.Encodings))
+	assert.True(t, parameters.Encodings[0].Active)
+	assert.Equal(t, RTCPriorityTypeLow, parameters.Encodings[0].Priority)
+
+	err = sender.SetParameters(stale)
+	assert.Equal(t, &rtcerr.InvalidModificationError{Err: ErrStaleTransactionID}, err)
+
+	modified := parameters
+	modified.Encodings = []RTPEncodingParameters{parameters.Encodings[0]}
+	modified.Encodings[0].SSRC++
+	assert.Equal(t, &rtcerr.InvalidModificationError{Err: ErrModifyingEncodings}, sender.SetParameters(modified))
+
+	// Pause the encoding, no packet leaves until it is resumed
+	parameters.Encodings[0].Active = false
+	parameters.Encodings[0].MaxBitrate = 500000
+	parameters.Encodings[0].MaxFramerate = 15
+	parameters.Encodings[0].Priority = RTCPriorityTypeHigh
+	assert.NoError(t, sender.SetParameters(parameters))
+	assert.Error(t, sender.SetParameters(parameters), "the TransactionID can only be used once")
+
+	changed := <-changedParameters
+	assert.Equal(t, "", changed.TransactionID)
+	assert.False(t, changed.Encodings[0].Active)
+	assert.Equal(t, uint64(500000), changed.Encodings[0].MaxBitrate)
+	assert.Equal(t, float64(15), changed.Encodings[0].MaxFramerate)
+	assert.Equal(t, RTCPriorityTypeHigh, changed.Encodings[0].Priority)
+
+	// Wait for the packets already sent to arrive
+	func() {
+		for {
+			select {
+			case last = <-packets:
+			case <-time.After(100 * time.Millisecond):
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 90}))
+	}
+	select {
+	case <-packets:
+		t.Fatal("a packet of a paused encoding was sent")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	parameters = sender.GetParameters()
+	parameters.Encodings[0].Active = true
+	assert.NoError(t, sender.SetParameters(parameters))
+	<-changedParameters
+
+	assert.Equal(t, last.SequenceNumber+1, receive().SequenceNumber, "the sequence numbers must continue after a pause")
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRTPReceiver_Sources(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	assert.NoError(t, m.RegisterHeaderExtension(AudioLevelURI, RTPCodecTypeAudio))
+	assert.NoError(t, m.RegisterHeaderExtension(CSRCAudioLevelURI, RTPCodecTypeAudio))
+
+	api := NewAPI(WithMediaEngine(m))
+	pcOffer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, rand.Uint32(), "audio", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	receiverChan := make(chan *RTPReceiver, 1)
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		receiverChan <- r
+		for {
+			if _, readErr := remoteTrack.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	extensionIDs := map[string]uint8{}
+	var receiver *RTPReceiver
+	for sequenceNumber := uint16(0); ; sequenceNumber++ {
+		select {
+		case receiver = <-receiverChan:
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		// The first packets are written before the extensions are negotiated
+		if receiver != nil {
+			if sources := receiver.GetSynchronizationSources(); len(sources) != 0 && sources[0].AudioLevel != nil {
+				break
+			}
+		}
+
+		for _, e := range sender.GetParameters().HeaderExtensions {
+			extensionIDs[e.URI] = uint8(e.ID)
+		}
+		pkt := &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				SSRC:           track.SSRC(),
+				PayloadType:    DefaultPayloadTypeOpus,
+				SequenceNumber: sequenceNumber,
+				Timestamp:      uint32(sequenceNumber) * 960,
+				CSRC:           []uint32{1, 2},
+			},
+			Payload: []byte{0x00},
+		}
+		if id := extensionIDs[AudioLevelURI]; id != 0 {
+			assert.NoError(t, pkt.Header.SetExtension(id, []byte{20}))
+		}
+		if id := extensionIDs[CSRCAudioLevelURI]; id != 0 {
+			assert.NoError(t, pkt.Header.SetExtension(id, []byte{0, audioLevelSilence}))
+		}
+		assert.NoError(t, track.WriteRTP(pkt))
+	}
+
+	synchronizationSources := receiver.GetSynchronizationSources()
+	assert.Equal(t, 1, len(synchronizationSources))
+	assert.Equal(t, track.SSRC(), synchronizationSources[0].Source)
+	assert.InDelta(t, 0.1, *synchronizationSources[0].AudioLevel, 0.0001)
+
+	contributingSources := receiver.GetContributingSources()
+	assert.Equal(t, 2, len(contributingSources))
+	for _, source := range contributingSources {
+		switch source.Source {
+		case 1:
+			assert.Equal(t, 1.0, *source.AudioLevel)
+		case 2:
+			assert.Equal(t, 0.0, *source.AudioLevel)
+		default:
+			t.Fatalf("unexpected CSRC %d", source.Source)
+		}
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_RemotePayloadTypes(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	const (
+		remotePayloadType     = 100
+		remoteOpusPayloadType = 110
+	)
+
+	offerMediaEngine := MediaEngine{}
+	_, err := offerMediaEngine.RegisterCodec(NewRTPVP8Codec(remotePayloadType, 90000))
+	assert.NoError(t, err)
+	_, err = offerMediaEngine.RegisterCodec(NewRTPOpusCodec(remoteOpusPayloadType, 48000))
+	assert.NoError(t, err)
+
+	pcOffer, err := NewAPI(WithMediaEngine(offerMediaEngine)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	offerTrack, err := pcOffer.NewTrack(remotePayloadType, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(offerTrack)
+	assert.NoError(t, err)
+
+	answerTrack, err := pcAnswer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTrack(answerTrack)
+	assert.NoError(t, err)
+
+	onTrack := func(pc *PeerConnection) chan *Track {
+		tracks := make(chan *Track, 1)
+		pc.OnTrack(func(track *Track, r *RTPReceiver) {
+			tracks <- track
+		})
+		return tracks
+	}
+	offerTracks, answerTracks := onTrack(pcOffer), onTrack(pcAnswer)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.Regexp(t, `(?m)^a=rtpmap:100 VP8/90000`, pcAnswer.LocalDescription().SDP)
+
+	// Both sides send VP8 with the PayloadType of the offer
+	for _, pair := range []struct {
+		track  *Track
+		tracks chan *Track
+	}{{offerTrack, answerTracks}, {answerTrack, offerTracks}} {
+		var remoteTrack *Track
+		for remoteTrack == nil {
+			select {
+			case remoteTrack = <-pair.tracks:
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, pair.track.WriteSample(media.Sample{Data: []byte{0xAA}, Samples: 90}))
+			}
+		}
+		assert.Equal(t, uint8(remotePayloadType), remoteTrack.PayloadType())
+		assert.Equal(t, VP8, remoteTrack.Codec().Name)
+	}
+
+	codec, err := pcAnswer.api.mediaEngine.getCodec(remotePayloadType)
+	assert.NoError(t, err)
+	assert.Equal(t, VP8, codec.Name)
+	_, err = pcAnswer.api.mediaEngine.getCodec(DefaultPayloadTypeVP8)
+	assert.Equal(t, ErrCodecNotFound, err)
+
+	// Opus wasn't negotiated by the first offer, a renegotiation adopts its PayloadType
+	codec, err = pcAnswer.api.mediaEngine.getCodec(DefaultPayloadTypeOpus)
+	assert.NoError(t, err)
+	assert.Equal(t, Opus, codec.Name)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.Regexp(t, `(?m)^a=rtpmap:110 opus/48000/2`, pcAnswer.LocalDescription().SDP)
+
+	codec, err = pcAnswer.api.mediaEngine.getCodec(remoteOpusPayloadType)
+	assert.NoError(t, err)
+	assert.Equal(t, Opus, codec.Name)
+	codec, err = pcAnswer.api.mediaEngine.getCodec(remotePayloadType)
+	assert.NoError(t, err)
+	assert.Equal(t, VP8, codec.Name)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRTPTransceiver_SetCodecPreferences(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	recorder, err := pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	h264 := RTPCodecCapability{MimeType: "video/H264", ClockRate: 90000, SDPFmtpLine: "profile-level-id=42001f;packetization-mode=1;level-asymmetry-allowed=1"}
+	vp8 := RTPCodecCapability{MimeType: "video/vp8", ClockRate: 90000}
+	opus := RTPCodecCapability{MimeType: "audio/opus", ClockRate: 48000, Channels: 2, SDPFmtpLine: "minptime=10;useinbandfec=1"}
+	rtx := RTPCodecCapability{MimeType: "video/rtx", ClockRate: 90000}
+
+	assert.Equal(t, &rtcerr.InvalidModificationError{Err: ErrCodecNotFound}, recorder.SetCodecPreferences([]RTPCodecCapability{opus}))
+	assert.Equal(t, &rtcerr.InvalidModificationError{Err: ErrCodecNotFound}, recorder.SetCodecPreferences([]RTPCodecCapability{rtx}))
+	assert.NoError(t, recorder.SetCodecPreferences([]RTPCodecCapability{h264}))
+
+	// videoCodecs returns the names of the codecs of every video media section
+	videoCodecs := func(desc SessionDescription) [][]string {
+		names := [][]string{}
+		for _, media := range desc.parsed.MediaDescriptions {
+			if media.MediaName.Media != mediaNameVideo {
+				continue
+			}
+
+			mediaNames := []string{}
+			for _, codec := range codecsFromMediaDescription(desc.parsed, media) {
+				mediaNames = append(mediaNames, codec.Name)
+			}
+			names = append(names, mediaNames)
+		}
+		return names
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{H264}, {VP8, VP9, H264}}, videoCodecs(offer))
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	// The answer only has the codecs of the offer, in the order of the preferences of the answerer
+	answerTransceivers := pcAnswer.GetTransceivers()
+	assert.NoError(t, answerTransceivers[0].SetCodecPreferences([]RTPCodecCapability{vp8, h264}))
+	assert.NoError(t, answerTransceivers[1].SetCodecPreferences([]RTPCodecCapability{h264, vp8}))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{H264}, {H264, VP8}}, videoCodecs(answer))
+
+	// A media section without common codecs is rejected
+	assert.NoError(t, answerTransceivers[0].SetCodecPreferences([]RTPCodecCapability{vp8}))
+	answer, err = pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{}, {H264, VP8}}, videoCodecs(answer))
+	assert.Equal(t, 0, answer.parsed.MediaDescriptions[0].MediaName.Port.Value)
+
+	assert.NoError(t, answerTransceivers[0].SetCodecPreferences(nil))
+	assert.NoError(t, answerTransceivers[1].SetCodecPreferences(nil))
+	answer, err = pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{H264}, {VP8, VP9, H264}}, videoCodecs(answer))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRTPDTMFSender(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, rand.Uint32(), "audio", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	dtmf := sender.DTMF()
+	assert.NotNil(t, dtmf)
+	assert.False(t, dtmf.CanInsertDTMF())
+	assert.IsType(t, &rtcerr.InvalidStateError{}, dtmf.InsertDTMF("1", 0, 0))
+
+	receivedTones := make(chan string, 10)
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		r.OnToneChange(func(tone string) {
+			receivedTones <- tone
+		})
+		for {
+			if _, readErr := remoteTrack.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	done := make(chan struct{})
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(20 * time.Millisecond):
+				if writeErr := track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 960}); writeErr != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for !dtmf.CanInsertDTMF() {
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.IsType(t, &rtcerr.InvalidCharacterError{}, dtmf.InsertDTMF("1x", 0, 0))
+
+	sentTones := make(chan string, 10)
+	dtmf.OnToneChange(func(tone string) {
+		sentTones <- tone
+	})
+	assert.NoError(t, dtmf.InsertDTMF("1#", 40*time.Millisecond, 30*time.Millisecond))
+
+	for _, expected := range []string{"1", "#", ""} {
+		assert.Equal(t, expected, <-sentTones)
+	}
+	assert.Empty(t, dtmf.ToneBuffer())
+	for _, expected := range []string{"1", "", "#", ""} {
+		assert.Equal(t, expected, <-receivedTones)
+	}
+
+	close(done)
+	<-writerDone
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRTPDTMFSender_Pacing(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer := newPacedPair(t, 1000000)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, rand.Uint32(), "audio", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	dtmf := sender.DTMF()
+
+	var mu sync.Mutex
+	var received []*rtp.Packet
+	receivedTones := make(chan string, 10)
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		r.OnToneChange(func(tone string) {
+			receivedTones <- tone
+		})
+		for {
+			pkt, readErr := remoteTrack.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			mu.Lock()
+			received = append(received, pkt)
+			mu.Unlock()
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	done := make(chan struct{})
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(20 * time.Millisecond):
+				if writeErr := track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 960}); writeErr != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for !dtmf.CanInsertDTMF() {
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.NoError(t, dtmf.InsertDTMF("1", 40*time.Millisecond, 30*time.Millisecond))
+	for _, expected := range []string{"1", ""} {
+		select {
+		case tone := <-receivedTones:
+			assert.Equal(t, expected, tone)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "tone wasn't received", expected)
+		}
+	}
+
+	// The packets of the Track resume after the telephone-events
+	time.Sleep(100 * time.Millisecond)
+	close(done)
+	<-writerDone
+
+	mu.Lock()
+	telephoneEvents := 0
+	for _, pkt := range received {
+		if pkt.PayloadType != DefaultPayloadTypeOpus {
+			telephoneEvents++
+		}
+	}
+	assert.Equal(t, 4, telephoneEvents, "the event and its three end packets")
+	assertContiguousSequenceNumbers(t, received)
+	mu.Unlock()
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRTPDTMFSender_NotNegotiated(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	// The answerer doesn't support telephone-event
+	m := MediaEngine{}
+	_, err = m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	assert.NoError(t, err)
+	pcAnswer, err := NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTransceiverFromKind(RTPCodecTypeAudio, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, rand.Uint32(), "audio", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	for !sender.hasSent() {
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.False(t, sender.DTMF().CanInsertDTMF())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestRTPReceiver_RequestKeyframe(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	keyframeRequests := make(chan *Track, 10)
+	sender.OnKeyframeRequest(func(requested *Track) {
+		keyframeRequests <- requested
+	})
+	go func() {
+		for {
+			if _, readErr := sender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	receiverChan := make(chan *RTPReceiver, 1)
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		receiverChan <- r
+		for {
+			if _, readErr := remoteTrack.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	var receiver *RTPReceiver
+	for receiver == nil {
+		assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+		select {
+		case receiver = <-receiverChan:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	// The second request is merged into the first one
+	assert.NoError(t, receiver.RequestKeyframe())
+	assert.NoError(t, receiver.RequestKeyframe())
+	assert.Equal(t, track, <-keyframeRequests)
+	assert.Error(t, receiver.RequestKeyframeSimulcast("missing"))
+
+	// A repeated FIR is only handled once
+	fir := &rtcp.FullIntraRequest{MediaSSRC: track.SSRC(), FIR: []rtcp.FIREntry{{SSRC: track.SSRC(), SequenceNumber: 7}}}
+	assert.NoError(t, pcAnswer.WriteRTCP([]rtcp.Packet{fir}))
+	assert.NoError(t, pcAnswer.WriteRTCP([]rtcp.Packet{fir}))
+	assert.Equal(t, track, <-keyframeRequests)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, keyframeRequests)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Pacing(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// A burst of 30 packets of 1000 bytes takes over 500ms at 400kbps
+	s := SettingEngine{}
+	s.SetPacingRate(400000)
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	const burstSize = 30
+	trackStarted, burstReceived := make(chan struct{}), make(chan struct{})
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		close(trackStarted)
+
+		received := 0
+		for {
+			p, readErr := remoteTrack.ReadRTP()
+			if readErr != nil {
+				return
+			}
+
+			if len(p.Payload) > 1 && p.Payload[0] == 0xFF {
+				if received++; received == burstSize {
+					close(burstReceived)
+				}
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	sequenceNumber := uint16(0)
+	writePacket := func(payload []byte) {
+		sequenceNumber++
+		assert.NoError(t, track.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, SSRC: track.SSRC(), PayloadType: track.PayloadType(), SequenceNumber: sequenceNumber},
+			Payload: payload,
+		}))
+	}
+
+	func() {
+		for {
+			writePacket([]byte{0x00})
+			select {
+			case <-trackStarted:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}()
+
+	packetsSent := func() uint32 {
+		return pcOffer.GetStats()[fmt.Sprintf("OutboundRTP-%d", track.SSRC())].(OutboundRTPStreamStats).PacketsSent
+	}
+	sentBeforeBurst := packetsSent()
+
+	start := time.Now()
+	for i := 0; i < burstSize; i++ {
+		payload := make([]byte, 1000)
+		payload[0] = 0xFF
+		writePacket(payload)
+	}
+	assert.True(t, time.Since(start) < 100*time.Millisecond, "writing doesn't wait for the packets to be sent")
+
+	// Only the packets actually sent are counted
+	assert.Less(t, packetsSent(), sentBeforeBurst+burstSize)
+
+	<-burstReceived
+	assert.True(t, time.Since(start) > 300*time.Millisecond, "the burst is spread over time")
+	assert.Eventually(t, func() bool { return packetsSent() == sentBeforeBurst+burstSize }, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// sendWithLostPacket writes packets to track until done is closed. After started is closed one
+// packet is lost, it is only stored in the retransmission buffer of sender
+func sendWithLostPacket(t *testing.T, track *Track, sender *RTPSender, started, done <-chan struct{}) {
+	lostSequenceNumber := uint16(0)
+	for sequenceNumber := uint16(1); ; sequenceNumber++ {
+		select {
+		case <-done:
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		p := &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				SequenceNumber: sequenceNumber,
+				PayloadType:    DefaultPayloadTypeVP8,
+				SSRC:           track.SSRC(),
+			},
+			Payload: []byte{0x00},
+		}
+
+		select {
+		case <-started:
+			if lostSequenceNumber == 0 {
+				lostSequenceNumber = sequenceNumber + 2
+			}
+		default:
+		}
+
+		if sequenceNumber == lostSequenceNumber {
+			sender.trackEncodings[0].nackResponder.add(&p.Header, p.Payload)
+			continue
+		}
+		assert.NoError(t, track.WriteRTP(p))
+	}
+}
@@ -89,6 +89,15 @@ func (e *NotReadableError) Error() string {
 	return fmt.Sprintf("NotReadableError: %v", e.Err)
 }
 
+// InvalidCharacterError indicates a string contains characters that are not allowed.
+type InvalidCharacterError struct {
+	Err error
+}
+
+func (e *InvalidCharacterError) Error() string {
+	return fmt.Sprintf("InvalidCharacterError: %v", e.Err)
+}
+
 // RangeError indicates an error when a value is not in the set or range
 // of allowed values.
 type RangeError struct {
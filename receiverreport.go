@@ -0,0 +1,183 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// receptionStats keeps the statistics of an incoming RTP stream that are sent in
+// Receiver Reports, as described in RFC 3550 appendix A
+type receptionStats struct {
+	mu sync.Mutex
+
+	started               bool
+	baseSequenceNumber    uint16
+	highestSequenceNumber uint16
+	cycles                uint32
+	packetsReceived       uint32
+
+	// Counters at the time of the previous report, used for the fraction lost
+	expectedPrior uint32
+	receivedPrior uint32
+
+	// Interarrival jitter in RTP timestamp units
+	jitter      float64
+	lastTransit uint32
+	haveTransit bool
+	startTime   time.Time
+
+	// Middle 32 bits of the NTP timestamp of the last Sender Report, and when it arrived
+	lastSenderReport     uint32
+	lastSenderReportTime time.Time
+
+	// The counters of the last Sender Report, reported as the remote outbound stream
+	senderReport          rtcp.SenderReport
+	senderReportsReceived uint64
+
+	bytesReceived      uint64
+	lastPacketReceived time.Time
+}
+
+// receivedStreamStats is a snapshot of the statistics of an incoming RTP stream
+type receivedStreamStats struct {
+	packetsReceived    uint32
+	packetsLost        int32
+	bytesReceived      uint64
+	lastPacketReceived time.Time
+
+	// Interarrival jitter in RTP timestamp units
+	jitter float64
+
+	// The last Sender Report received for the stream, valid if senderReportsReceived isn't 0
+	senderReport          rtcp.SenderReport
+	senderReportTime      time.Time
+	senderReportsReceived uint64
+}
+
+func newReceptionStats() *receptionStats {
+	return &receptionStats{startTime: time.Now()}
+}
+
+// update accounts for a received packet, clockRate is 0 to leave the jitter untouched
+func (s *receptionStats) update(sequenceNumber uint16, timestamp uint32, clockRate uint32, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		s.started = true
+		s.baseSequenceNumber = sequenceNumber
+		s.highestSequenceNumber = sequenceNumber
+	} else if diff := sequenceNumber - s.highestSequenceNumber; diff != 0 && diff < 0x8000 {
+		if sequenceNumber < s.highestSequenceNumber {
+			s.cycles += 1 << 16
+		}
+		s.highestSequenceNumber = sequenceNumber
+	}
+	s.packetsReceived++
+	s.lastPacketReceived = now
+
+	if clockRate == 0 {
+		return
+	}
+
+	arrival := uint32(now.Sub(s.startTime).Seconds() * float64(clockRate))
+	transit := arrival - timestamp
+	if s.haveTransit {
+		d := int32(transit - s.lastTransit)
+		if d < 0 {
+			d = -d
+		}
+		s.jitter += (float64(d) - s.jitter) / 16
+	}
+	s.lastTransit = transit
+	s.haveTransit = true
+}
+
+// onSenderReport stores the time of a Sender Report, it is echoed back in the next Receiver Report
+func (s *receptionStats) onSenderReport(senderReport *rtcp.SenderReport, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastSenderReport = uint32(senderReport.NTPTime >> 16)
+	s.lastSenderReportTime = now
+	s.senderReport = *senderReport
+	s.senderReportsReceived++
+}
+
+// addBytes accounts for the payload bytes of a received packet
+func (s *receptionStats) addBytes(payloadSize int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bytesReceived += uint64(payloadSize)
+}
+
+// getStats returns the cumulative statistics of the stream, packets lost are negative when
+// duplicates were received
+func (s *receptionStats) getStats() receivedStreamStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := receivedStreamStats{
+		packetsReceived:       s.packetsReceived,
+		bytesReceived:         s.bytesReceived,
+		lastPacketReceived:    s.lastPacketReceived,
+		jitter:                s.jitter,
+		senderReport:          s.senderReport,
+		senderReportTime:      s.lastSenderReportTime,
+		senderReportsReceived: s.senderReportsReceived,
+	}
+	if s.started {
+		expected := (s.cycles | uint32(s.highestSequenceNumber)) - uint32(s.baseSequenceNumber) + 1
+		stats.packetsLost = int32(expected - s.packetsReceived)
+	}
+	return stats
+}
+
+// report builds the reception report block of the stream, and starts a new reporting interval
+func (s *receptionStats) report(ssrc uint32, now time.Time) (rtcp.ReceptionReport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		return rtcp.ReceptionReport{}, false
+	}
+
+	extendedHighest := s.cycles | uint32(s.highestSequenceNumber)
+	expected := extendedHighest - uint32(s.baseSequenceNumber) + 1
+
+	totalLost := uint32(0)
+	if expected > s.packetsReceived {
+		totalLost = expected - s.packetsReceived
+	}
+	if totalLost > 0x7FFFFF {
+		totalLost = 0x7FFFFF
+	}
+
+	fractionLost := uint8(0)
+	expectedInterval := expected - s.expectedPrior
+	receivedInterval := s.packetsReceived - s.receivedPrior
+	if expectedInterval != 0 && expectedInterval > receivedInterval {
+		fractionLost = uint8(((expectedInterval - receivedInterval) << 8) / expectedInterval)
+	}
+	s.expectedPrior = expected
+	s.receivedPrior = s.packetsReceived
+
+	delay := uint32(0)
+	if !s.lastSenderReportTime.IsZero() {
+		delay = uint32(now.Sub(s.lastSenderReportTime).Seconds() * 65536)
+	}
+
+	return rtcp.ReceptionReport{
+		SSRC:               ssrc,
+		FractionLost:       fractionLost,
+		TotalLost:          totalLost,
+		LastSequenceNumber: extendedHighest,
+		Jitter:             uint32(s.jitter),
+		LastSenderReport:   s.lastSenderReport,
+		Delay:              delay,
+	}, true
+}
@@ -0,0 +1,74 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceptionStats(t *testing.T) {
+	s := newReceptionStats()
+	now := s.startTime
+
+	_, ok := s.report(1234, now)
+	assert.False(t, ok, "no report before packets are received")
+
+	// 0 is lost, 65535 is reordered
+	for i, sequenceNumber := range []uint16{65533, 65535, 65534, 1} {
+		s.update(sequenceNumber, uint32(i*900), 90000, now.Add(time.Duration(i)*10*time.Millisecond))
+	}
+
+	s.onSenderReport(&rtcp.SenderReport{SSRC: 1234, NTPTime: 0x0000123456780000}, now)
+
+	report, ok := s.report(1234, now.Add(500*time.Millisecond))
+	assert.True(t, ok)
+	assert.Equal(t, rtcp.ReceptionReport{
+		SSRC:               1234,
+		FractionLost:       256 / 5,
+		TotalLost:          1,
+		LastSequenceNumber: 1<<16 | 1,
+		Jitter:             0,
+		LastSenderReport:   0x12345678,
+		Delay:              1 << 15,
+	}, report)
+
+	// The fraction lost only describes the packets since the previous report
+	s.update(2, 3600, 90000, now.Add(70*time.Millisecond))
+	report, ok = s.report(1234, now.Add(time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, uint8(0), report.FractionLost)
+	assert.Equal(t, uint32(1), report.TotalLost)
+	assert.Equal(t, uint32(1<<16|2), report.LastSequenceNumber)
+	assert.NotZero(t, report.Jitter, "the packet arrived 30ms late")
+}
+
+func TestReceptionStats_GetStats(t *testing.T) {
+	s := newReceptionStats()
+	now := s.startTime
+	assert.Equal(t, receivedStreamStats{}, s.getStats())
+
+	// 3 is lost, 2 is received twice
+	for i, sequenceNumber := range []uint16{1, 2, 2, 4} {
+		s.update(sequenceNumber, uint32(i*900), 90000, now)
+		s.addBytes(100)
+	}
+	stats := s.getStats()
+	assert.Equal(t, uint32(4), stats.packetsReceived)
+	assert.Equal(t, int32(0), stats.packetsLost)
+	assert.Equal(t, uint64(400), stats.bytesReceived)
+	assert.Equal(t, now, stats.lastPacketReceived)
+
+	s.update(6, 4500, 90000, now)
+	assert.Equal(t, int32(1), s.getStats().packetsLost)
+
+	senderReport := &rtcp.SenderReport{SSRC: 1234, NTPTime: 0x0000123456780000, PacketCount: 10, OctetCount: 1000}
+	s.onSenderReport(senderReport, now)
+	stats = s.getStats()
+	assert.Equal(t, uint64(1), stats.senderReportsReceived)
+	assert.Equal(t, *senderReport, stats.senderReport)
+	assert.Equal(t, now, stats.senderReportTime)
+}
@@ -29,3 +29,13 @@ type RTCPFeedback struct {
 	// For example, type="nack" parameter="pli" will send Picture Loss Indicator packets.
 	Parameter string
 }
+
+// hasRTCPFeedback tells if feedbacks contains the given type and parameter
+func hasRTCPFeedback(feedbacks []RTCPFeedback, typ, parameter string) bool {
+	for _, feedback := range feedbacks {
+		if feedback.Type == typ && feedback.Parameter == parameter {
+			return true
+		}
+	}
+	return false
+}
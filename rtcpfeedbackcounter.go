@@ -0,0 +1,59 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pion/rtcp"
+)
+
+// rtcpFeedbackCounts are the numbers of feedback packets sent or received for a media SSRC
+type rtcpFeedbackCounts struct {
+	nackCount uint32
+	pliCount  uint32
+	firCount  uint32
+}
+
+// rtcpFeedbackCounter counts the NACK, PLI and FIR packets of RTCP compounds by the SSRC of the
+// media they are about
+type rtcpFeedbackCounter struct {
+	mu     sync.Mutex
+	counts map[uint32]rtcpFeedbackCounts
+}
+
+func newRTCPFeedbackCounter() *rtcpFeedbackCounter {
+	return &rtcpFeedbackCounter{counts: map[uint32]rtcpFeedbackCounts{}}
+}
+
+// count accounts for the feedback packets of a RTCP compound
+func (c *rtcpFeedbackCounter) count(pkts []rtcp.Packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, pkt := range pkts {
+		switch p := pkt.(type) {
+		case *rtcp.TransportLayerNack:
+			counts := c.counts[p.MediaSSRC]
+			counts.nackCount++
+			c.counts[p.MediaSSRC] = counts
+		case *rtcp.PictureLossIndication:
+			counts := c.counts[p.MediaSSRC]
+			counts.pliCount++
+			c.counts[p.MediaSSRC] = counts
+		case *rtcp.FullIntraRequest:
+			for _, entry := range p.FIR {
+				counts := c.counts[entry.SSRC]
+				counts.firCount++
+				c.counts[entry.SSRC] = counts
+			}
+		}
+	}
+}
+
+// get returns the feedback packets counted for ssrc
+func (c *rtcpFeedbackCounter) get(ssrc uint32) rtcpFeedbackCounts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[ssrc]
+}
@@ -0,0 +1,59 @@
+package webrtc
+
+// RTCPriorityType indicates the relative priority of an encoding, it is used
+// to share the available bandwidth between the encodings of a PeerConnection
+type RTCPriorityType int
+
+const (
+	// RTCPriorityTypeVeryLow is the lowest priority
+	RTCPriorityTypeVeryLow RTCPriorityType = iota + 1
+
+	// RTCPriorityTypeLow is the priority of an encoding by default
+	RTCPriorityTypeLow
+
+	// RTCPriorityTypeMedium is twice the priority of RTCPriorityTypeLow
+	RTCPriorityTypeMedium
+
+	// RTCPriorityTypeHigh is the highest priority
+	RTCPriorityTypeHigh
+)
+
+// This is done this way because of a linter.
+const (
+	rtcPriorityTypeVeryLowStr = "very-low"
+	rtcPriorityTypeLowStr     = "low"
+	rtcPriorityTypeMediumStr  = "medium"
+	rtcPriorityTypeHighStr    = "high"
+)
+
+// NewRTCPriorityType defines a procedure for creating a new RTCPriorityType
+// from a raw string naming the priority.
+func NewRTCPriorityType(raw string) RTCPriorityType {
+	switch raw {
+	case rtcPriorityTypeVeryLowStr:
+		return RTCPriorityTypeVeryLow
+	case rtcPriorityTypeLowStr:
+		return RTCPriorityTypeLow
+	case rtcPriorityTypeMediumStr:
+		return RTCPriorityTypeMedium
+	case rtcPriorityTypeHighStr:
+		return RTCPriorityTypeHigh
+	default:
+		return RTCPriorityType(Unknown)
+	}
+}
+
+func (p RTCPriorityType) String() string {
+	switch p {
+	case RTCPriorityTypeVeryLow:
+		return rtcPriorityTypeVeryLowStr
+	case RTCPriorityTypeLow:
+		return rtcPriorityTypeLowStr
+	case RTCPriorityTypeMedium:
+		return rtcPriorityTypeMediumStr
+	case RTCPriorityTypeHigh:
+		return rtcPriorityTypeHighStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
@@ -0,0 +1,132 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// AudioLevelURI is the RFC 6464 header extension carrying the audio level of the SSRC
+	AudioLevelURI = "urn:ietf:params:rtp-hdrext:ssrc-audio-level"
+
+	// CSRCAudioLevelURI is the RFC 6465 header extension carrying the audio level of every CSRC
+	CSRCAudioLevelURI = "urn:ietf:params:rtp-hdrext:csrc-audio-level"
+
+	// Sources are only reported if a packet was received from them within this duration
+	rtpSourceTimeout = 10 * time.Second
+
+	// Audio levels are encoded in -dBov, 127 is silence
+	audioLevelMask    = 0x7F
+	audioLevelSilence = 127
+)
+
+// RTPContributingSource describes a CSRC of the packets received by a RTPReceiver
+type RTPContributingSource struct {
+	// Timestamp is when the last packet from the source was received
+	Timestamp time.Time
+
+	// Source is the CSRC or SSRC identifier
+	Source uint32
+
+	// AudioLevel is between 0 (silence) and 1 (loudest), it is nil if the last
+	// packet didn't carry the audio level of the source
+	AudioLevel *float64
+
+	// RTPTimestamp is the RTP timestamp of the last packet from the source
+	RTPTimestamp uint32
+}
+
+// RTPSynchronizationSource describes a SSRC of the packets received by a RTPReceiver
+type RTPSynchronizationSource struct {
+	RTPContributingSource
+}
+
+// rtpSources keeps the contributing and synchronization sources of the packets received by a RTPReceiver
+type rtpSources struct {
+	mu sync.Mutex
+
+	synchronizationSources map[uint32]RTPContributingSource
+	contributingSources    map[uint32]RTPContributingSource
+}
+
+func newRTPSources() *rtpSources {
+	return &rtpSources{
+		synchronizationSources: map[uint32]RTPContributingSource{},
+		contributingSources:    map[uint32]RTPContributingSource{},
+	}
+}
+
+// update records a received packet, the audio levels are read from the header extensions with the
+// given IDs, or ignored if they are 0
+func (s *rtpSources) update(header *rtp.Header, audioLevelExtensionID, csrcAudioLevelExtensionID uint8, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	source := RTPContributingSource{Timestamp: now, Source: header.SSRC, RTPTimestamp: header.Timestamp}
+	if audioLevelExtensionID != 0 {
+		if payload := header.GetExtension(audioLevelExtensionID); len(payload) >= 1 {
+			source.AudioLevel = audioLevel(payload[0])
+		}
+	}
+	s.synchronizationSources[header.SSRC] = source
+
+	var levels []byte
+	if csrcAudioLevelExtensionID != 0 {
+		levels = header.GetExtension(csrcAudioLevelExtensionID)
+	}
+	for i, csrc := range header.CSRC {
+		source := RTPContributingSource{Timestamp: now, Source: csrc, RTPTimestamp: header.Timestamp}
+		if i < len(levels) {
+			source.AudioLevel = audioLevel(levels[i])
+		}
+		s.contributingSources[csrc] = source
+	}
+}
+
+// get returns the sources of sources that sent a packet within rtpSourceTimeout, the most recent first.
+// Older sources are removed
+func (s *rtpSources) get(sources map[uint32]RTPContributingSource, now time.Time) []RTPContributingSource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recent := []RTPContributingSource{}
+	for id, source := range sources {
+		if now.Sub(source.Timestamp) > rtpSourceTimeout {
+			delete(sources, id)
+			continue
+		}
+		recent = append(recent, source)
+	}
+
+	sort.Slice(recent, func(i, j int) bool {
+		return recent[i].Timestamp.After(recent[j].Timestamp)
+	})
+	return recent
+}
+
+func (s *rtpSources) getContributingSources(now time.Time) []RTPContributingSource {
+	return s.get(s.contributingSources, now)
+}
+
+func (s *rtpSources) getSynchronizationSources(now time.Time) []RTPSynchronizationSource {
+	sources := []RTPSynchronizationSource{}
+	for _, source := range s.get(s.synchronizationSources, now) {
+		sources = append(sources, RTPSynchronizationSource{source})
+	}
+	return sources
+}
+
+// audioLevel converts an audio level in -dBov to a linear value between 0 and 1
+func audioLevel(b byte) *float64 {
+	level := 0.0
+	if dBov := b & audioLevelMask; dBov != audioLevelSilence {
+		level = math.Pow(10, -float64(dBov)/20)
+	}
+	return &level
+}
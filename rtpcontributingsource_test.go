@@ -0,0 +1,52 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPSources(t *testing.T) {
+	sources := newRTPSources()
+	now := time.Now()
+
+	header := &rtp.Header{SSRC: 1234, Timestamp: 4000, CSRC: []uint32{1, 2}}
+	assert.NoError(t, header.SetExtension(1, []byte{0x80 | 20}))
+	assert.NoError(t, header.SetExtension(2, []byte{0, audioLevelSilence}))
+	sources.update(header, 1, 2, now.Add(-11*time.Second))
+
+	// Only the sources of the last 10 seconds are returned
+	assert.Equal(t, []RTPSynchronizationSource{}, sources.getSynchronizationSources(now))
+	assert.Equal(t, []RTPContributingSource{}, sources.getContributingSources(now))
+
+	sources.update(header, 1, 2, now.Add(-time.Second))
+	header.CSRC = []uint32{2}
+	header.Timestamp = 5000
+	sources.update(header, 0, 0, now)
+
+	synchronizationSources := sources.getSynchronizationSources(now)
+	assert.Equal(t, 1, len(synchronizationSources))
+	assert.Equal(t, uint32(1234), synchronizationSources[0].Source)
+	assert.Equal(t, uint32(5000), synchronizationSources[0].RTPTimestamp)
+	assert.Nil(t, synchronizationSources[0].AudioLevel, "the audio level extension wasn't negotiated")
+
+	contributingSources := sources.getContributingSources(now)
+	assert.Equal(t, 2, len(contributingSources))
+	assert.Equal(t, uint32(2), contributingSources[0].Source, "the most recent source must be first")
+	assert.Equal(t, now, contributingSources[0].Timestamp)
+	assert.Equal(t, uint32(1), contributingSources[1].Source)
+	assert.Equal(t, uint32(4000), contributingSources[1].RTPTimestamp)
+	assert.Equal(t, 1.0, *contributingSources[1].AudioLevel)
+	assert.Nil(t, contributingSources[0].AudioLevel)
+}
+
+func TestAudioLevel(t *testing.T) {
+	assert.Equal(t, 1.0, *audioLevel(0))
+	assert.InDelta(t, 0.1, *audioLevel(20), 0.0001)
+	assert.InDelta(t, 0.01, *audioLevel(0x80 | 40), 0.0001, "the voice activity bit must be ignored")
+	assert.Equal(t, 0.0, *audioLevel(audioLevelSilence))
+}
@@ -0,0 +1,217 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
+)
+
+const (
+	dtmfTones = "0123456789ABCD#*,"
+
+	// Limits of https://w3c.github.io/webrtc-pc/#dom-rtcdtmfsender-insertdtmf
+	dtmfDefaultDuration     = 100 * time.Millisecond
+	dtmfMinDuration         = 40 * time.Millisecond
+	dtmfMaxDuration         = 6000 * time.Millisecond
+	dtmfDefaultInterToneGap = 70 * time.Millisecond
+	dtmfMinInterToneGap     = 30 * time.Millisecond
+	dtmfCommaDelay          = 2 * time.Second
+
+	// A packet is sent every dtmfPacketInterval while a tone is played, the end packet
+	// is sent dtmfEndPacketCount times in case of losses
+	dtmfPacketInterval = 20 * time.Millisecond
+	dtmfEndPacketCount = 3
+
+	// Volume of the tones in -dBm0
+	dtmfVolume = 10
+
+	// The duration of a telephone-event is 16 bits, longer events are split in segments
+	telephoneEventMaxDuration = 0xFFFF
+)
+
+// RTPDTMFSender sends DTMF tones in the audio stream of a RTPSender as RFC 4733 telephone-events.
+// The packets of the Track are dropped while a tone is sent
+type RTPDTMFSender struct {
+	mu     sync.Mutex
+	sender *RTPSender
+
+	// The telephone-event codec negotiated for the stream, nil if DTMF can't be sent
+	codec *RTPCodec
+
+	toneBuffer   string
+	duration     time.Duration
+	interToneGap time.Duration
+	playing      bool
+
+	onToneChangeHdlr func(tone string)
+}
+
+func newRTPDTMFSender(sender *RTPSender) *RTPDTMFSender {
+	return &RTPDTMFSender{sender: sender}
+}
+
+func (d *RTPDTMFSender) setCodec(codec *RTPCodec) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.codec = codec
+}
+
+func (d *RTPDTMFSender) getCodec() *RTPCodec {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.codec
+}
+
+// CanInsertDTMF tells if the RTPSender is sending and telephone-event was negotiated for it
+func (d *RTPDTMFSender) CanInsertDTMF() bool {
+	return d.getCodec() != nil && d.sender.hasSent() && !d.sender.hasStopped()
+}
+
+// ToneBuffer returns the tones that remain to be sent
+func (d *RTPDTMFSender) ToneBuffer() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.toneBuffer
+}
+
+// OnToneChange sets an event handler which is invoked when a tone of the buffer starts to be
+// sent, and with an empty tone once all of them were sent
+func (d *RTPDTMFSender) OnToneChange(f func(tone string)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onToneChangeHdlr = f
+}
+
+// InsertDTMF replaces the tone buffer with tones and starts sending them. Tones are 0-9, A-D,
+// # and *, a comma delays the next tone by 2 seconds. Every tone lasts duration, between 40ms
+// and 6s, and is followed by interToneGap, at least 30ms. A zero duration or interToneGap uses
+// the defaults of 100ms and 70ms.
+func (d *RTPDTMFSender) InsertDTMF(tones string, duration, interToneGap time.Duration) error {
+	if !d.CanInsertDTMF() {
+		return &rtcerr.InvalidStateError{Err: ErrDTMFNotNegotiated}
+	}
+
+	tones = strings.ToUpper(tones)
+	for _, tone := range tones {
+		if !strings.ContainsRune(dtmfTones, tone) {
+			return &rtcerr.InvalidCharacterError{Err: ErrInvalidDTMFTone}
+		}
+	}
+
+	switch {
+	case duration == 0:
+		duration = dtmfDefaultDuration
+	case duration < dtmfMinDuration:
+		duration = dtmfMinDuration
+	case duration > dtmfMaxDuration:
+		duration = dtmfMaxDuration
+	}
+
+	switch {
+	case interToneGap == 0:
+		interToneGap = dtmfDefaultInterToneGap
+	case interToneGap < dtmfMinInterToneGap:
+		interToneGap = dtmfMinInterToneGap
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.toneBuffer, d.duration, d.interToneGap = tones, duration, interToneGap
+	if tones != "" && !d.playing {
+		d.playing = true
+		go d.run()
+	}
+	return nil
+}
+
+// run sends the tones of the buffer until it is empty or the RTPSender is stopped
+func (d *RTPDTMFSender) run() {
+	for {
+		d.mu.Lock()
+		if d.toneBuffer == "" || d.sender.hasStopped() {
+			d.toneBuffer = ""
+			d.playing = false
+			handler := d.onToneChangeHdlr
+			d.mu.Unlock()
+
+			if handler != nil {
+				handler("")
+			}
+			return
+		}
+
+		tone := d.toneBuffer[0]
+		d.toneBuffer = d.toneBuffer[1:]
+		codec, duration, interToneGap, handler := d.codec, d.duration, d.interToneGap, d.onToneChangeHdlr
+		d.mu.Unlock()
+
+		if handler != nil {
+			handler(string(tone))
+		}
+
+		delay := dtmfCommaDelay
+		if tone != ',' {
+			if err := d.sendTone(codec, uint8(strings.IndexByte(dtmfEvents, tone)), duration); err != nil {
+				d.mu.Lock()
+				d.toneBuffer = ""
+				d.mu.Unlock()
+				continue
+			}
+			delay = interToneGap
+		}
+
+		select {
+		case <-d.sender.stopCalled:
+		case <-time.After(delay):
+		}
+	}
+}
+
+// sendTone sends the telephone-event packets of a tone in the stream of the RTPSender, the
+// duration of the event grows by dtmfPacketInterval with every packet
+func (d *RTPDTMFSender) sendTone(codec *RTPCodec, event uint8, duration time.Duration) error {
+	timestamp := d.sender.startTelephoneEvent()
+	defer d.sender.endTelephoneEvent()
+
+	ticker := time.NewTicker(dtmfPacketInterval)
+	defer ticker.Stop()
+
+	total := uint32(duration.Seconds() * float64(codec.ClockRate))
+	step := uint32(dtmfPacketInterval.Seconds() * float64(codec.ClockRate))
+	segmentStart := uint32(0)
+	for elapsed := step; ; elapsed += step {
+		if elapsed > total {
+			elapsed = total
+		}
+		if elapsed-segmentStart > telephoneEventMaxDuration {
+			segmentStart += telephoneEventMaxDuration
+			timestamp += telephoneEventMaxDuration
+		}
+
+		e := telephoneEvent{event: event, end: elapsed == total, volume: dtmfVolume, duration: uint16(elapsed - segmentStart)}
+		count := 1
+		if e.end {
+			count = dtmfEndPacketCount
+		}
+		for i := 0; i < count; i++ {
+			if err := d.sender.writeTelephoneEvent(codec.PayloadType, timestamp, elapsed == step, e.marshal()); err != nil {
+				return err
+			}
+		}
+
+		if e.end {
+			return nil
+		}
+
+		select {
+		case <-d.sender.stopCalled:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
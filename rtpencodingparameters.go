@@ -5,4 +5,16 @@ package webrtc
 // http://draft.ortc.org/#dom-rtcrtpencodingparameters
 type RTPEncodingParameters struct {
 	RTPCodingParameters
+
+	// Active tells if the encoding is sent. Send starts every encoding, they are
+	// paused and resumed with RTPSender.SetParameters
+	Active bool `json:"active"`
+
+	// Limits of the encoding, 0 means unlimited. Pion WebRTC doesn't encode media so these
+	// are only reported to the handler set with RTPSender.OnParametersChange
+	MaxBitrate            uint64  `json:"maxBitrate"`
+	MaxFramerate          float64 `json:"maxFramerate"`
+	ScaleResolutionDownBy float64 `json:"scaleResolutionDownBy"`
+
+	Priority RTCPriorityType `json:"priority"`
 }
@@ -0,0 +1,8 @@
+package webrtc
+
+// RTPHeaderExtensionParameter represents a negotiated RFC5285 RTP header extension.
+// https://w3c.github.io/webrtc-pc/#dictionary-rtcrtpheaderextensionparameters-members
+type RTPHeaderExtensionParameter struct {
+	URI string `json:"uri"`
+	ID  int    `json:"id"`
+}
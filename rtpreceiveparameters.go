@@ -2,5 +2,6 @@ package webrtc
 
 // RTPReceiveParameters contains the RTP stack settings used by receivers
 type RTPReceiveParameters struct {
-	Encodings RTPDecodingParameters
+	Encodings        []RTPDecodingParameters
+	HeaderExtensions []RTPHeaderExtensionParameter
 }
@@ -3,27 +3,78 @@
 package webrtc
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v2"
 	"github.com/pion/srtp"
 )
 
+// trackStreams maintains a mapping of RTP/RTCP streams to a specific track
+// a RTPReceiver may contain multiple streams if we are dealing with Simulcast
+type trackStreams struct {
+	track *Track
+
+	rtpReadStream  *srtp.ReadStreamSRTP
+	rtcpReadStream *srtp.ReadStreamSRTCP
+
+	// The RTCP of the Track, drained by the RTPReceiver so Sender Reports are handled even if the
+	// application doesn't read it
+	rtcpPackets chan receivedPacket
+
+	nackGenerator     *nackGenerator
+	receptionStats    *receptionStats
+	keyframeRequester *keyframeRequester
+
+	// The Interceptors of the API bound to this Track once its codec is known
+	streamInfo *StreamInfo
+	rtpReader  RTPReader
+	rtcpReader RTCPReader
+
+	// Retransmissions received on the RTX stream, unwrapped into packets of this Track. Once there
+	// is a RTX stream, or packets were probed before the Track was received, the packets of the
+	// Track are read from packets, in the order they arrive
+	repairSSRC       uint32
+	repairReadStream *srtp.ReadStreamSRTP
+	packets          chan receivedPacket
+}
+
+// receivedPacket is a packet read from one of the streams of a Track, repaired if it was
+// unwrapped from the RTX stream
+type receivedPacket struct {
+	data     []byte
+	repaired bool
+	err      error
+}
+
 // RTPReceiver allows an application to inspect the receipt of a Track
 type RTPReceiver struct {
 	kind      RTPCodecType
 	transport *DTLSTransport
 
-	track *Track
+	tracks           []trackStreams
+	headerExtensions []RTPHeaderExtensionParameter
+
+	// IDs of the header extensions read from every packet, 0 if they weren't negotiated
+	transportCCExtensionID    uint8
+	audioLevelExtensionID     uint8
+	csrcAudioLevelExtensionID uint8
+
+	// The contributing and synchronization sources of the packets read
+	sources *rtpSources
+
+	// Decodes the DTMF tones of the telephone-event packets read
+	telephoneEvents  telephoneEventDecoder
+	onToneChangeHdlr func(tone string)
 
 	closed, received chan interface{}
 	mu               sync.RWMutex
 
-	rtpReadStream  *srtp.ReadStreamSRTP
-	rtcpReadStream *srtp.ReadStreamSRTCP
-
 	// A reference to the associated api object
 	api *API
 }
@@ -40,6 +91,7 @@ func (api *API) NewRTPReceiver(kind RTPCodecType, transport *DTLSTransport) (*RT
 		api:       api,
 		closed:    make(chan interface{}),
 		received:  make(chan interface{}),
+		sources:   newRTPSources(),
 	}, nil
 }
 
@@ -51,11 +103,64 @@ func (r *RTPReceiver) Transport() *DTLSTransport {
 	return r.transport
 }
 
+// GetParameters describes the current configuration for the encoding and
+// transmission of media on the receiver's track, including the negotiated
+// RTP header extensions.
+func (r *RTPReceiver) GetParameters() RTPReceiveParameters {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	parameters := RTPReceiveParameters{
+		Encodings:        []RTPDecodingParameters{},
+		HeaderExtensions: append([]RTPHeaderExtensionParameter{}, r.headerExtensions...),
+	}
+	for _, t := range r.tracks {
+		parameters.Encodings = append(parameters.Encodings, RTPDecodingParameters{
+			RTPCodingParameters{
+				RID:         t.track.RID(),
+				SSRC:        t.track.SSRC(),
+				PayloadType: t.track.PayloadType(),
+				RTX:         RTPRtxParameters{SSRC: t.repairSSRC},
+			},
+		})
+	}
+	return parameters
+}
+
+// GetReceiverCapabilities returns the codecs and header extensions of kind kind the RTPReceivers
+// of the API can receive, these are the ones of its MediaEngine
+func (api *API) GetReceiverCapabilities(kind RTPCodecType) RTPCapabilities {
+	return api.mediaEngine.getCapabilities(kind, RTPTransceiverDirectionRecvonly)
+}
+
+// GetCapabilities returns the codecs and header extensions of kind kind the RTPReceiver can receive,
+// see API.GetReceiverCapabilities
+func (r *RTPReceiver) GetCapabilities(kind RTPCodecType) RTPCapabilities {
+	return r.api.GetReceiverCapabilities(kind)
+}
+
 // Track returns the RTCRtpTransceiver track
 func (r *RTPReceiver) Track() *Track {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.track
+
+	if len(r.tracks) == 0 {
+		return nil
+	}
+	return r.tracks[0].track
+}
+
+// Tracks returns the RTCRtpTransceiver tracks
+// A RTPReceiver to support Simulcast may now have multiple tracks
+func (r *RTPReceiver) Tracks() []*Track {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tracks []*Track
+	for i := range r.tracks {
+		tracks = append(tracks, r.tracks[i].track)
+	}
+	return tracks
 }
 
 // Receive initialize the track and starts all the transports
@@ -69,40 +174,93 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 	}
 	defer close(r.received)
 
-	r.track = &Track{
-		kind:     r.kind,
-		ssrc:     parameters.Encodings.SSRC,
-		receiver: r,
+	r.headerExtensions = parameters.HeaderExtensions
+	for _, e := range parameters.HeaderExtensions {
+		switch e.URI {
+		case sdp.TransportCCURI:
+			r.transportCCExtensionID = uint8(e.ID)
+		case AudioLevelURI:
+			r.audioLevelExtensionID = uint8(e.ID)
+		case CSRCAudioLevelURI:
+			r.csrcAudioLevelExtensionID = uint8(e.ID)
+		}
 	}
+	for _, encoding := range parameters.Encodings {
+		t := trackStreams{
+			track: &Track{
+				kind:     r.kind,
+				ssrc:     encoding.SSRC,
+				rid:      encoding.RID,
+				receiver: r,
+			},
+			receptionStats:    newReceptionStats(),
+			keyframeRequester: &keyframeRequester{},
+		}
 
-	srtpSession, err := r.transport.getSRTPSession()
-	if err != nil {
-		return err
-	}
+		// Simulcast streams are announced by RID only, the SSRC is learned from the
+		// first packet and the streams are opened in receiveForRID
+		if encoding.SSRC != 0 {
+			var err error
+			var rtcpReadStream *srtp.ReadStreamSRTCP
+			if t.rtpReadStream, rtcpReadStream, err = r.streamsForSSRC(encoding.SSRC); err != nil {
+				return err
+			}
+			r.receiveRTCP(&t, rtcpReadStream)
+		}
 
-	r.rtpReadStream, err = srtpSession.OpenReadStream(parameters.Encodings.SSRC)
-	if err != nil {
-		return err
-	}
+		if encoding.RTX.SSRC != 0 {
+			if err := r.receiveRTX(&t, encoding.RTX.SSRC); err != nil {
+				return err
+			}
+		}
 
-	srtcpSession, err := r.transport.getSRTCPSession()
-	if err != nil {
-		return err
+		r.tracks = append(r.tracks, t)
 	}
 
-	r.rtcpReadStream, err = srtcpSession.OpenReadStream(parameters.Encodings.SSRC)
-	if err != nil {
-		return err
+	if !r.api.settingEngine.disableReceiverReports {
+		go r.runReceiverReports()
 	}
-
 	return nil
 }
 
-// Read reads incoming RTCP for this RTPReceiver
+// Read reads incoming RTCP for this RTPReceiver. The RTPReceiver handles the Sender Reports itself,
+// RTCP that isn't read is dropped
 func (r *RTPReceiver) Read(b []byte) (n int, err error) {
 	select {
 	case <-r.received:
-		return r.rtcpReadStream.Read(b)
+		r.mu.RLock()
+		var t trackStreams
+		if len(r.tracks) != 0 {
+			t = r.tracks[0]
+		}
+		r.mu.RUnlock()
+
+		if t.rtcpReadStream == nil {
+			return 0, fmt.Errorf("RTPReceiver has no RTCP stream to read from")
+		}
+		return r.readTrackRTCP(t, b)
+	case <-r.closed:
+		return 0, io.ErrClosedPipe
+	}
+}
+
+// ReadSimulcast reads incoming RTCP for this RTPReceiver for given rid
+func (r *RTPReceiver) ReadSimulcast(b []byte, rid string) (n int, err error) {
+	select {
+	case <-r.received:
+		r.mu.RLock()
+		var t trackStreams
+		for i := range r.tracks {
+			if r.tracks[i].track.RID() == rid {
+				t = r.tracks[i]
+			}
+		}
+		r.mu.RUnlock()
+
+		if t.rtcpReadStream == nil {
+			return 0, fmt.Errorf("no Track with RID %s is being received", rid)
+		}
+		return r.readTrackRTCP(t, b)
 	case <-r.closed:
 		return 0, io.ErrClosedPipe
 	}
@@ -119,6 +277,17 @@ func (r *RTPReceiver) ReadRTCP() ([]rtcp.Packet, error) {
 	return rtcp.Unmarshal(b[:i])
 }
 
+// ReadSimulcastRTCP is a convenience method that wraps ReadSimulcast and unmarshals for you
+func (r *RTPReceiver) ReadSimulcastRTCP(rid string) ([]rtcp.Packet, error) {
+	b := make([]byte, receiveMTU)
+	i, err := r.ReadSimulcast(b, rid)
+	if err != nil {
+		return nil, err
+	}
+
+	return rtcp.Unmarshal(b[:i])
+}
+
 func (r *RTPReceiver) haveReceived() bool {
 	select {
 	case <-r.received:
@@ -141,14 +310,24 @@ func (r *RTPReceiver) Stop() error {
 
 	select {
 	case <-r.received:
-		if r.rtcpReadStream != nil {
-			if err := r.rtcpReadStream.Close(); err != nil {
-				return err
+		for i := range r.tracks {
+			if r.tracks[i].streamInfo != nil {
+				r.api.interceptor.UnbindRemoteStream(r.tracks[i].streamInfo)
 			}
-		}
-		if r.rtpReadStream != nil {
-			if err := r.rtpReadStream.Close(); err != nil {
-				return err
+			if r.tracks[i].rtcpReadStream != nil {
+				if err := r.tracks[i].rtcpReadStream.Close(); err != nil {
+					return err
+				}
+			}
+			if r.tracks[i].rtpReadStream != nil {
+				if err := r.tracks[i].rtpReadStream.Close(); err != nil {
+					return err
+				}
+			}
+			if r.tracks[i].repairReadStream != nil {
+				if err := r.tracks[i].repairReadStream.Close(); err != nil {
+					return err
+				}
 			}
 		}
 	default:
@@ -159,7 +338,597 @@ func (r *RTPReceiver) Stop() error {
 }
 
 // readRTP should only be called by a track, this only exists so we can keep state in one place
-func (r *RTPReceiver) readRTP(b []byte) (n int, err error) {
+func (r *RTPReceiver) readRTP(b []byte, reader *Track) (n int, err error) {
 	<-r.received
-	return r.rtpReadStream.Read(b)
+
+	r.mu.RLock()
+	var rtpReader RTPReader
+	for i := range r.tracks {
+		if r.tracks[i].track == reader {
+			rtpReader = r.tracks[i].rtpReader
+			break
+		}
+	}
+	r.mu.RUnlock()
+
+	if rtpReader != nil {
+		return rtpReader.Read(b)
+	}
+	return r.readStreamRTP(b, reader)
+}
+
+// readStreamRTP reads a RTP packet of a Track from its streams, before the Interceptors
+func (r *RTPReceiver) readStreamRTP(b []byte, reader *Track) (n int, err error) {
+	r.mu.RLock()
+	var rtpReadStream *srtp.ReadStreamSRTP
+	var nackGenerator *nackGenerator
+	var packets chan receivedPacket
+	var receptionStats *receptionStats
+	transportCCExtensionID := r.transportCCExtensionID
+	audioLevelExtensionID, csrcAudioLevelExtensionID := r.audioLevelExtensionID, r.csrcAudioLevelExtensionID
+	onToneChangeHdlr := r.onToneChangeHdlr
+	for i := range r.tracks {
+		if r.tracks[i].track == reader {
+			rtpReadStream = r.tracks[i].rtpReadStream
+			nackGenerator = r.tracks[i].nackGenerator
+			packets = r.tracks[i].packets
+			receptionStats = r.tracks[i].receptionStats
+			break
+		}
+	}
+	r.mu.RUnlock()
+
+	if rtpReadStream == nil {
+		return 0, fmt.Errorf("unable to find stream for Track with SSRC(%d)", reader.SSRC())
+	}
+
+	repaired := false
+	if packets == nil {
+		n, err = rtpReadStream.Read(b)
+	} else {
+		select {
+		case p := <-packets:
+			if p.err != nil {
+				return 0, p.err
+			} else if len(b) < len(p.data) {
+				return 0, io.ErrShortBuffer
+			}
+			n, repaired = copy(b, p.data), p.repaired
+		case <-r.closed:
+			return 0, io.EOF
+		}
+	}
+
+	if err == nil && nackGenerator != nil && n >= rtpSequenceNumberOffset+2 {
+		nackGenerator.update(binary.BigEndian.Uint16(b[rtpSequenceNumberOffset:]), time.Now())
+	}
+	if err == nil && receptionStats != nil && n >= rtpHeaderLength {
+		// A repaired packet arrives a round trip late, it doesn't measure the jitter of the stream
+		clockRate := uint32(0)
+		if codec := reader.Codec(); codec != nil && !repaired {
+			clockRate = codec.ClockRate
+		}
+		receptionStats.update(binary.BigEndian.Uint16(b[rtpSequenceNumberOffset:]), binary.BigEndian.Uint32(b[rtpTimestampOffset:]), clockRate, time.Now())
+	}
+	if err != nil {
+		return n, err
+	}
+
+	header := &rtp.Header{}
+	if header.Unmarshal(b[:n]) != nil {
+		return n, nil
+	}
+	if receptionStats != nil {
+		payloadSize := n - header.PayloadOffset
+		if header.Padding && payloadSize > 0 {
+			payloadSize -= int(b[n-1])
+		}
+		receptionStats.addBytes(payloadSize)
+	}
+	now := time.Now()
+	r.sources.update(header, audioLevelExtensionID, csrcAudioLevelExtensionID, now)
+	if transportCCExtensionID != 0 {
+		if payload := header.GetExtension(transportCCExtensionID); len(payload) >= 2 {
+			r.transport.twccRecorder.record(header.SSRC, binary.BigEndian.Uint16(payload), now)
+		}
+	}
+	if onToneChangeHdlr != nil && r.kind == RTPCodecTypeAudio && r.api.mediaEngine.isTelephoneEvent(header.PayloadType) {
+		for _, tone := range r.telephoneEvents.decode(header.Timestamp, b[header.PayloadOffset:n]) {
+			onToneChangeHdlr(tone)
+		}
+	}
+	return n, nil
+}
+
+// OnToneChange sets an event handler which is invoked when a DTMF tone starts to be received in
+// the telephone-events of the audio Track, and with an empty tone when it ends
+func (r *RTPReceiver) OnToneChange(f func(tone string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onToneChangeHdlr = f
+}
+
+// RequestKeyframe asks the remote to send a keyframe of every Track of the RTPReceiver. A FIR
+// is sent if the codec was negotiated with the ccm fir RTCPFeedback and not nack pli, a PLI
+// otherwise. Requests within 500ms of the previous one for a Track are merged into it.
+func (r *RTPReceiver) RequestKeyframe() error {
+	return r.requestKeyframe(func(*Track) bool { return true })
+}
+
+// RequestKeyframeSimulcast asks the remote to send a keyframe of the Track with the given RID
+func (r *RTPReceiver) RequestKeyframeSimulcast(rid string) error {
+	return r.requestKeyframe(func(t *Track) bool { return t.RID() == rid })
+}
+
+func (r *RTPReceiver) requestKeyframe(match func(*Track) bool) error {
+	if r.kind != RTPCodecTypeVideo {
+		return fmt.Errorf("keyframes can only be requested for video")
+	} else if !r.haveReceived() {
+		return fmt.Errorf("RTPReceiver has not started receiving")
+	}
+
+	r.mu.RLock()
+	tracks := append([]trackStreams{}, r.tracks...)
+	r.mu.RUnlock()
+
+	now := time.Now()
+	pkts := []rtcp.Packet{}
+	matched := false
+	for _, t := range tracks {
+		ssrc := t.track.SSRC()
+		if ssrc == 0 || !match(t.track) {
+			continue
+		}
+
+		matched = true
+		if pkt := t.keyframeRequester.request(ssrc, useFIR(t.track.Codec()), now); pkt != nil {
+			pkts = append(pkts, pkt)
+		}
+	}
+
+	if !matched {
+		return fmt.Errorf("no Track is being received to request a keyframe of")
+	} else if len(pkts) == 0 {
+		return nil
+	}
+	return r.transport.writeRTCP(pkts)
+}
+
+// GetContributingSources returns the CSRCs of the packets read from the Tracks of the RTPReceiver
+// within the last 10 seconds, the most recent first. Their audio levels are read from the
+// csrc-audio-level header extension if it was negotiated
+func (r *RTPReceiver) GetContributingSources() []RTPContributingSource {
+	return r.sources.getContributingSources(time.Now())
+}
+
+// GetSynchronizationSources returns the SSRCs of the packets read from the Tracks of the RTPReceiver
+// within the last 10 seconds, the most recent first. Their audio levels are read from the
+// ssrc-audio-level header extension if it was negotiated
+func (r *RTPReceiver) GetSynchronizationSources() []RTPSynchronizationSource {
+	return r.sources.getSynchronizationSources(time.Now())
+}
+
+// receiveRTX opens the RTX stream that repairs a Track, its packets are read with the Track
+func (r *RTPReceiver) receiveRTX(t *trackStreams, ssrc uint32) error {
+	srtpSession, err := r.transport.getSRTPSession()
+	if err != nil {
+		return err
+	}
+
+	if t.repairReadStream, err = srtpSession.OpenReadStream(ssrc); err != nil {
+		return err
+	}
+	t.repairSSRC = ssrc
+	if t.packets == nil {
+		t.packets = make(chan receivedPacket, rtxBufferSize)
+		go r.readPrimary(t.rtpReadStream, t.packets)
+	}
+	go r.readRTX(t.track, t.repairReadStream, t.packets)
+	return nil
+}
+
+// readPrimary reads the packets of the primary stream of a Track until it is closed, so they are
+// read with the retransmissions of its RTX stream
+func (r *RTPReceiver) readPrimary(rtpReadStream *srtp.ReadStreamSRTP, packets chan receivedPacket) {
+	b := make([]byte, receiveMTU)
+	for {
+		n, err := rtpReadStream.Read(b)
+		p := receivedPacket{err: err}
+		if err == nil {
+			p.data = append([]byte{}, b[:n]...)
+		}
+
+		select {
+		case packets <- p:
+		case <-r.closed:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readRTX unwraps the packets of a RTX stream until it is closed. Packets are dropped if the Track isn't read
+func (r *RTPReceiver) readRTX(track *Track, repairReadStream *srtp.ReadStreamSRTP, packets chan receivedPacket) {
+	b := make([]byte, receiveMTU)
+	for {
+		n, err := repairReadStream.Read(b)
+		if err != nil {
+			return
+		} else if n < rtpPayloadTypeOffset+1 {
+			continue
+		}
+
+		// Use the codec the RTX PayloadType is associated with, or the PayloadType of the Track
+		payloadType := track.PayloadType()
+		if codec, err := r.api.mediaEngine.getCodec(b[rtpPayloadTypeOffset] & rtpPayloadTypeMask); err == nil {
+			if apt, ok := codec.associatedPayloadType(); ok {
+				payloadType = apt
+			}
+		}
+
+		repaired, err := unwrapRTX(b[:n], track.SSRC(), payloadType)
+		if err != nil {
+			continue
+		}
+
+		select {
+		case packets <- receivedPacket{data: repaired, repaired: true}:
+		default:
+		}
+	}
+}
+
+// readTrackRTCP reads the RTCP of a Track, through the Interceptors once they are bound
+func (r *RTPReceiver) readTrackRTCP(t trackStreams, b []byte) (int, error) {
+	if t.rtcpReader != nil {
+		return t.rtcpReader.Read(b)
+	}
+	return r.readStreamRTCP(t, b)
+}
+
+// receiveRTCP starts draining the RTCP stream of a Track
+func (r *RTPReceiver) receiveRTCP(t *trackStreams, rtcpReadStream *srtp.ReadStreamSRTCP) {
+	t.rtcpReadStream = rtcpReadStream
+	t.rtcpPackets = make(chan receivedPacket, rtcpBufferSize)
+	go r.readRTCP(t.track, t.receptionStats, rtcpReadStream, t.rtcpPackets)
+}
+
+// readRTCP reads the RTCP stream of a Track until it is closed, the Sender Reports are echoed in
+// the next Receiver Report. Packets are dropped if the RTPReceiver isn't read
+func (r *RTPReceiver) readRTCP(track *Track, stats *receptionStats, rtcpReadStream *srtp.ReadStreamSRTCP, packets chan receivedPacket) {
+	b := make([]byte, receiveMTU)
+	for {
+		n, err := rtcpReadStream.Read(b)
+		if err != nil {
+			select {
+			case packets <- receivedPacket{err: err}:
+			case <-r.closed:
+			}
+			return
+		}
+
+		if pkts, unmarshalErr := rtcp.Unmarshal(b[:n]); unmarshalErr == nil {
+			ssrc := track.SSRC()
+			for _, pkt := range pkts {
+				if senderReport, ok := pkt.(*rtcp.SenderReport); ok && senderReport.SSRC == ssrc {
+					stats.onSenderReport(senderReport, time.Now())
+				}
+			}
+		}
+
+		select {
+		case packets <- receivedPacket{data: append([]byte{}, b[:n]...)}:
+		default:
+		}
+	}
+}
+
+// readStreamRTCP reads the RTCP of a Track drained by readRTCP
+func (r *RTPReceiver) readStreamRTCP(t trackStreams, b []byte) (int, error) {
+	select {
+	case p := <-t.rtcpPackets:
+		if p.err != nil {
+			return 0, p.err
+		} else if len(b) < len(p.data) {
+			return 0, io.ErrShortBuffer
+		}
+		return copy(b, p.data), nil
+	case <-r.closed:
+		return 0, io.ErrClosedPipe
+	}
+}
+
+// runReceiverReports sends Receiver Reports at a randomized interval until the RTPReceiver is stopped
+func (r *RTPReceiver) runReceiverReports() {
+	timer := time.NewTimer(rtcpReportInterval(r.kind))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-r.closed:
+			return
+		case now := <-timer.C:
+			if receiverReport := r.receiverReport(now); receiverReport != nil {
+				// Errors are ignored, the next report describes the streams again
+				_ = r.transport.writeRTCP([]rtcp.Packet{receiverReport})
+			}
+			timer.Reset(rtcpReportInterval(r.kind))
+		}
+	}
+}
+
+// receiverReport builds a Receiver Report with a block for every Track that received packets
+func (r *RTPReceiver) receiverReport(now time.Time) *rtcp.ReceiverReport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	receiverReport := &rtcp.ReceiverReport{}
+	for i := range r.tracks {
+		if report, ok := r.tracks[i].receptionStats.report(r.tracks[i].track.SSRC(), now); ok {
+			receiverReport.Reports = append(receiverReport.Reports, report)
+		}
+	}
+
+	if len(receiverReport.Reports) == 0 {
+		return nil
+	}
+	return receiverReport
+}
+
+// GetStats returns the stats of the inbound streams of the Tracks of the RTPReceiver, with the codecs,
+// remote outbound streams and transport they refer to
+func (r *RTPReceiver) GetStats() StatsReport {
+	collector := newStatsReportCollector()
+	r.collectStats(collector)
+	if iceTransport := r.transport.ICETransport(); iceTransport != nil {
+		iceTransport.collectStats(collector)
+	}
+	return collector.Ready()
+}
+
+// collectStats collects the inbound stream of every Track that received packets, and the remote
+// outbound stream described by the Sender Reports of the remote
+func (r *RTPReceiver) collectStats(collector *statsReportCollector) {
+	r.mu.RLock()
+	tracks := append([]trackStreams{}, r.tracks...)
+	r.mu.RUnlock()
+
+	now := time.Now()
+	for _, t := range tracks {
+		received := t.receptionStats.getStats()
+		if received.packetsReceived == 0 {
+			continue
+		}
+
+		ssrc := t.track.SSRC()
+		feedback := r.transport.feedbackSent.get(ssrc)
+		stats := InboundRTPStreamStats{
+			Timestamp:                   statsTimestampFrom(now),
+			Type:                        StatsTypeInboundRTP,
+			ID:                          fmt.Sprintf("InboundRTP-%d", ssrc),
+			SSRC:                        ssrc,
+			Kind:                        r.kind.String(),
+			TransportID:                 "iceTransport",
+			FIRCount:                    feedback.firCount,
+			PLICount:                    feedback.pliCount,
+			NACKCount:                   feedback.nackCount,
+			PacketsReceived:             received.packetsReceived,
+			PacketsLost:                 received.packetsLost,
+			BytesReceived:               received.bytesReceived,
+			LastPacketReceivedTimestamp: statsTimestampFrom(received.lastPacketReceived),
+		}
+
+		if codec := t.track.Codec(); codec != nil {
+			stats.CodecID = collectCodecStats(collector, codec, CodecTypeDecode)
+			if codec.ClockRate != 0 {
+				stats.Jitter = received.jitter / float64(codec.ClockRate)
+			}
+		}
+
+		if received.senderReportsReceived != 0 {
+			remoteStats := RemoteOutboundRTPStreamStats{
+				Timestamp:       statsTimestampFrom(received.senderReportTime),
+				Type:            StatsTypeRemoteOutboundRTP,
+				ID:              fmt.Sprintf("RemoteOutboundRTP-%d", ssrc),
+				SSRC:            ssrc,
+				Kind:            stats.Kind,
+				TransportID:     stats.TransportID,
+				CodecID:         stats.CodecID,
+				PacketsSent:     received.senderReport.PacketCount,
+				BytesSent:       uint64(received.senderReport.OctetCount),
+				LocalID:         stats.ID,
+				RemoteTimestamp: statsTimestampFrom(ntpTimeToTime(received.senderReport.NTPTime)),
+			}
+			stats.RemoteID = remoteStats.ID
+
+			collector.Collecting()
+			collector.Collect(remoteStats.ID, remoteStats)
+		}
+
+		collector.Collecting()
+		collector.Collect(stats.ID, stats)
+	}
+}
+
+// bindInterceptors binds the Interceptors of the API to track, it is called once the codec
+// of the Track is known
+func (r *RTPReceiver) bindInterceptors(track *Track) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.tracks {
+		if r.tracks[i].track != track || r.tracks[i].streamInfo != nil {
+			continue
+		}
+
+		track.mu.RLock()
+		info := &StreamInfo{
+			ID:               track.id,
+			SSRC:             track.ssrc,
+			RID:              track.rid,
+			Kind:             track.kind,
+			PayloadType:      track.payloadType,
+			Codec:            track.codec,
+			HeaderExtensions: r.headerExtensions,
+		}
+		track.mu.RUnlock()
+
+		t := r.tracks[i]
+		r.tracks[i].streamInfo = info
+		r.tracks[i].rtpReader = r.api.interceptor.BindRemoteStream(info, RTPReaderFunc(func(b []byte) (int, error) {
+			return r.readStreamRTP(b, track)
+		}))
+		r.tracks[i].rtcpReader = r.api.interceptor.BindRTCPReader(info, RTCPReaderFunc(func(b []byte) (int, error) {
+			return r.readStreamRTCP(t, b)
+		}))
+		return
+	}
+}
+
+// startNACKGenerator starts sending NACKs for the packets of track that are missing. Missing
+// packets are detected while the Track is read
+func (r *RTPReceiver) startNACKGenerator(track *Track) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.tracks {
+		if r.tracks[i].track != track || r.tracks[i].nackGenerator != nil {
+			continue
+		}
+
+		nackGenerator := newNACKGenerator()
+		r.tracks[i].nackGenerator = nackGenerator
+		go r.runNACKGenerator(track.SSRC(), nackGenerator)
+		return
+	}
+}
+
+func (r *RTPReceiver) runNACKGenerator(ssrc uint32, nackGenerator *nackGenerator) {
+	ticker := time.NewTicker(nackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.closed:
+			return
+		case now := <-ticker.C:
+			sequenceNumbers := nackGenerator.nack(now)
+			if len(sequenceNumbers) == 0 {
+				continue
+			}
+
+			// Errors are ignored, the packets will be NACKed again on the next tick
+			_ = r.transport.writeRTCP([]rtcp.Packet{&rtcp.TransportLayerNack{
+				MediaSSRC: ssrc,
+				Nacks:     nackPairsFromSequenceNumbers(sequenceNumbers),
+			}})
+		}
+	}
+}
+
+// nackGeneratorStats returns the NACK generator counters of track
+func (r *RTPReceiver) nackGeneratorStats(track *Track) NACKGeneratorStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := range r.tracks {
+		if r.tracks[i].track == track && r.tracks[i].nackGenerator != nil {
+			return r.tracks[i].nackGenerator.getStats()
+		}
+	}
+	return NACKGeneratorStats{}
+}
+
+// receiveForRID is the sibling of Receive except for RIDs instead of SSRCs
+// It populates all the internal state for the given RID, the packets probed for the RID are read
+// from the Track before the ones that follow
+func (r *RTPReceiver) receiveForRID(rid string, ssrc uint32, probed [][]byte) (*Track, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.tracks {
+		if r.tracks[i].track.RID() != rid {
+			continue
+		} else if r.tracks[i].rtpReadStream != nil {
+			return nil, fmt.Errorf("Track with RID %s is already being received", rid)
+		}
+
+		rtpReadStream, rtcpReadStream, err := r.streamsForSSRC(ssrc)
+		if err != nil {
+			return nil, err
+		}
+
+		r.tracks[i].track.mu.Lock()
+		r.tracks[i].track.ssrc = ssrc
+		r.tracks[i].track.mu.Unlock()
+
+		r.tracks[i].rtpReadStream = rtpReadStream
+		r.receiveRTCP(&r.tracks[i], rtcpReadStream)
+
+		if len(probed) != 0 {
+			r.tracks[i].packets = make(chan receivedPacket, rtxBufferSize)
+			for _, data := range probed {
+				r.tracks[i].packets <- receivedPacket{data: data}
+			}
+			go r.readPrimary(rtpReadStream, r.tracks[i].packets)
+		}
+
+		// The RTX stream arrived first, it is received along with the stream it repairs
+		if repairSSRC := r.tracks[i].repairSSRC; repairSSRC != 0 {
+			if err := r.receiveRTX(&r.tracks[i], repairSSRC); err != nil {
+				return nil, err
+			}
+		}
+		return r.tracks[i].track, nil
+	}
+
+	return nil, fmt.Errorf("no trackStreams found for SSRC %d and RID %s", ssrc, rid)
+}
+
+// receiveRTXForRID receives the RTX stream with the SSRC ssrc, which repairs the Simulcast
+// stream of the Track with the RID rid
+func (r *RTPReceiver) receiveRTXForRID(rid string, ssrc uint32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.tracks {
+		if r.tracks[i].track.RID() != rid {
+			continue
+		} else if r.tracks[i].repairSSRC != 0 {
+			return fmt.Errorf("RTX stream of Track with RID %s is already being received", rid)
+		} else if r.tracks[i].rtpReadStream == nil {
+			r.tracks[i].repairSSRC = ssrc
+			return nil
+		}
+
+		return r.receiveRTX(&r.tracks[i], ssrc)
+	}
+
+	return fmt.Errorf("no trackStreams found for RTX SSRC %d and RID %s", ssrc, rid)
+}
+
+func (r *RTPReceiver) streamsForSSRC(ssrc uint32) (*srtp.ReadStreamSRTP, *srtp.ReadStreamSRTCP, error) {
+	srtpSession, err := r.transport.getSRTPSession()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rtpReadStream, err := srtpSession.OpenReadStream(ssrc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srtcpSession, err := r.transport.getSRTCPSession()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rtcpReadStream, err := srtcpSession.OpenReadStream(ssrc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rtpReadStream, rtcpReadStream, nil
 }
@@ -3,22 +3,146 @@
 package webrtc
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
+	mathRand "math/rand"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
+	"github.com/pion/sdp/v2"
 	"github.com/pion/srtp"
+	"github.com/pion/webrtc/v2/internal/util"
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 )
 
-// RTPSender allows an application to control how a given Track is encoded and transmitted to a remote peer
-type RTPSender struct {
+// trackEncoding is a single RTP stream sent by a RTPSender. A RTPSender has more than one
+// when sending Simulcast, each one with a distinct RID
+type trackEncoding struct {
 	track          *Track
 	rtcpReadStream *srtp.ReadStreamSRTCP
 
+	// The stream the encoding was created with. These are kept when the Track is replaced, the
+	// packets of the replacement Track are rewritten to continue the same stream
+	ssrc        uint32
+	payloadType uint8
+	rid         string
+	codec       *RTPCodec
+	cname       string
+
+	// Set by ReplaceTrack and when the encoding is resumed, the offsets are computed from the
+	// first packet written after that
+	rewrite, sourceChanged bool
+	sequenceNumberOffset   uint16
+	timestampOffset        uint32
+
+	// Set while DTMF tones are sent in the stream, the packets of the Track are dropped
+	sendingTelephoneEvent bool
+
+	// Settings changed with SetParameters, packets are dropped while the encoding isn't active
+	active                bool
+	maxBitrate            uint64
+	maxFramerate          float64
+	scaleResolutionDownBy float64
+	priority              RTCPriorityType
+
+	nackResponder *nackResponder
+
+	// The Interceptors of the API bound to this encoding when Send is called
+	streamInfo *StreamInfo
+	rtpWriter  RTPWriter
+	rtcpReader RTCPReader
+
+	// RFC 4588 retransmission stream, retransmissions are only sent on it once negotiated
+	rtxSSRC           uint32
+	rtxPayloadType    uint8
+	rtxSequenceNumber uint16
+	rtxNegotiated     bool
+
+	// Counters of the packets sent, used to build Sender Reports
+	packetCount      uint32
+	octetCount       uint32
+	lastRTPTimestamp uint32
+	lastPacketTime   time.Time
+	senderReport     SenderReportStats
+	bytesSent        uint64
+
+	// Where the next packets written continue from. Unlike the counters these include the
+	// packets the pacer still queues
+	nextSequenceNumber      uint16
+	lastWrittenRTPTimestamp uint32
+	lastWriteTime           time.Time
+
+	// The packets that couldn't be written
+	packetsDiscarded uint32
+	bytesDiscarded   uint64
+
+	// The feedback and the reception reports the remote sent about the encoding
+	feedbackReceived      *rtcpFeedbackCounter
+	receptionReport       rtcp.ReceptionReport
+	receptionReportTime   time.Time
+	roundTripTime         time.Duration
+	receptionReportsCount uint64
+
+	// Sequence number of the last FIR received, a FIR that repeats it was already handled
+	lastFIRSequenceNumber uint8
+	receivedFIR           bool
+}
+
+// newTrackEncoding creates the encoding of a Track, a RTX SSRC is reserved if the
+// MediaEngine has a RTX codec for the PayloadType of the Track
+func newTrackEncoding(api *API, track *Track) *trackEncoding {
+	// The Track is read directly since the callers hold its lock
+	encoding := &trackEncoding{
+		track:       track,
+		ssrc:        track.ssrc,
+		payloadType: track.payloadType,
+		rid:         track.rid,
+		codec:       track.codec,
+		cname:       track.label,
+		active:      true,
+		priority:    RTCPriorityTypeLow,
+
+		feedbackReceived: newRTCPFeedbackCounter(),
+	}
+	if _, ok := api.mediaEngine.getRTXPayloadType(track.payloadType); ok {
+		encoding.rtxSSRC = mathRand.Uint32()
+		encoding.rtxSequenceNumber = uint16(mathRand.Uint32())
+	}
+	return encoding
+}
+
+// RTPSender allows an application to control how a given Track is encoded and transmitted to a remote peer
+type RTPSender struct {
+	trackEncodings []*trackEncoding
+
 	transport *DTLSTransport
 
+	// The RTPTransceiver this RTPSender belongs to, used to determine the mid
+	rtpTransceiver *RTPTransceiver
+
+	// Header extensions negotiated with the remote, these are used to
+	// signal the mid and RID when sending Simulcast
+	headerExtensions []RTPHeaderExtensionParameter
+
+	// ID of the transport-wide sequence number header extension, 0 if it wasn't negotiated
+	transportCCExtensionID uint8
+
+	// Size of the retransmission buffer of every encoding, 0 if the NACK responder is disabled
+	nackBufferSize uint16
+
+	// Identifies the parameters returned by the last GetParameters, empty once they were set
+	transactionID string
+
+	onParametersChangeHdlr func(RTPSendParameters)
+	onKeyframeRequestHdlr  func(*Track)
+
+	// Sends DTMF tones in the stream of the first encoding, nil for video
+	dtmf *RTPDTMFSender
+
 	// TODO(sgotti) remove this when in future we'll avoid replacing
 	// a transceiver sender since we can just check the
 	// transceiver negotiation status
@@ -46,13 +170,112 @@ func (api *API) NewRTPSender(track *Track, transport *DTLSTransport) (*RTPSender
 	}
 	track.totalSenderCount++
 
-	return &RTPSender{
-		track:      track,
-		transport:  transport,
-		api:        api,
-		sendCalled: make(chan interface{}),
-		stopCalled: make(chan interface{}),
-	}, nil
+	r := &RTPSender{
+		trackEncodings: []*trackEncoding{newTrackEncoding(api, track)},
+		transport:      transport,
+		api:            api,
+		sendCalled:     make(chan interface{}),
+		stopCalled:     make(chan interface{}),
+	}
+	if track.kind == RTPCodecTypeAudio {
+		r.dtmf = newRTPDTMFSender(r)
+	}
+	return r, nil
+}
+
+// AddEncoding adds an additional Simulcast encoding to the RTPSender. The Track must have
+// a RID that isn't used by any other encoding of this RTPSender, and must share the Kind and
+// PayloadType of the Track the RTPSender was created with.
+func (r *RTPSender) AddEncoding(track *Track) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if track == nil {
+		return fmt.Errorf("Track must not be nil")
+	} else if r.hasSent() || r.hasStopped() {
+		return fmt.Errorf("AddEncoding must be called before the RTPSender is started")
+	} else if track.RID() == "" {
+		return fmt.Errorf("Track used as an additional encoding must have a RID")
+	}
+
+	baseTrack := r.trackEncodings[0].track
+	if baseTrack == nil || baseTrack.RID() == "" {
+		return fmt.Errorf("RTPSender must be created with a Track that has a RID to add encodings")
+	} else if baseTrack.Kind() != track.Kind() || baseTrack.PayloadType() != track.PayloadType() {
+		return fmt.Errorf("Track used as an additional encoding must match the Kind and PayloadType of the RTPSender")
+	}
+
+	for _, encoding := range r.trackEncodings {
+		if encoding.rid == track.RID() {
+			return fmt.Errorf("RTPSender already has an encoding with RID %s", track.RID())
+		} else if encoding.ssrc == track.SSRC() {
+			return fmt.Errorf("RTPSender already has an encoding with SSRC %d", track.SSRC())
+		}
+	}
+
+	if r.rtpTransceiver != nil {
+		if err := r.rtpTransceiver.validateSendEncoding(track.RID()); err != nil {
+			return err
+		}
+	}
+
+	track.mu.Lock()
+	defer track.mu.Unlock()
+	if track.receiver != nil {
+		return fmt.Errorf("RTPSender can not be constructed with remote track")
+	}
+	encoding := newTrackEncoding(r.api, track)
+	if r.nackBufferSize != 0 {
+		var err error
+		if encoding.nackResponder, err = newNACKResponder(r.nackBufferSize); err != nil {
+			return err
+		}
+	}
+	track.totalSenderCount++
+
+	r.trackEncodings = append(r.trackEncodings, encoding)
+	return nil
+}
+
+// EnableNACKResponder makes the RTPSender keep the last bufferSize packets of every encoding, and
+// retransmit them when the remote sends a NACK. bufferSize must be a power of two.
+// NACKs are answered while RTCP is read from the RTPSender, so Read, ReadRTCP or ReadSimulcast must be called.
+// The remote only sends NACKs if the codec was registered with the nack RTCPFeedback.
+func (r *RTPSender) EnableNACKResponder(bufferSize uint16) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nackBufferSize != 0 {
+		return fmt.Errorf("NACK responder is already enabled")
+	}
+
+	for _, encoding := range r.trackEncodings {
+		nackResponder, err := newNACKResponder(bufferSize)
+		if err != nil {
+			return err
+		}
+		encoding.nackResponder = nackResponder
+	}
+	r.nackBufferSize = bufferSize
+	return nil
+}
+
+// NACKResponderStats returns the counters of the NACK responder summed over every encoding
+func (r *RTPSender) NACKResponderStats() NACKResponderStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := NACKResponderStats{}
+	for _, encoding := range r.trackEncodings {
+		if encoding.nackResponder == nil {
+			continue
+		}
+
+		encodingStats := encoding.nackResponder.getStats()
+		stats.PacketsRetransmitted += encodingStats.PacketsRetransmitted
+		stats.PacketsUnavailable += encodingStats.PacketsUnavailable
+	}
+	return stats
 }
 
 func (r *RTPSender) isNegotiated() bool {
@@ -67,6 +290,83 @@ func (r *RTPSender) setNegotiated() {
 	r.negotiated = true
 }
 
+func (r *RTPSender) setRTPTransceiver(rtpTransceiver *RTPTransceiver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rtpTransceiver = rtpTransceiver
+}
+
+// setTrack replaces the Track of every encoding, this is used when the RTPTransceiver stops sending
+func (r *RTPSender) setTrack(track *Track) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, encoding := range r.trackEncodings {
+		encoding.track = track
+	}
+}
+
+// ReplaceTrack replaces the Track sent by the RTPSender without renegotiation. The replacement
+// must have the same codec, its packets are sent with the SSRC and PayloadType of the Track the
+// RTPSender was created with, and continue its sequence numbers and timestamps.
+// A nil Track stops sending until ReplaceTrack is called again. ReplaceTrack is not supported
+// when sending Simulcast.
+func (r *RTPSender) ReplaceTrack(track *Track) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.hasStopped() {
+		return fmt.Errorf("RTPSender has been stopped")
+	} else if len(r.trackEncodings) != 1 {
+		return fmt.Errorf("ReplaceTrack is not supported when sending Simulcast")
+	}
+
+	encoding := r.trackEncodings[0]
+	if track == encoding.track {
+		return nil
+	}
+
+	if track != nil {
+		track.mu.Lock()
+		defer track.mu.Unlock()
+
+		switch {
+		case track.receiver != nil:
+			return fmt.Errorf("RTPSender can not send a remote track")
+		case track.rid != "":
+			return fmt.Errorf("Track used to replace must not have a RID")
+		case track.codec == nil || encoding.codec == nil || track.codec.Type != encoding.codec.Type ||
+			!strings.EqualFold(track.codec.Name, encoding.codec.Name) ||
+			track.codec.ClockRate != encoding.codec.ClockRate || track.codec.Channels != encoding.codec.Channels:
+			return ErrIncompatibleTrack
+		}
+	}
+
+	if oldTrack := encoding.track; oldTrack != nil {
+		oldTrack.mu.Lock()
+		filtered := []*RTPSender{}
+		for _, s := range oldTrack.activeSenders {
+			if s != r {
+				filtered = append(filtered, s)
+			}
+		}
+		oldTrack.activeSenders = filtered
+		oldTrack.totalSenderCount--
+		oldTrack.mu.Unlock()
+	}
+
+	if track != nil {
+		track.totalSenderCount++
+		if r.hasSent() {
+			track.activeSenders = append(track.activeSenders, r)
+		}
+	}
+
+	encoding.track = track
+	encoding.rewrite = true
+	encoding.sourceChanged = true
+	return nil
+}
+
 // Transport returns the currently-configured *DTLSTransport or nil
 // if one has not yet been configured
 func (r *RTPSender) Transport() *DTLSTransport {
@@ -76,19 +376,172 @@ func (r *RTPSender) Transport() *DTLSTransport {
 }
 
 // Track returns the RTCRtpTransceiver track, or nil
+// When sending Simulcast this is the Track of the first encoding
 func (r *RTPSender) Track() *Track {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.track
+	return r.trackEncodings[0].track
+}
+
+// Tracks returns the Track of every encoding of this RTPSender
+func (r *RTPSender) Tracks() []*Track {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tracks := []*Track{}
+	for _, encoding := range r.trackEncodings {
+		if encoding.track != nil {
+			tracks = append(tracks, encoding.track)
+		}
+	}
+	return tracks
+}
+
+// GetParameters describes the current configuration for the encoding and
+// transmission of media on the sender's tracks, including the negotiated
+// RTP header extensions. Use the IDs of HeaderExtensions when setting
+// extensions on packets written to the Track.
+func (r *RTPSender) GetParameters() RTPSendParameters {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.transactionID = util.RandSeq(transactionIDLength)
+	parameters := r.parameters()
+	parameters.TransactionID = r.transactionID
+	return parameters
+}
+
+// GetSenderCapabilities returns the codecs and header extensions of kind kind the RTPSenders
+// of the API can send, these are the ones of its MediaEngine
+func (api *API) GetSenderCapabilities(kind RTPCodecType) RTPCapabilities {
+	return api.mediaEngine.getCapabilities(kind, RTPTransceiverDirectionSendonly)
+}
+
+// GetCapabilities returns the codecs and header extensions of kind kind the RTPSender can send,
+// see API.GetSenderCapabilities
+func (r *RTPSender) GetCapabilities(kind RTPCodecType) RTPCapabilities {
+	return r.api.GetSenderCapabilities(kind)
+}
+
+// SetParameters changes the Active, MaxBitrate, MaxFramerate, ScaleResolutionDownBy and Priority
+// of the encodings. parameters must be the ones returned by the last call to GetParameters, the
+// other fields can't be modified. Packets of an encoding that isn't active are dropped, when it is
+// resumed the sequence numbers and timestamps continue those sent before it was paused.
+func (r *RTPSender) SetParameters(parameters RTPSendParameters) error {
+	r.mu.Lock()
+
+	if r.hasStopped() {
+		r.mu.Unlock()
+		return fmt.Errorf("RTPSender has been stopped")
+	} else if r.transactionID == "" || parameters.TransactionID != r.transactionID {
+		r.mu.Unlock()
+		return &rtcerr.InvalidModificationError{Err: ErrStaleTransactionID}
+	} else if len(parameters.Encodings) != len(r.trackEncodings) {
+		r.mu.Unlock()
+		return &rtcerr.InvalidModificationError{Err: ErrModifyingEncodings}
+	}
+
+	for i, encoding := range r.trackEncodings {
+		switch e := parameters.Encodings[i]; {
+		case e.RID != encoding.rid || e.SSRC != encoding.ssrc || e.PayloadType != encoding.payloadType:
+			r.mu.Unlock()
+			return &rtcerr.InvalidModificationError{Err: ErrModifyingEncodings}
+		case e.MaxFramerate < 0:
+			r.mu.Unlock()
+			return &rtcerr.RangeError{Err: fmt.Errorf("MaxFramerate must not be negative")}
+		case e.ScaleResolutionDownBy != 0 && e.ScaleResolutionDownBy < 1:
+			r.mu.Unlock()
+			return &rtcerr.RangeError{Err: fmt.Errorf("ScaleResolutionDownBy must be at least 1")}
+		}
+	}
+
+	for i, encoding := range r.trackEncodings {
+		e := parameters.Encodings[i]
+		if e.Active && !encoding.active {
+			encoding.rewrite = true
+			encoding.sourceChanged = true
+		}
+
+		encoding.active = e.Active
+		encoding.maxBitrate = e.MaxBitrate
+		encoding.maxFramerate = e.MaxFramerate
+		encoding.scaleResolutionDownBy = e.ScaleResolutionDownBy
+		encoding.priority = e.Priority
+	}
+	r.transactionID = ""
+
+	updated := r.parameters()
+	hdlr := r.onParametersChangeHdlr
+	r.mu.Unlock()
+
+	if hdlr != nil {
+		hdlr(updated)
+	}
+	return nil
+}
+
+// OnParametersChange sets an event handler which is invoked when SetParameters changed the
+// parameters of the encodings, so the encoder or bandwidth controller feeding the RTPSender
+// can apply the new limits
+func (r *RTPSender) OnParametersChange(f func(RTPSendParameters)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onParametersChangeHdlr = f
+}
+
+// OnKeyframeRequest sets an event handler which is invoked when the remote requests a keyframe
+// of an encoding with a PLI or FIR, with the Track the encoding is currently sending. A source
+// feeding many RTPSenders can pass their requests to RTPReceiver.RequestKeyframe, which merges
+// them. Requests are handled while RTCP is read, so Read, ReadRTCP or ReadSimulcast must be called.
+func (r *RTPSender) OnKeyframeRequest(f func(track *Track)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onKeyframeRequestHdlr = f
+}
+
+// parameters returns the parameters of the RTPSender without a TransactionID
+func (r *RTPSender) parameters() RTPSendParameters {
+	parameters := RTPSendParameters{
+		Encodings:        []RTPEncodingParameters{},
+		HeaderExtensions: append([]RTPHeaderExtensionParameter{}, r.headerExtensions...),
+	}
+	for _, encoding := range r.trackEncodings {
+		coding := RTPCodingParameters{
+			RID:         encoding.rid,
+			SSRC:        encoding.ssrc,
+			PayloadType: encoding.payloadType,
+		}
+		if encoding.rtxNegotiated {
+			coding.RTX.SSRC = encoding.rtxSSRC
+		}
+		parameters.Encodings = append(parameters.Encodings, RTPEncodingParameters{
+			RTPCodingParameters:   coding,
+			Active:                encoding.active,
+			MaxBitrate:            encoding.maxBitrate,
+			MaxFramerate:          encoding.maxFramerate,
+			ScaleResolutionDownBy: encoding.scaleResolutionDownBy,
+			Priority:              encoding.priority,
+		})
+	}
+	return parameters
+}
+
+// isSimulcast tells if the RTPSender sends its encodings with RIDs
+func (r *RTPSender) isSimulcast() bool {
+	track := r.Track()
+	return track != nil && track.RID() != ""
 }
 
 // Send Attempts to set the parameters controlling the sending of media.
+// An entry of parameters.Encodings is expected for every encoding of the RTPSender.
 func (r *RTPSender) Send(parameters RTPSendParameters) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if r.hasSent() {
 		return fmt.Errorf("Send has already been called")
+	} else if len(parameters.Encodings) != len(r.trackEncodings) {
+		return fmt.Errorf("Send expected %d encodings but got %d", len(r.trackEncodings), len(parameters.Encodings))
 	}
 
 	srtcpSession, err := r.transport.getSRTCPSession()
@@ -96,55 +549,266 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 		return err
 	}
 
-	r.rtcpReadStream, err = srtcpSession.OpenReadStream(parameters.Encodings.SSRC)
-	if err != nil {
-		return err
+	for i, encoding := range r.trackEncodings {
+		encoding.rtcpReadStream, err = srtcpSession.OpenReadStream(parameters.Encodings[i].SSRC)
+		if err != nil {
+			return err
+		}
+
+		// The codec may have been negotiated with another PayloadType than the one of the Track
+		if payloadType := parameters.Encodings[i].PayloadType; payloadType != encoding.payloadType {
+			if codec, err := r.api.mediaEngine.getCodec(payloadType); err == nil && encoding.codec != nil && codec.matches(encoding.codec) {
+				encoding.payloadType = payloadType
+				encoding.rewrite = true
+			}
+		}
+
+		if rtxSSRC := parameters.Encodings[i].RTX.SSRC; rtxSSRC != 0 {
+			rtxPayloadType, ok := r.api.mediaEngine.getRTXPayloadType(encoding.payloadType)
+			if !ok {
+				return fmt.Errorf("no RTX codec is registered for PayloadType %d", encoding.payloadType)
+			}
+
+			encoding.rtxSSRC = rtxSSRC
+			encoding.rtxPayloadType = rtxPayloadType
+			encoding.rtxNegotiated = true
+		}
+	}
+	r.headerExtensions = parameters.HeaderExtensions
+	for _, e := range parameters.HeaderExtensions {
+		if e.URI == sdp.TransportCCURI {
+			r.transportCCExtensionID = uint8(e.ID)
+		}
 	}
 
-	r.track.mu.Lock()
-	r.track.activeSenders = append(r.track.activeSenders, r)
-	r.track.mu.Unlock()
+	for i, encoding := range r.trackEncodings {
+		r.bindEncoding(encoding, parameters.Encodings[i].SSRC)
+		if encoding.track == nil {
+			continue
+		}
+
+		encoding.track.mu.Lock()
+		encoding.track.activeSenders = append(encoding.track.activeSenders, r)
+		encoding.track.mu.Unlock()
+	}
+
+	// DTMF is sent with the telephone-event codec of the clock rate of the audio codec
+	if r.dtmf != nil {
+		codec, _ := r.api.mediaEngine.getTelephoneEventCodec(r.trackEncodings[0].codec.ClockRate)
+		r.dtmf.setCodec(codec)
+	}
 
 	close(r.sendCalled)
+	go r.runSenderReports(r.trackEncodings[0].codec.Type)
 	return nil
 }
 
+// SenderReportStats returns the last Sender Report sent for every encoding of the RTPSender.
+// Sender Reports are sent automatically once the encoding has sent packets.
+func (r *RTPSender) SenderReportStats() []SenderReportStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := []SenderReportStats{}
+	for _, encoding := range r.trackEncodings {
+		if encoding.senderReport.ReportsSent != 0 {
+			stats = append(stats, encoding.senderReport)
+		}
+	}
+	return stats
+}
+
+// runSenderReports sends Sender Reports at a randomized interval until the RTPSender is stopped
+func (r *RTPSender) runSenderReports(kind RTPCodecType) {
+	timer := time.NewTimer(rtcpReportInterval(kind))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-r.stopCalled:
+			return
+		case now := <-timer.C:
+			if pkts := r.senderReports(now); len(pkts) != 0 {
+				// Errors are ignored, the next report describes the stream again
+				_ = r.transport.writeRTCP(pkts)
+			}
+			timer.Reset(rtcpReportInterval(kind))
+		}
+	}
+}
+
+// senderReports builds a Sender Report for every encoding that sent packets, followed by their CNAMEs
+func (r *RTPSender) senderReports(now time.Time) []rtcp.Packet {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pkts := []rtcp.Packet{}
+	sourceDescription := &rtcp.SourceDescription{}
+	for _, encoding := range r.trackEncodings {
+		if encoding.packetCount == 0 {
+			continue
+		}
+
+		// The RTP timestamp is extrapolated from the last packet sent
+		rtpTime := encoding.lastRTPTimestamp + encoding.elapsedTimestamp(encoding.lastPacketTime, now)
+
+		encoding.senderReport = SenderReportStats{
+			SSRC:        encoding.ssrc,
+			NTPTime:     ntpTime(now),
+			RTPTime:     rtpTime,
+			PacketCount: encoding.packetCount,
+			OctetCount:  encoding.octetCount,
+			SentAt:      now,
+			ReportsSent: encoding.senderReport.ReportsSent + 1,
+		}
+
+		pkts = append(pkts, &rtcp.SenderReport{
+			SSRC:        encoding.senderReport.SSRC,
+			NTPTime:     encoding.senderReport.NTPTime,
+			RTPTime:     encoding.senderReport.RTPTime,
+			PacketCount: encoding.senderReport.PacketCount,
+			OctetCount:  encoding.senderReport.OctetCount,
+		})
+		sourceDescription.Chunks = append(sourceDescription.Chunks, rtcp.SourceDescriptionChunk{
+			Source: encoding.senderReport.SSRC,
+			Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: encoding.cname}},
+		})
+	}
+
+	if len(pkts) != 0 {
+		pkts = append(pkts, sourceDescription)
+	}
+	return pkts
+}
+
+// GetStats returns the stats of the outbound streams of the RTPSender, with the codecs, remote inbound
+// streams and transport they refer to
+func (r *RTPSender) GetStats() StatsReport {
+	collector := newStatsReportCollector()
+	r.collectStats(collector)
+	if iceTransport := r.transport.ICETransport(); iceTransport != nil {
+		iceTransport.collectStats(collector)
+	}
+	return collector.Ready()
+}
+
+// collectStats collects the outbound stream of every encoding of the RTPSender, and the remote
+// inbound stream described by the reception reports of the remote. These are read with the
+// RTCP of the RTPSender, so Read, ReadRTCP or ReadSimulcast must be called.
+func (r *RTPSender) collectStats(collector *statsReportCollector) {
+	if !r.hasSent() || r.hasStopped() {
+		return
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	for _, encoding := range r.trackEncodings {
+		feedback := encoding.feedbackReceived.get(encoding.ssrc)
+		stats := OutboundRTPStreamStats{
+			Timestamp:   statsTimestampFrom(now),
+			Type:        StatsTypeOutboundRTP,
+			ID:          fmt.Sprintf("OutboundRTP-%d", encoding.ssrc),
+			SSRC:        encoding.ssrc,
+			Kind:        encoding.codec.Type.String(),
+			TransportID: "iceTransport",
+			CodecID:     collectCodecStats(collector, encoding.codec, CodecTypeEncode),
+			FIRCount:    feedback.firCount,
+			PLICount:    feedback.pliCount,
+			NACKCount:   feedback.nackCount,
+			PacketsSent: encoding.packetCount,
+			BytesSent:   encoding.bytesSent,
+
+			PacketsDiscardedOnSend: encoding.packetsDiscarded,
+			BytesDiscardedOnSend:   encoding.bytesDiscarded,
+		}
+		if !encoding.lastPacketTime.IsZero() {
+			stats.LastPacketSentTimestamp = statsTimestampFrom(encoding.lastPacketTime)
+		}
+
+		if encoding.receptionReportsCount != 0 {
+			// The cumulative number of packets lost is a signed 24 bit value
+			report := encoding.receptionReport
+			remoteStats := RemoteInboundRTPStreamStats{
+				Timestamp:     statsTimestampFrom(encoding.receptionReportTime),
+				Type:          StatsTypeRemoteInboundRTP,
+				ID:            fmt.Sprintf("RemoteInboundRTP-%d", encoding.ssrc),
+				SSRC:          encoding.ssrc,
+				Kind:          stats.Kind,
+				TransportID:   stats.TransportID,
+				CodecID:       stats.CodecID,
+				PacketsLost:   int32(report.TotalLost<<8) >> 8,
+				LocalID:       stats.ID,
+				RoundTripTime: encoding.roundTripTime.Seconds(),
+				FractionLost:  float64(report.FractionLost) / 256,
+			}
+			if encoding.codec.ClockRate != 0 {
+				remoteStats.Jitter = float64(report.Jitter) / float64(encoding.codec.ClockRate)
+			}
+			stats.RemoteID = remoteStats.ID
+
+			collector.Collecting()
+			collector.Collect(remoteStats.ID, remoteStats)
+		}
+
+		collector.Collecting()
+		collector.Collect(stats.ID, stats)
+	}
+}
+
 // Stop irreversibly stops the RTPSender
 func (r *RTPSender) Stop() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	select {
-	case <-r.stopCalled:
+	if r.hasStopped() {
 		return nil
-	default:
 	}
 
-	r.track.mu.Lock()
-	defer r.track.mu.Unlock()
-	filtered := []*RTPSender{}
-	for _, s := range r.track.activeSenders {
-		if s != r {
-			filtered = append(filtered, s)
-		} else {
-			r.track.totalSenderCount--
+	for _, encoding := range r.trackEncodings {
+		if encoding.track == nil {
+			continue
+		}
+
+		encoding.track.mu.Lock()
+		filtered := []*RTPSender{}
+		for _, s := range encoding.track.activeSenders {
+			if s != r {
+				filtered = append(filtered, s)
+			}
 		}
+		encoding.track.activeSenders = filtered
+		encoding.track.totalSenderCount--
+		encoding.track.mu.Unlock()
 	}
-	r.track.activeSenders = filtered
 	close(r.stopCalled)
 
-	if r.hasSent() {
-		return r.rtcpReadStream.Close()
+	// The packets still waiting to be paced are never sent
+	if r.transport.pacer != nil {
+		r.transport.pacer.drop(r)
 	}
 
-	return nil
+	if !r.hasSent() {
+		return nil
+	}
+
+	closeErrs := []error{}
+	for _, encoding := range r.trackEncodings {
+		r.api.interceptor.UnbindLocalStream(encoding.streamInfo)
+		if err := encoding.rtcpReadStream.Close(); err != nil {
+			closeErrs = append(closeErrs, err)
+		}
+	}
+	return util.FlattenErrs(closeErrs)
 }
 
-// Read reads incoming RTCP for this RTPReceiver
+// Read reads incoming RTCP for this RTPSender
+// When sending Simulcast this is the RTCP of the first encoding
 func (r *RTPSender) Read(b []byte) (n int, err error) {
 	select {
 	case <-r.sendCalled:
-		return r.rtcpReadStream.Read(b)
+		return r.readEncodingRTCP(r.trackEncodings[0], b)
 	case <-r.stopCalled:
 		return 0, io.ErrClosedPipe
 	}
@@ -161,29 +825,527 @@ func (r *RTPSender) ReadRTCP() ([]rtcp.Packet, error) {
 	return rtcp.Unmarshal(b[:i])
 }
 
+// ReadSimulcast reads incoming RTCP for the encoding of this RTPSender with the given RID
+func (r *RTPSender) ReadSimulcast(b []byte, rid string) (n int, err error) {
+	select {
+	case <-r.sendCalled:
+		for _, encoding := range r.trackEncodings {
+			if encoding.rid == rid {
+				return r.readEncodingRTCP(encoding, b)
+			}
+		}
+		return 0, fmt.Errorf("no encoding with RID %s", rid)
+	case <-r.stopCalled:
+		return 0, io.ErrClosedPipe
+	}
+}
+
+// ReadSimulcastRTCP is a convenience method that wraps ReadSimulcast and unmarshals for you
+func (r *RTPSender) ReadSimulcastRTCP(rid string) ([]rtcp.Packet, error) {
+	b := make([]byte, receiveMTU)
+	i, err := r.ReadSimulcast(b, rid)
+	if err != nil {
+		return nil, err
+	}
+
+	return rtcp.Unmarshal(b[:i])
+}
+
+// bindEncoding binds the Interceptors of the API to an encoding, it is called by Send
+func (r *RTPSender) bindEncoding(encoding *trackEncoding, ssrc uint32) {
+	encoding.streamInfo = &StreamInfo{
+		SSRC:             ssrc,
+		RID:              encoding.rid,
+		Kind:             encoding.codec.Type,
+		PayloadType:      encoding.payloadType,
+		Codec:            encoding.codec,
+		HeaderExtensions: r.headerExtensions,
+	}
+	if encoding.track != nil {
+		encoding.streamInfo.ID = encoding.track.ID()
+	}
+
+	encoding.rtpWriter = r.api.interceptor.BindLocalStream(encoding.streamInfo, RTPWriterFunc(func(header *rtp.Header, payload []byte) (int, error) {
+		return r.sendEncodingRTP(encoding, header, payload)
+	}))
+	encoding.rtcpReader = r.api.interceptor.BindRTCPReader(encoding.streamInfo, RTCPReaderFunc(func(b []byte) (int, error) {
+		return r.readEncodingRTCPStream(encoding, b)
+	}))
+}
+
+// readEncodingRTCP reads incoming RTCP for a single encoding through the Interceptors
+func (r *RTPSender) readEncodingRTCP(encoding *trackEncoding, b []byte) (int, error) {
+	r.mu.RLock()
+	rtcpReader := encoding.rtcpReader
+	r.mu.RUnlock()
+
+	return rtcpReader.Read(b)
+}
+
+// reportsForSSRC returns pkts with only the reception reports about ssrc in their Receiver and
+// Sender Reports, and the transport-cc and REMB feedback only if it is addressed to ssrc first.
+// The RTCP of the remote is read from the stream of every SSRC it is about, so this way each
+// report and feedback reaches the BandwidthEstimator once
+func reportsForSSRC(pkts []rtcp.Packet, ssrc uint32) []rtcp.Packet {
+	filter := func(reports []rtcp.ReceptionReport) []rtcp.ReceptionReport {
+		filtered := []rtcp.ReceptionReport{}
+		for _, report := range reports {
+			if report.SSRC == ssrc {
+				filtered = append(filtered, report)
+			}
+		}
+		return filtered
+	}
+
+	filtered := make([]rtcp.Packet, 0, len(pkts))
+	for _, pkt := range pkts {
+		switch p := pkt.(type) {
+		case *rtcp.ReceiverReport:
+			report := *p
+			report.Reports = filter(p.Reports)
+			pkt = &report
+		case *rtcp.SenderReport:
+			report := *p
+			report.Reports = filter(p.Reports)
+			pkt = &report
+		case *rtcp.TransportLayerCC:
+			if p.MediaSSRC != ssrc {
+				continue
+			}
+		case *rtcp.ReceiverEstimatedMaximumBitrate:
+			if len(p.SSRCs) == 0 || p.SSRCs[0] != ssrc {
+				continue
+			}
+		}
+		filtered = append(filtered, pkt)
+	}
+	return filtered
+}
+
+// readEncodingRTCPStream reads incoming RTCP for a single encoding and answers the NACKs in it
+func (r *RTPSender) readEncodingRTCPStream(encoding *trackEncoding, b []byte) (int, error) {
+	n, err := encoding.rtcpReadStream.Read(b)
+	if err != nil {
+		return n, err
+	}
+
+	pkts, err := rtcp.Unmarshal(b[:n])
+	if err != nil {
+		return n, nil
+	}
+	r.mu.RLock()
+	ssrc := encoding.ssrc
+	r.mu.RUnlock()
+
+	now := time.Now()
+	r.transport.bandwidthEstimation.rtcp(reportsForSSRC(pkts, ssrc), now)
+	encoding.feedbackReceived.count(pkts)
+
+	r.mu.Lock()
+	nackResponder := encoding.nackResponder
+	keyframeRequested := false
+	for _, pkt := range pkts {
+		var reports []rtcp.ReceptionReport
+		switch p := pkt.(type) {
+		case *rtcp.ReceiverReport:
+			reports = p.Reports
+		case *rtcp.SenderReport:
+			reports = p.Reports
+		case *rtcp.PictureLossIndication:
+			keyframeRequested = keyframeRequested || p.MediaSSRC == ssrc
+		case *rtcp.FullIntraRequest:
+			for _, entry := range p.FIR {
+				if entry.SSRC != ssrc || (encoding.receivedFIR && entry.SequenceNumber == encoding.lastFIRSequenceNumber) {
+					continue
+				}
+
+				encoding.lastFIRSequenceNumber, encoding.receivedFIR = entry.SequenceNumber, true
+				keyframeRequested = true
+			}
+		}
+
+		for _, report := range reports {
+			if report.SSRC != ssrc {
+				continue
+			}
+
+			encoding.receptionReport, encoding.receptionReportTime = report, now
+			encoding.receptionReportsCount++
+			if rtt, ok := roundTripTime(report, now); ok {
+				encoding.roundTripTime = rtt
+			}
+		}
+	}
+	track, onKeyframeRequestHdlr := encoding.track, r.onKeyframeRequestHdlr
+	r.mu.Unlock()
+
+	if keyframeRequested && onKeyframeRequestHdlr != nil {
+		onKeyframeRequestHdlr(track)
+	}
+	if nackResponder == nil {
+		return n, nil
+	}
+
+	for _, pkt := range pkts {
+		if nack, ok := pkt.(*rtcp.TransportLayerNack); ok && nack.MediaSSRC == ssrc {
+			r.retransmit(encoding, nackResponder, nack)
+		}
+	}
+	return n, nil
+}
+
+// retransmit sends the packets requested by a NACK again, on the RTX stream if it was negotiated
+func (r *RTPSender) retransmit(encoding *trackEncoding, nackResponder *nackResponder, nack *rtcp.TransportLayerNack) {
+	srtpSession, err := r.transport.getSRTPSession()
+	if err != nil {
+		return
+	}
+
+	writeStream, err := srtpSession.OpenWriteStream()
+	if err != nil {
+		return
+	}
+
+	for _, pair := range nack.Nacks {
+		for _, sequenceNumber := range pair.PacketList() {
+			p := nackResponder.get(sequenceNumber)
+			if p == nil {
+				continue
+			}
+
+			header, payload := &p.Header, p.Payload
+			r.mu.Lock()
+			if encoding.rtxNegotiated {
+				header, payload = wrapRTX(header, payload, encoding.rtxSSRC, encoding.rtxPayloadType, encoding.rtxSequenceNumber)
+				encoding.rtxSequenceNumber++
+			}
+			r.mu.Unlock()
+
+			_, _ = r.writeToStream(encoding.kind(), writeStream, header, payload, func(err error) {
+				if err == nil {
+					nackResponder.packetRetransmitted()
+				}
+			})
+		}
+	}
+}
+
 // SendRTP sends a RTP packet on this RTPSender
 //
 // You should use Track instead to send packets. This is exposed because pion/webrtc currently
 // provides no way for users to send RTP packets directly. This is makes users unable to send
 // retransmissions to a single RTPSender. in /v3 this will go away, only use this API if you really
 // need it.
+//
+// When sending Simulcast the encoding is selected by the SSRC of the header.
 func (r *RTPSender) SendRTP(header *rtp.Header, payload []byte) (int, error) {
+	r.mu.RLock()
+	encoding := r.trackEncodings[0]
+	for _, e := range r.trackEncodings {
+		if e.ssrc == header.SSRC {
+			encoding = e
+			break
+		}
+	}
+	r.mu.RUnlock()
+
+	return r.writeEncodingRTP(encoding, header, payload)
+}
+
+// writeRTP sends a RTP packet for the encoding of the given Track
+func (r *RTPSender) writeRTP(track *Track, header *rtp.Header, payload []byte) (int, error) {
+	r.mu.Lock()
+	var encoding *trackEncoding
+	for _, e := range r.trackEncodings {
+		if e.track == track {
+			encoding = e
+			break
+		}
+	}
+	if encoding != nil && encoding.sendingTelephoneEvent {
+		r.mu.Unlock()
+		return 0, nil
+	}
+	if encoding != nil && encoding.rewrite {
+		header = encoding.rewriteHeader(header, time.Now())
+	}
+	r.mu.Unlock()
+
+	if encoding == nil {
+		return 0, fmt.Errorf("Track is not sent by this RTPSender")
+	}
+	return r.writeEncodingRTP(encoding, header, payload)
+}
+
+func (r *RTPSender) writeEncodingRTP(encoding *trackEncoding, header *rtp.Header, payload []byte) (int, error) {
 	select {
 	case <-r.stopCalled:
 		return 0, fmt.Errorf("RTPSender has been stopped")
 	case <-r.sendCalled:
-		srtpSession, err := r.transport.getSRTPSession()
-		if err != nil {
+		r.mu.RLock()
+		rtpWriter, active := encoding.rtpWriter, encoding.active
+		r.mu.RUnlock()
+
+		if !active {
+			return 0, nil
+		}
+		return rtpWriter.WriteRTP(header, payload)
+	}
+}
+
+// sendEncodingRTP sends a RTP packet of an encoding once it went through the Interceptors
+func (r *RTPSender) sendEncodingRTP(encoding *trackEncoding, header *rtp.Header, payload []byte) (int, error) {
+	srtpSession, err := r.transport.getSRTPSession()
+	if err != nil {
+		return 0, err
+	}
+
+	writeStream, err := srtpSession.OpenWriteStream()
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.RLock()
+	nackResponder := encoding.nackResponder
+	r.mu.RUnlock()
+
+	if encoding.rid != "" {
+		if header, err = r.simulcastHeader(encoding.rid, header); err != nil {
 			return 0, err
 		}
+	}
+
+	if nackResponder != nil {
+		nackResponder.add(header, payload)
+	}
+
+	r.mu.Lock()
+	encoding.nextSequenceNumber = header.SequenceNumber + 1
+	encoding.lastWrittenRTPTimestamp = header.Timestamp
+	encoding.lastWriteTime = time.Now()
+	r.mu.Unlock()
+
+	// The counters of the Sender Reports and stats only include the packets actually written
+	timestamp, size := header.Timestamp, len(payload)
+	return r.writeToStream(encoding.kind(), writeStream, header, payload, func(err error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
 
-		writeStream, err := srtpSession.OpenWriteStream()
 		if err != nil {
-			return 0, err
+			encoding.packetsDiscarded++
+			encoding.bytesDiscarded += uint64(size)
+			return
 		}
+		encoding.packetCount++
+		encoding.octetCount += uint32(size)
+		encoding.bytesSent += uint64(size)
+		encoding.lastRTPTimestamp = timestamp
+		encoding.lastPacketTime = time.Now()
+	})
+}
+
+// writeToStream writes a packet of kind, through the pacer if pacing is enabled. sent is called
+// with the result of the write once the packet was written or discarded. The packet is copied
+// since it is written once the caller returned, the error of a paced write only reaches sent
+func (r *RTPSender) writeToStream(kind RTPCodecType, writeStream *srtp.WriteStreamSRTP, header *rtp.Header, payload []byte, sent func(error)) (int, error) {
+	pacer := r.transport.pacer
+	if pacer == nil {
+		n, err := r.writeToStreamNow(writeStream, header, payload)
+		sent(err)
+		return n, err
+	}
 
+	h, p := cloneHeader(header), append([]byte{}, payload...)
+	n := h.MarshalSize() + len(p)
+	if err := pacer.enqueue(r, kind, n, func() {
+		_, err := r.writeToStreamNow(writeStream, h, p)
+		sent(err)
+	}); err != nil {
+		sent(err)
+		return 0, err
+	}
+	return n, nil
+}
+
+// writeToStreamNow writes a packet, with a transport-wide sequence number if transport-cc was negotiated
+func (r *RTPSender) writeToStreamNow(writeStream *srtp.WriteStreamSRTP, header *rtp.Header, payload []byte) (int, error) {
+	r.mu.RLock()
+	transportCCExtensionID := r.transportCCExtensionID
+	r.mu.RUnlock()
+
+	if transportCCExtensionID == 0 {
 		return writeStream.WriteRTP(header, payload)
 	}
+
+	bandwidthEstimation := r.transport.bandwidthEstimation
+	transportSequenceNumber := bandwidthEstimation.nextTransportSequenceNumber()
+	value := make([]byte, 2)
+	binary.BigEndian.PutUint16(value, transportSequenceNumber)
+
+	h := cloneHeader(header)
+	if err := h.SetExtension(transportCCExtensionID, value); err != nil {
+		return 0, err
+	}
+
+	n, err := writeStream.WriteRTP(h, payload)
+	if err == nil {
+		bandwidthEstimation.packetSent(transportSequenceNumber, h.MarshalSize()+len(payload), time.Now())
+	}
+	return n, err
+}
+
+// simulcastHeader returns a copy of header with the mid and RID header extensions set. The
+// header is copied since the same packet is written to every RTPSender of a Track
+func (r *RTPSender) simulcastHeader(rid string, header *rtp.Header) (*rtp.Header, error) {
+	r.mu.RLock()
+	headerExtensions := r.headerExtensions
+	rtpTransceiver := r.rtpTransceiver
+	r.mu.RUnlock()
+
+	h := cloneHeader(header)
+	for _, e := range headerExtensions {
+		var value string
+		switch e.URI {
+		case sdp.SDESMidURI:
+			if rtpTransceiver == nil {
+				continue
+			}
+			value = rtpTransceiver.Mid()
+		case sdp.SDESRTPStreamIDURI:
+			value = rid
+		default:
+			continue
+		}
+
+		if err := h.SetExtension(uint8(e.ID), []byte(value)); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// rtxSSRC returns the SSRC reserved for retransmissions of track, or 0 if there is none
+func (r *RTPSender) rtxSSRC(track *Track) uint32 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, encoding := range r.trackEncodings {
+		if encoding.track == track {
+			return encoding.rtxSSRC
+		}
+	}
+	return 0
+}
+
+// codingParameters returns the RID, SSRC and PayloadType track is sent with. These are the ones
+// of the Track the encoding was created with, even once another Track replaced it
+func (r *RTPSender) codingParameters(track *Track) RTPCodingParameters {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, encoding := range r.trackEncodings {
+		if encoding.track == track {
+			return RTPCodingParameters{RID: encoding.rid, SSRC: encoding.ssrc, PayloadType: encoding.payloadType}
+		}
+	}
+	return RTPCodingParameters{}
+}
+
+// kind returns the kind of the codec the encoding was created with
+func (e *trackEncoding) kind() RTPCodecType {
+	if e.codec == nil {
+		return RTPCodecType(0)
+	}
+	return e.codec.Type
+}
+
+// elapsedTimestamp returns the RTP timestamp units elapsed between since, the time a packet was
+// sent or written, and now
+func (e *trackEncoding) elapsedTimestamp(since, now time.Time) uint32 {
+	if e.codec == nil || since.IsZero() {
+		return 0
+	}
+	return uint32(now.Sub(since).Seconds() * float64(e.codec.ClockRate))
+}
+
+// rewriteHeader maps a packet of a replacement Track onto the SSRC and PayloadType of the encoding,
+// continuing the sequence numbers and timestamps of the packets sent before the Track was replaced
+func (e *trackEncoding) rewriteHeader(header *rtp.Header, now time.Time) *rtp.Header {
+	if e.sourceChanged {
+		e.sourceChanged = false
+		e.sequenceNumberOffset = e.nextSequenceNumber - header.SequenceNumber
+
+		// The timestamps must increase even if the Track is replaced right after a packet was written
+		elapsed := e.elapsedTimestamp(e.lastWriteTime, now)
+		if elapsed == 0 {
+			elapsed = 1
+		}
+		e.timestampOffset = e.lastWrittenRTPTimestamp + elapsed - header.Timestamp
+	}
+
+	h := cloneHeader(header)
+	h.SSRC = e.ssrc
+	h.PayloadType = e.payloadType
+	h.SequenceNumber += e.sequenceNumberOffset
+	h.Timestamp += e.timestampOffset
+	return h
+}
+
+// DTMF returns the RTPDTMFSender sending DTMF tones with an audio RTPSender, nil for video
+func (r *RTPSender) DTMF() *RTPDTMFSender {
+	return r.dtmf
+}
+
+// startTelephoneEvent drops the packets of the Track of the first encoding until endTelephoneEvent is
+// called, so telephone-events can be sent in its stream. It returns the RTP timestamp of an event starting now
+func (r *RTPSender) startTelephoneEvent() uint32 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	encoding := r.trackEncodings[0]
+	encoding.sendingTelephoneEvent = true
+	return encoding.lastWrittenRTPTimestamp + encoding.elapsedTimestamp(encoding.lastWriteTime, time.Now())
+}
+
+// writeTelephoneEvent sends a telephone-event packet in the stream of the first encoding
+func (r *RTPSender) writeTelephoneEvent(payloadType uint8, timestamp uint32, marker bool, payload []byte) error {
+	r.mu.RLock()
+	encoding := r.trackEncodings[0]
+	header := &rtp.Header{
+		Version:        2,
+		Marker:         marker,
+		PayloadType:    payloadType,
+		SequenceNumber: encoding.nextSequenceNumber,
+		Timestamp:      timestamp,
+		SSRC:           encoding.ssrc,
+	}
+	r.mu.RUnlock()
+
+	_, err := r.writeEncodingRTP(encoding, header, payload)
+	return err
+}
+
+// endTelephoneEvent resumes the packets of the Track, they continue the sequence numbers and
+// timestamps of the telephone-events
+func (r *RTPSender) endTelephoneEvent() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	encoding := r.trackEncodings[0]
+	encoding.sendingTelephoneEvent = false
+	encoding.rewrite = true
+	encoding.sourceChanged = true
+}
+
+// hasStopped tells if Stop has been called for this instance
+func (r *RTPSender) hasStopped() bool {
+	select {
+	case <-r.stopCalled:
+		return true
+	default:
+		return false
+	}
 }
 
 // hasSent tells if data has been ever sent for this instance
@@ -195,3 +1357,10 @@ func (r *RTPSender) hasSent() bool {
 		return false
 	}
 }
+
+// cloneHeader copies a header, so its extensions can be changed without modifying the original
+func cloneHeader(header *rtp.Header) *rtp.Header {
+	h := *header
+	h.Extensions = append([]rtp.Extension{}, header.Extensions...)
+	return &h
+}
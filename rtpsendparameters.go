@@ -1,6 +1,11 @@
 package webrtc
 
-// RTPSendParameters contains the RTP stack settings used by receivers
+// RTPSendParameters contains the RTP stack settings used by senders
 type RTPSendParameters struct {
-	Encodings RTPEncodingParameters
+	// TransactionID identifies the last call to RTPSender.GetParameters, SetParameters
+	// only accepts the parameters it returned
+	TransactionID string
+
+	Encodings        []RTPEncodingParameters
+	HeaderExtensions []RTPHeaderExtensionParameter
 }
@@ -4,7 +4,10 @@ package webrtc
 
 import (
 	"fmt"
+	"strings"
 	"sync/atomic"
+
+	"github.com/pion/webrtc/v2/pkg/rtcerr"
 )
 
 // RTPTransceiver represents a combination of an RTPSender and an RTPReceiver that share a common mid.
@@ -14,8 +17,16 @@ type RTPTransceiver struct {
 	receiver  atomic.Value // *RTPReceiver
 	direction atomic.Value // RTPTransceiverDirection
 
+	// Codecs set with SetCodecPreferences
+	codecPreferences atomic.Value // []RTPCodecCapability
+
+	// RIDs requested with RtpTransceiverInit.SendEncodings
+	sendEncodings []RTPEncodingParameters
+
 	stopped bool
 	kind    RTPCodecType
+
+	api *API
 }
 
 // Sender returns the RTPTransceiver's RTPSender if it has one
@@ -28,9 +39,85 @@ func (t *RTPTransceiver) Sender() *RTPSender {
 }
 
 func (t *RTPTransceiver) setSender(s *RTPSender) {
+	if s != nil {
+		s.setRTPTransceiver(t)
+	}
 	t.sender.Store(s)
 }
 
+// validateSendEncoding checks that a RID was requested with RtpTransceiverInit.SendEncodings.
+// Any RID is accepted if no SendEncodings were given
+func (t *RTPTransceiver) validateSendEncoding(rid string) error {
+	if len(t.sendEncodings) == 0 {
+		return nil
+	}
+
+	for _, encoding := range t.sendEncodings {
+		if encoding.RID == rid {
+			return nil
+		}
+	}
+	return fmt.Errorf("RID %s was not requested in SendEncodings", rid)
+}
+
+// SetCodecPreferences sets the codecs the RTPTransceiver negotiates, in descending order of
+// preference. Every codec must be supported by the MediaEngine for the kind of the RTPTransceiver.
+// RTX codecs are negotiated for the preferred codecs they are associated with.
+// An empty list restores the default, every codec of the MediaEngine.
+// The preferences apply to the offers and answers created afterwards.
+func (t *RTPTransceiver) SetCodecPreferences(codecs []RTPCodecCapability) error {
+	if len(codecs) == 0 {
+		t.codecPreferences.Store([]RTPCodecCapability{})
+		return nil
+	}
+
+	onlyRTX := true
+	for _, capability := range codecs {
+		supported := false
+		for _, codec := range t.api.mediaEngine.GetCodecsByKind(t.kind) {
+			if codec.matchesCapability(capability) {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return &rtcerr.InvalidModificationError{Err: ErrCodecNotFound}
+		}
+
+		if !strings.EqualFold(capability.MimeType, t.kind.String()+"/"+RTX) {
+			onlyRTX = false
+		}
+	}
+	if onlyRTX {
+		return &rtcerr.InvalidModificationError{Err: ErrCodecPreferencesOnlyRTX}
+	}
+
+	t.codecPreferences.Store(append([]RTPCodecCapability{}, codecs...))
+	return nil
+}
+
+// getCodecs returns the codecs of mediaEngine matching the codec preferences of the RTPTransceiver in
+// their order, or all the codecs of its kind if it has none
+func (t *RTPTransceiver) getCodecs(mediaEngine *MediaEngine) []*RTPCodec {
+	mediaEngineCodecs := mediaEngine.GetCodecsByKind(t.kind)
+	preferences, _ := t.codecPreferences.Load().([]RTPCodecCapability)
+	if len(preferences) == 0 {
+		return mediaEngineCodecs
+	}
+
+	codecs := []*RTPCodec{}
+	added := map[*RTPCodec]bool{}
+	for _, capability := range preferences {
+		for _, codec := range mediaEngineCodecs {
+			if !added[codec] && codec.matchesCapability(capability) {
+				added[codec] = true
+				codecs = append(codecs, codec)
+			}
+		}
+	}
+	return codecs
+}
+
 // Receiver returns the RTPTransceiver's RTPReceiver if it has one
 func (t *RTPTransceiver) Receiver() *RTPReceiver {
 	if v := t.receiver.Load(); v != nil {
@@ -93,7 +180,7 @@ func (t *RTPTransceiver) setDirection(d RTPTransceiverDirection) {
 }
 
 func (t *RTPTransceiver) setSendingTrack(track *Track) error {
-	t.Sender().track = track
+	t.Sender().setTrack(track)
 	if track == nil {
 		t.setSender(nil)
 	}
@@ -0,0 +1,62 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pion/rtp"
+)
+
+// Size of the original sequence number (OSN) that prefixes the payload of a RTX packet
+const rtxOSNLength = 2
+
+// RepairedRTPStreamIDURI is the header extension carrying the RID of the stream a RTX packet
+// repairs. It identifies the RTX streams of Simulcast, which aren't declared with their SSRC
+const RepairedRTPStreamIDURI = "urn:ietf:params:rtp-hdrext:sdes:repaired-rtp-stream-id"
+
+// wrapRTX builds the RFC 4588 retransmission of a packet. The original sequence number is
+// put in front of the payload, and the packet is sent with the SSRC and PayloadType of the RTX stream
+func wrapRTX(header *rtp.Header, payload []byte, ssrc uint32, payloadType uint8, sequenceNumber uint16) (*rtp.Header, []byte) {
+	h := cloneHeader(header)
+	h.SSRC = ssrc
+	h.PayloadType = payloadType
+	h.SequenceNumber = sequenceNumber
+	h.Padding = false
+
+	rtxPayload := make([]byte, rtxOSNLength+len(payload))
+	binary.BigEndian.PutUint16(rtxPayload, header.SequenceNumber)
+	copy(rtxPayload[rtxOSNLength:], payload)
+
+	return h, rtxPayload
+}
+
+// unwrapRTX restores the original packet from a RFC 4588 retransmission, using the SSRC and
+// PayloadType of the stream it repairs. Padding only packets can't be unwrapped
+func unwrapRTX(raw []byte, ssrc uint32, payloadType uint8) ([]byte, error) {
+	p := &rtp.Packet{}
+	if err := p.Unmarshal(raw); err != nil {
+		return nil, err
+	}
+
+	payload := p.Payload
+	if p.Padding && len(payload) > 0 {
+		paddingLength := int(payload[len(payload)-1])
+		if paddingLength > len(payload) {
+			return nil, fmt.Errorf("RTX packet has invalid padding")
+		}
+		payload = payload[:len(payload)-paddingLength]
+	}
+	if len(payload) <= rtxOSNLength {
+		return nil, fmt.Errorf("RTX packet has no payload")
+	}
+
+	p.SequenceNumber = binary.BigEndian.Uint16(payload)
+	p.SSRC = ssrc
+	p.PayloadType = payloadType
+	p.Padding = false
+	p.Payload = payload[rtxOSNLength:]
+
+	return p.Marshal()
+}
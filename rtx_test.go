@@ -0,0 +1,70 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTX_WrapUnwrap(t *testing.T) {
+	original := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    96,
+			SequenceNumber: 5000,
+			Timestamp:      3000,
+			SSRC:           1234,
+		},
+		Payload: []byte{0x01, 0x02, 0x03},
+	}
+
+	header, payload := wrapRTX(&original.Header, original.Payload, 5678, 97, 10)
+	assert.Equal(t, uint32(5678), header.SSRC)
+	assert.Equal(t, uint8(97), header.PayloadType)
+	assert.Equal(t, uint16(10), header.SequenceNumber)
+	assert.Equal(t, []byte{0x13, 0x88, 0x01, 0x02, 0x03}, payload)
+
+	// The original header must not be modified
+	assert.Equal(t, uint32(1234), original.SSRC)
+
+	raw, err := (&rtp.Packet{Header: *header, Payload: payload}).Marshal()
+	assert.NoError(t, err)
+
+	unwrapped, err := unwrapRTX(raw, 1234, 96)
+	assert.NoError(t, err)
+
+	expected, err := original.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, unwrapped)
+}
+
+func TestRTX_UnwrapPadding(t *testing.T) {
+	header := rtp.Header{
+		Version:        2,
+		Padding:        true,
+		PayloadType:    97,
+		SequenceNumber: 10,
+		SSRC:           5678,
+	}
+
+	// Padding only packets, as sent for bandwidth probing
+	raw, err := (&rtp.Packet{Header: header, Payload: []byte{0x00, 0x00, 0x00, 0x04}}).Marshal()
+	assert.NoError(t, err)
+	_, err = unwrapRTX(raw, 1234, 96)
+	assert.Error(t, err)
+
+	raw, err = (&rtp.Packet{Header: header, Payload: []byte{0x00, 0x01, 0xAA, 0x00, 0x02}}).Marshal()
+	assert.NoError(t, err)
+	unwrapped, err := unwrapRTX(raw, 1234, 96)
+	assert.NoError(t, err)
+
+	p := &rtp.Packet{}
+	assert.NoError(t, p.Unmarshal(unwrapped))
+	assert.False(t, p.Padding)
+	assert.Equal(t, uint16(1), p.SequenceNumber)
+	assert.Equal(t, []byte{0xAA}, p.Payload)
+}
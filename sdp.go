@@ -4,6 +4,7 @@ package webrtc
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -18,17 +19,56 @@ type trackDetails struct {
 	label string
 	id    string
 	ssrc  uint32
+	rids  []string
+
+	// SSRC of the RFC 4588 retransmission stream, 0 if there is none
+	repairSSRC uint32
+}
+
+func trackDetailsForSSRC(trackDetails []trackDetails, ssrc uint32) *trackDetails {
+	for i := range trackDetails {
+		if trackDetails[i].ssrc == ssrc {
+			return &trackDetails[i]
+		}
+	}
+	return nil
+}
+
+func trackDetailsForRID(trackDetails []trackDetails, mid, rid string) *trackDetails {
+	for i := range trackDetails {
+		if trackDetails[i].mid != mid {
+			continue
+		}
+
+		for _, r := range trackDetails[i].rids {
+			if r == rid {
+				return &trackDetails[i]
+			}
+		}
+	}
+	return nil
+}
+
+func filterTrackWithSSRC(incomingTracks []trackDetails, ssrc uint32) []trackDetails {
+	filtered := []trackDetails{}
+	for i := range incomingTracks {
+		if incomingTracks[i].ssrc != ssrc {
+			filtered = append(filtered, incomingTracks[i])
+		}
+	}
+	return filtered
 }
 
 // extract all trackDetails from an SDP.
-func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) map[uint32]trackDetails {
-	incomingTracks := map[uint32]trackDetails{}
-	rtxRepairFlows := map[uint32]bool{}
+func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) []trackDetails {
+	incomingTracks := []trackDetails{}
+	rtxRepairFlows := map[uint32]uint32{}
 
 	for _, media := range s.MediaDescriptions {
 		// Plan B can have multiple tracks in a signle media section
 		trackLabel := ""
 		trackID := ""
+		tracksInMediaSection := []trackDetails{}
 
 		// If media section is recvonly or inactive skip
 		if _, ok := media.Attribute(sdp.AttrKeyRecvOnly); ok {
@@ -42,12 +82,12 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) m
 			continue
 		}
 
-		for _, attr := range media.Attributes {
-			codecType := NewRTPCodecType(media.MediaName.Media)
-			if codecType == 0 {
-				continue
-			}
+		codecType := NewRTPCodecType(media.MediaName.Media)
+		if codecType == 0 {
+			continue
+		}
 
+		for _, attr := range media.Attributes {
 			switch attr.Key {
 			case sdp.AttrKeySSRCGroup:
 				split := strings.Split(attr.Value, " ")
@@ -57,7 +97,7 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) m
 					// as this declares that the second SSRC (632943048) is a rtx repair flow (RFC4588) for the first
 					// (2231627014) as specified in RFC5576
 					if len(split) == 3 {
-						_, err := strconv.ParseUint(split[1], 10, 32)
+						primaryFlow, err := strconv.ParseUint(split[1], 10, 32)
 						if err != nil {
 							log.Warnf("Failed to parse SSRC: %v", err)
 							continue
@@ -67,8 +107,8 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) m
 							log.Warnf("Failed to parse SSRC: %v", err)
 							continue
 						}
-						rtxRepairFlows[uint32(rtxRepairFlow)] = true
-						delete(incomingTracks, uint32(rtxRepairFlow)) // Remove if rtx was added as track before
+						rtxRepairFlows[uint32(rtxRepairFlow)] = uint32(primaryFlow)
+						tracksInMediaSection = filterTrackWithSSRC(tracksInMediaSection, uint32(rtxRepairFlow)) // Remove if rtx was added as track before
 					}
 				}
 
@@ -89,10 +129,12 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) m
 					log.Warnf("Failed to parse SSRC: %v", err)
 					continue
 				}
-				if rtxRepairFlow := rtxRepairFlows[uint32(ssrc)]; rtxRepairFlow {
+				if _, rtxRepairFlow := rtxRepairFlows[uint32(ssrc)]; rtxRepairFlow {
 					continue // This ssrc is a RTX repair flow, ignore
 				}
-				if existingValues, ok := incomingTracks[uint32(ssrc)]; ok && existingValues.label != "" && existingValues.id != "" {
+
+				existingValues := trackDetailsForSSRC(tracksInMediaSection, uint32(ssrc))
+				if existingValues != nil && existingValues.label != "" && existingValues.id != "" {
 					continue // This ssrc is already fully defined
 				}
 
@@ -103,14 +145,320 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) m
 
 				// Plan B might send multiple a=ssrc lines under a single m= section. This is also why a single trackDetails{}
 				// is not defined at the top of the loop over s.MediaDescriptions.
-				incomingTracks[uint32(ssrc)] = trackDetails{midValue, codecType, trackLabel, trackID, uint32(ssrc)}
+				details := trackDetails{mid: midValue, kind: codecType, label: trackLabel, id: trackID, ssrc: uint32(ssrc)}
+				if existingValues != nil {
+					*existingValues = details
+				} else {
+					tracksInMediaSection = append(tracksInMediaSection, details)
+				}
 			}
 		}
+
+		// Retransmissions are received with the Track they repair
+		for repairFlow, primaryFlow := range rtxRepairFlows {
+			if details := trackDetailsForSSRC(tracksInMediaSection, primaryFlow); details != nil {
+				details.repairSSRC = repairFlow
+			}
+		}
+
+		// A media section that declares RIDs is receiving Simulcast. The SSRCs (if any) are ignored
+		// and learned from the RTP Stream ID header extension of the incoming packets instead.
+		if rids := getRids(media); len(rids) != 0 {
+			incomingTracks = append(incomingTracks, trackDetails{mid: midValue, kind: codecType, label: trackLabel, id: trackID, rids: rids})
+		} else {
+			incomingTracks = append(incomingTracks, tracksInMediaSection...)
+		}
 	}
 
 	return incomingTracks
 }
 
+// getRids returns the RIDs the remote is sending in a media section. If an a=simulcast
+// attribute is present it determines the order, paused streams (prefixed with ~) are included.
+// https://tools.ietf.org/html/draft-ietf-mmusic-rid-15#section-4
+// https://tools.ietf.org/html/draft-ietf-mmusic-sdp-simulcast-14#section-5.1
+func getRids(media *sdp.MediaDescription) []string {
+	rids := []string{}
+	for _, attr := range media.Attributes {
+		if attr.Key != sdpAttributeRid {
+			continue
+		}
+
+		split := strings.Split(attr.Value, " ")
+		if len(split) >= 2 && split[1] == sdpSimulcastDirectionSend {
+			rids = append(rids, split[0])
+		}
+	}
+
+	simulcast, ok := media.Attribute(sdpAttributeSimulcast)
+	if !ok {
+		return rids
+	}
+
+	contains := func(values []string, value string) bool {
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+
+	orderedRids := []string{}
+	split := strings.Split(simulcast, " ")
+	for i := 0; i+1 < len(split); i += 2 {
+		if split[i] != sdpSimulcastDirectionSend {
+			continue
+		}
+
+		for _, stream := range strings.Split(split[i+1], ";") {
+			for _, rid := range strings.Split(stream, ",") {
+				rid = strings.TrimPrefix(rid, "~")
+				if contains(rids, rid) && !contains(orderedRids, rid) {
+					orderedRids = append(orderedRids, rid)
+				}
+			}
+		}
+	}
+
+	if len(orderedRids) == 0 {
+		return rids
+	}
+	return orderedRids
+}
+
+// extMapsFromSDP returns the RTP header extensions of a SessionDescription keyed by URI.
+// When BUNDLE is used an extension must use the same ID in every media section.
+func extMapsFromSDP(s *sdp.SessionDescription) map[string]sdp.ExtMap {
+	extMaps := map[string]sdp.ExtMap{}
+	addExtMaps(extMaps, s.Attributes)
+	for _, media := range s.MediaDescriptions {
+		addExtMaps(extMaps, media.Attributes)
+	}
+
+	return extMaps
+}
+
+// extMapsFromMediaDescription returns the RTP header extensions that apply to a single media section keyed by URI.
+func extMapsFromMediaDescription(s *sdp.SessionDescription, media *sdp.MediaDescription) map[string]sdp.ExtMap {
+	extMaps := map[string]sdp.ExtMap{}
+	addExtMaps(extMaps, s.Attributes)
+	addExtMaps(extMaps, media.Attributes)
+
+	return extMaps
+}
+
+func addExtMaps(extMaps map[string]sdp.ExtMap, attributes []sdp.Attribute) {
+	for _, attr := range attributes {
+		if attr.Key != sdp.AttrKeyExtMap {
+			continue
+		}
+
+		e := sdp.ExtMap{}
+		if err := e.Unmarshal(attr.Key + ":" + attr.Value); err != nil || e.URI == nil {
+			continue
+		}
+		if _, ok := extMaps[e.URI.String()]; !ok {
+			extMaps[e.URI.String()] = e
+		}
+	}
+}
+
+// extMapsForMediaSection returns the RTP header extensions to put in the media section of a RTPTransceiver.
+// Extensions the remote has chosen an ID for use it. When offering, the other extensions use the ID
+// from the MediaEngine, or the lowest ID the remote isn't already using. Simulcast always needs the
+// mid and RID extensions, and the repaired RID extension to receive its RTX streams
+func extMapsForMediaSection(mediaEngine *MediaEngine, t *RTPTransceiver, remoteExtMaps map[string]sdp.ExtMap, isSimulcast, isOffer bool) []sdp.ExtMap {
+	uris := mediaEngine.getHeaderExtensionURIs(t.kind, t.Direction())
+	if isSimulcast {
+		for _, simulcastURI := range []string{sdp.SDESMidURI, sdp.SDESRTPStreamIDURI, RepairedRTPStreamIDURI} {
+			found := false
+			for _, uri := range uris {
+				if uri == simulcastURI {
+					found = true
+					break
+				}
+			}
+			if !found {
+				uris = append(uris, simulcastURI)
+			}
+		}
+	}
+
+	usedIDs := map[int]bool{}
+	for _, e := range remoteExtMaps {
+		usedIDs[e.Value] = true
+	}
+
+	extMaps := []sdp.ExtMap{}
+	for _, uri := range uris {
+		if e, ok := remoteExtMaps[uri]; ok {
+			extMaps = append(extMaps, sdp.ExtMap{Value: e.Value, URI: e.URI})
+			continue
+		} else if !isOffer {
+			continue
+		}
+
+		id := mediaEngine.getHeaderExtensionID(uri)
+		if id == 0 || usedIDs[id] {
+			id = 0
+			for candidate := headerExtensionIDMin; candidate <= headerExtensionIDMax; candidate++ {
+				if !usedIDs[candidate] {
+					id = candidate
+					break
+				}
+			}
+		}
+
+		u, err := url.Parse(uri)
+		if id == 0 || err != nil {
+			continue
+		}
+		usedIDs[id] = true
+		extMaps = append(extMaps, sdp.ExtMap{Value: id, URI: u})
+	}
+	return extMaps
+}
+
+// findMediaDescription returns the media section with the given mid, or the first one of kind
+func findMediaDescription(s *sdp.SessionDescription, mid string, kind RTPCodecType) *sdp.MediaDescription {
+	var byKind *sdp.MediaDescription
+	for _, media := range s.MediaDescriptions {
+		if mid != "" && getMidValue(media) == mid {
+			return media
+		} else if byKind == nil && NewRTPCodecType(media.MediaName.Media) == kind {
+			byKind = media
+		}
+	}
+	return byKind
+}
+
+// rtxFromSDP returns true if the remote accepts RTX retransmissions of payloadType in the media section
+func rtxFromSDP(remote *sdp.SessionDescription, mid string, kind RTPCodecType, payloadType uint8) bool {
+	if remote == nil {
+		return false
+	}
+
+	remoteMedia := findMediaDescription(remote, mid, kind)
+	if remoteMedia == nil {
+		return false
+	}
+
+	for _, codec := range codecsFromMediaDescription(remote, remoteMedia) {
+		rtx := NewRTPCodec(kind, codec.Name, codec.ClockRate, 0, codec.Fmtp, codec.PayloadType, nil)
+		if apt, ok := rtx.associatedPayloadType(); ok && apt == payloadType {
+			return true
+		}
+	}
+	return false
+}
+
+// codecFromSDP returns true if the remote media section with the given mid accepts codec with its PayloadType
+func codecFromSDP(remote *sdp.SessionDescription, mid string, kind RTPCodecType, codec *RTPCodec) bool {
+	if remote == nil {
+		return false
+	}
+
+	remoteMedia := findMediaDescription(remote, mid, kind)
+	if remoteMedia == nil {
+		return false
+	}
+
+	for _, remoteCodec := range codecsFromMediaDescription(remote, remoteMedia) {
+		if remoteCodec.PayloadType == codec.PayloadType && codec.matchesSDP(remoteCodec) {
+			return true
+		}
+	}
+	return false
+}
+
+// codecsFromMediaDescription returns the codecs of the formats of a media section, in their order
+func codecsFromMediaDescription(s *sdp.SessionDescription, media *sdp.MediaDescription) []sdp.Codec {
+	codecs := []sdp.Codec{}
+	for _, format := range media.MediaName.Formats {
+		payloadType, err := strconv.ParseUint(format, 10, 8)
+		if err != nil {
+			continue
+		}
+
+		if codec, err := s.GetCodecForPayloadType(uint8(payloadType)); err == nil {
+			codecs = append(codecs, codec)
+		}
+	}
+	return codecs
+}
+
+// codecsForMediaSection returns the codecs to put in the media section of a RTPTransceiver, in the order
+// of its codec preferences. When answering only the codecs the remote offered in the media section are
+// accepted. RTX codecs are only kept with the codec they are associated with
+func codecsForMediaSection(mediaEngine *MediaEngine, t *RTPTransceiver, remoteCodecs []sdp.Codec, isOffer bool) []*RTPCodec {
+	codecs := t.getCodecs(mediaEngine)
+	if !isOffer {
+		accepted := []*RTPCodec{}
+		for _, codec := range codecs {
+			for _, remoteCodec := range remoteCodecs {
+				if codec.matchesSDP(remoteCodec) {
+					accepted = append(accepted, codec)
+					break
+				}
+			}
+		}
+		codecs = accepted
+	}
+
+	payloadTypes := map[uint8]bool{}
+	for _, codec := range codecs {
+		payloadTypes[codec.PayloadType] = true
+	}
+
+	filtered := []*RTPCodec{}
+	for _, codec := range codecs {
+		if strings.EqualFold(codec.Name, RTX) {
+			if apt, ok := codec.associatedPayloadType(); !ok || !payloadTypes[apt] {
+				continue
+			}
+		}
+		filtered = append(filtered, codec)
+	}
+	return filtered
+}
+
+// headerExtensionsFromSDP returns the RTP header extensions negotiated for the media section with the given
+// mid, these are the extensions in both descriptions using the IDs of the remote. If no section has the mid,
+// the first section of kind kind is used
+func headerExtensionsFromSDP(local, remote *sdp.SessionDescription, mid string, kind RTPCodecType) []RTPHeaderExtensionParameter {
+	headerExtensions := []RTPHeaderExtensionParameter{}
+	if local == nil || remote == nil {
+		return headerExtensions
+	}
+
+	localMedia, remoteMedia := findMediaDescription(local, mid, kind), findMediaDescription(remote, mid, kind)
+	if localMedia == nil || remoteMedia == nil {
+		return headerExtensions
+	}
+
+	localExtMaps := extMapsFromMediaDescription(local, localMedia)
+	for _, attr := range append(append([]sdp.Attribute{}, remote.Attributes...), remoteMedia.Attributes...) {
+		if attr.Key != sdp.AttrKeyExtMap {
+			continue
+		}
+
+		e := sdp.ExtMap{}
+		if err := e.Unmarshal(attr.Key + ":" + attr.Value); err != nil || e.URI == nil {
+			continue
+		}
+		if _, ok := localExtMaps[e.URI.String()]; ok {
+			headerExtensions = append(headerExtensions, RTPHeaderExtensionParameter{URI: e.URI.String(), ID: e.Value})
+		}
+	}
+	return headerExtensions
+}
+
+// isSendingSimulcast tells if the RTPTransceiver has a RTPSender sending encodings with RIDs
+func isSendingSimulcast(t *RTPTransceiver) bool {
+	return t.Sender() != nil && t.Sender().isSimulcast()
+}
+
 func addCandidatesToMediaDescriptions(candidates []ICECandidate, m *sdp.MediaDescription, iceGatheringState ICEGatheringState) {
 	appendCandidateIfNew := func(c sdp.ICECandidate, attributes []sdp.Attribute) {
 		marshaled := c.Marshal()
@@ -207,7 +555,8 @@ func populateLocalCandidates(sessionDescription *SessionDescription, i *ICEGathe
 	}
 }
 
-func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaEngine *MediaEngine, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, iceGatheringState ICEGatheringState, transceivers ...*RTPTransceiver) (bool, error) {
+func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaSection mediaSection, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, iceGatheringState ICEGatheringState) (bool, error) {
+	transceivers := mediaSection.transceivers
 	if len(transceivers) < 1 {
 		return false, fmt.Errorf("addTransceiverSDP() called with 0 transceivers")
 	}
@@ -215,20 +564,17 @@ func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaEngine *Med
 	t := transceivers[0]
 	media := sdp.NewJSEPMediaDescription(t.kind.String(), []string{}).
 		WithValueAttribute(sdp.AttrKeyConnectionSetup, dtlsRole.String()).
-		WithValueAttribute(sdp.AttrKeyMID, midValue).
+		WithValueAttribute(sdp.AttrKeyMID, mediaSection.id).
 		WithICECredentials(iceParams.UsernameFragment, iceParams.Password).
 		WithPropertyAttribute(sdp.AttrKeyRTCPMux).
 		WithPropertyAttribute(sdp.AttrKeyRTCPRsize)
 
-	codecs := mediaEngine.GetCodecsByKind(t.kind)
+	codecs := mediaSection.codecs
 	for _, codec := range codecs {
 		media.WithCodec(codec.PayloadType, codec.Name, codec.ClockRate, codec.Channels, codec.SDPFmtpLine)
 
 		for _, feedback := range codec.RTPCodecCapability.RTCPFeedback {
 			media.WithValueAttribute("rtcp-fb", fmt.Sprintf("%d %s %s", codec.PayloadType, feedback.Type, feedback.Parameter))
-			if feedback.Type == TypeRTCPFBTransportCC {
-				media.WithTransportCCExtMap()
-			}
 		}
 	}
 	if len(codecs) == 0 {
@@ -244,17 +590,54 @@ func addTransceiverSDP(d *sdp.SessionDescription, isPlanB bool, mediaEngine *Med
 		return false, nil
 	}
 
+	for _, e := range mediaSection.extMaps {
+		media.WithExtMap(e)
+	}
+
+	// Accept the Simulcast streams the remote is offering to send
+	simulcast := []string{}
+	if len(mediaSection.rids) > 0 && (t.Direction() == RTPTransceiverDirectionRecvonly || t.Direction() == RTPTransceiverDirectionSendrecv) {
+		for _, rid := range mediaSection.rids {
+			media.WithValueAttribute(sdpAttributeRid, rid+" "+sdpSimulcastDirectionRecv)
+		}
+		simulcast = append(simulcast, sdpSimulcastDirectionRecv+" "+strings.Join(mediaSection.rids, ";"))
+	}
+
 	for _, mt := range transceivers {
-		if mt.Sender() != nil && mt.Sender().track != nil {
-			track := mt.Sender().track
-			media = media.WithMediaSource(track.SSRC(), track.Label() /* cname */, track.Label() /* streamLabel */, track.ID())
+		if mt.Sender() != nil && mt.Sender().Track() != nil {
+			tracks := mt.Sender().Tracks()
+			for _, track := range tracks {
+				ssrc := mt.Sender().codingParameters(track).SSRC
+				media = media.WithMediaSource(ssrc, track.Label() /* cname */, track.Label() /* streamLabel */, track.ID())
+
+				// Announce the retransmission stream of the Track, the remote ignores it if it doesn't accept RTX
+				if rtxSSRC := mt.Sender().rtxSSRC(track); rtxSSRC != 0 {
+					media = media.WithValueAttribute(sdp.AttrKeySSRCGroup, fmt.Sprintf("%s %d %d", sdp.SemanticTokenFlowIdentification, ssrc, rtxSSRC))
+					media = media.WithMediaSource(rtxSSRC, track.Label() /* cname */, track.Label() /* streamLabel */, track.ID())
+				}
+			}
 			if !isPlanB {
+				track := tracks[0]
 				media = media.WithPropertyAttribute("msid:" + track.Label() + " " + track.ID())
+
+				// Offer the Simulcast streams we are sending
+				if track.RID() != "" && (mt.Direction() == RTPTransceiverDirectionSendonly || mt.Direction() == RTPTransceiverDirectionSendrecv) {
+					rids := []string{}
+					for _, track := range tracks {
+						rids = append(rids, track.RID())
+						media.WithValueAttribute(sdpAttributeRid, track.RID()+" "+sdpSimulcastDirectionSend)
+					}
+					simulcast = append([]string{sdpSimulcastDirectionSend + " " + strings.Join(rids, ";")}, simulcast...)
+				}
 				break
 			}
 		}
 	}
 
+	if len(simulcast) > 0 {
+		media.WithValueAttribute(sdpAttributeSimulcast, strings.Join(simulcast, " "))
+	}
+
 	media = media.WithPropertyAttribute(t.Direction().String())
 
 	addCandidatesToMediaDescriptions(candidates, media, iceGatheringState)
@@ -267,10 +650,13 @@ type mediaSection struct {
 	id           string
 	transceivers []*RTPTransceiver
 	data         bool
+	rids         []string
+	extMaps      []sdp.ExtMap
+	codecs       []*RTPCodec
 }
 
 // populateSDP serializes a PeerConnections state into an SDP
-func populateSDP(d *sdp.SessionDescription, isPlanB bool, isICELite bool, isTrickle bool, mediaEngine *MediaEngine, connectionRole sdp.ConnectionRole, candidates []ICECandidate, iceParams ICEParameters, mediaSections []mediaSection, iceGatheringState ICEGatheringState) (*sdp.SessionDescription, error) {
+func populateSDP(d *sdp.SessionDescription, isPlanB bool, isICELite bool, isTrickle bool, connectionRole sdp.ConnectionRole, candidates []ICECandidate, iceParams ICEParameters, mediaSections []mediaSection, iceGatheringState ICEGatheringState) (*sdp.SessionDescription, error) {
 	var err error
 
 	bundleValue := "BUNDLE"
@@ -290,7 +676,7 @@ func populateSDP(d *sdp.SessionDescription, isPlanB bool, isICELite bool, isTric
 		shouldAddID := true
 		if m.data {
 			addDataMediaSection(d, m.id, iceParams, candidates, connectionRole, iceGatheringState)
-		} else if shouldAddID, err = addTransceiverSDP(d, isPlanB, mediaEngine, m.id, iceParams, candidates, connectionRole, iceGatheringState, m.transceivers...); err != nil {
+		} else if shouldAddID, err = addTransceiverSDP(d, isPlanB, m, iceParams, candidates, connectionRole, iceGatheringState); err != nil {
 			return nil, err
 		}
 
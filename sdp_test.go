@@ -199,27 +199,27 @@ func TestTrackDetailsFromSDP(t *testing.T) {
 
 		tracks := trackDetailsFromSDP(nil, s)
 		assert.Equal(t, 3, len(tracks))
-		if _, ok := tracks[1000]; ok {
+		if trackDetail := trackDetailsForSSRC(tracks, 1000); trackDetail != nil {
 			assert.Fail(t, "got the unknown track ssrc:1000 which should have been skipped")
 		}
-		if track, ok := tracks[2000]; !ok {
+		if track := trackDetailsForSSRC(tracks, 2000); track == nil {
 			assert.Fail(t, "missing audio track with ssrc:2000")
 		} else {
 			assert.Equal(t, RTPCodecTypeAudio, track.kind)
 			assert.Equal(t, uint32(2000), track.ssrc)
 			assert.Equal(t, "audio_trk_label", track.label)
 		}
-		if track, ok := tracks[3000]; !ok {
+		if track := trackDetailsForSSRC(tracks, 3000); track == nil {
 			assert.Fail(t, "missing video track with ssrc:3000")
 		} else {
 			assert.Equal(t, RTPCodecTypeVideo, track.kind)
 			assert.Equal(t, uint32(3000), track.ssrc)
 			assert.Equal(t, "video_trk_label", track.label)
 		}
-		if _, ok := tracks[4000]; ok {
+		if trackDetail := trackDetailsForSSRC(tracks, 4000); trackDetail != nil {
 			assert.Fail(t, "got the rtx track ssrc:3000 which should have been skipped")
 		}
-		if track, ok := tracks[5000]; !ok {
+		if track := trackDetailsForSSRC(tracks, 5000); track == nil {
 			assert.Fail(t, "missing video track with ssrc:5000")
 		} else {
 			assert.Equal(t, RTPCodecTypeVideo, track.kind)
@@ -255,6 +255,62 @@ func TestTrackDetailsFromSDP(t *testing.T) {
 
 		assert.Equal(t, 0, len(trackDetailsFromSDP(nil, s)))
 	})
+
+	t.Run("Simulcast declared with RIDs", func(t *testing.T) {
+		s := &sdp.SessionDescription{
+			MediaDescriptions: []*sdp.MediaDescription{
+				{
+					MediaName: sdp.MediaName{
+						Media: "video",
+					},
+					Attributes: []sdp.Attribute{
+						{Key: "mid", Value: "0"},
+						{Key: "sendonly"},
+						{Key: "msid", Value: "video_stream_id video_trk_id"},
+						{Key: "rid", Value: "f send"},
+						{Key: "rid", Value: "h send"},
+						{Key: "rid", Value: "q send"},
+						{Key: "rid", Value: "x recv"},
+						{Key: "simulcast", Value: "send q;~h;f"},
+						{Key: "ssrc", Value: "5000"},
+					},
+				},
+			},
+		}
+
+		tracks := trackDetailsFromSDP(nil, s)
+		assert.Equal(t, 1, len(tracks))
+		assert.Equal(t, uint32(0), tracks[0].ssrc)
+		assert.Equal(t, []string{"q", "h", "f"}, tracks[0].rids)
+		assert.Equal(t, "video_trk_id", tracks[0].id)
+		assert.Equal(t, "video_stream_id", tracks[0].label)
+
+		assert.Equal(t, &tracks[0], trackDetailsForRID(tracks, "0", "h"))
+		assert.Nil(t, trackDetailsForRID(tracks, "0", "x"))
+		assert.Nil(t, trackDetailsForRID(tracks, "1", "h"))
+	})
+}
+
+func TestExtMapsFromSDP(t *testing.T) {
+	s := &sdp.SessionDescription{
+		MediaDescriptions: []*sdp.MediaDescription{
+			{
+				MediaName: sdp.MediaName{
+					Media: "video",
+				},
+				Attributes: []sdp.Attribute{
+					{Key: "extmap", Value: "3 " + sdp.SDESMidURI},
+					{Key: "extmap", Value: "10/sendonly " + sdp.SDESRTPStreamIDURI},
+					{Key: "extmap", Value: "invalid"},
+				},
+			},
+		},
+	}
+
+	extMaps := extMapsFromSDP(s)
+	assert.Equal(t, 2, len(extMaps))
+	assert.Equal(t, 3, extMaps[sdp.SDESMidURI].Value)
+	assert.Equal(t, 10, extMaps[sdp.SDESRTPStreamIDURI].Value)
 }
 
 func TestHaveApplicationMediaSection(t *testing.T) {
@@ -0,0 +1,80 @@
+// +build !js
+
+package webrtc
+
+import (
+	mathRand "math/rand"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+const (
+	// Average interval between two Sender or Receiver Reports, the actual interval is randomized
+	// between half and one and a half times this value as RFC 3550 section 6.2 recommends
+	rtcpReportIntervalVideo = time.Second
+	rtcpReportIntervalAudio = 5 * time.Second
+
+	// Seconds between the NTP epoch (1900) and the Unix epoch (1970)
+	ntpEpochOffset = 2208988800
+)
+
+// SenderReportStats describes the last RTCP Sender Report sent for an encoding of a RTPSender
+type SenderReportStats struct {
+	// SSRC of the encoding the report describes
+	SSRC uint32
+
+	// NTPTime is the wallclock time the report was sent at, in the NTP timestamp format
+	NTPTime uint64
+
+	// RTPTime is the RTP timestamp that corresponds to NTPTime
+	RTPTime uint32
+
+	// PacketCount and OctetCount are the number of packets and payload octets sent until the report
+	PacketCount uint32
+	OctetCount  uint32
+
+	// SentAt is when the report was sent
+	SentAt time.Time
+
+	// ReportsSent is the number of Sender Reports sent for the encoding
+	ReportsSent uint64
+}
+
+// ntpTime converts a time to the 64 bit NTP timestamp format of RFC 3550
+func ntpTime(t time.Time) uint64 {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := (uint64(t.Nanosecond()) << 32) / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+// ntpTimeToTime converts a 64 bit NTP timestamp to a time
+func ntpTimeToTime(ntp uint64) time.Time {
+	seconds := int64(ntp>>32) - ntpEpochOffset
+	nanoseconds := ((ntp & 0xFFFFFFFF) * uint64(time.Second)) >> 32
+	return time.Unix(seconds, int64(nanoseconds))
+}
+
+// roundTripTime computes the round trip time from the Sender Report echoed in a reception report,
+// as described in RFC 3550 section 6.4.1. It is false if the report doesn't echo a Sender Report
+func roundTripTime(report rtcp.ReceptionReport, now time.Time) (time.Duration, bool) {
+	if report.LastSenderReport == 0 {
+		return 0, false
+	}
+
+	// Both are in units of 1/65536 seconds
+	rtt := int32(uint32(ntpTime(now)>>16) - report.LastSenderReport - report.Delay)
+	if rtt < 0 {
+		rtt = 0
+	}
+	return time.Duration(rtt) * time.Second / 65536, true
+}
+
+// rtcpReportInterval returns the randomized delay before the next Sender or Receiver Report
+func rtcpReportInterval(kind RTPCodecType) time.Duration {
+	interval := rtcpReportIntervalVideo
+	if kind == RTPCodecTypeAudio {
+		interval = rtcpReportIntervalAudio
+	}
+	return time.Duration((0.5 + mathRand.Float64()) * float64(interval))
+}
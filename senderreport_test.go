@@ -0,0 +1,80 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNTPTime(t *testing.T) {
+	assert.Equal(t, uint64(ntpEpochOffset)<<32, ntpTime(time.Unix(0, 0)))
+	assert.Equal(t, uint64(ntpEpochOffset+1)<<32|1<<31, ntpTime(time.Unix(1, int64(500*time.Millisecond))))
+
+	now := time.Unix(1600000000, int64(250*time.Millisecond))
+	assert.Equal(t, now, ntpTimeToTime(ntpTime(now)))
+}
+
+func TestRoundTripTime(t *testing.T) {
+	now := time.Now()
+	sentAt := now.Add(-300 * time.Millisecond)
+
+	// The remote held the Sender Report for 100ms before echoing it
+	rtt, ok := roundTripTime(rtcp.ReceptionReport{
+		LastSenderReport: uint32(ntpTime(sentAt) >> 16),
+		Delay:            65536 / 10,
+	}, now)
+	assert.True(t, ok)
+	assert.InDelta(t, (200 * time.Millisecond).Seconds(), rtt.Seconds(), 0.001)
+
+	_, ok = roundTripTime(rtcp.ReceptionReport{}, now)
+	assert.False(t, ok, "no Sender Report was echoed")
+}
+
+func TestRTCPReportInterval(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		interval := rtcpReportInterval(RTPCodecTypeVideo)
+		assert.True(t, interval >= rtcpReportIntervalVideo/2 && interval < rtcpReportIntervalVideo*3/2)
+
+		interval = rtcpReportInterval(RTPCodecTypeAudio)
+		assert.True(t, interval >= rtcpReportIntervalAudio/2 && interval < rtcpReportIntervalAudio*3/2)
+	}
+}
+
+func TestRTPSender_SenderReports(t *testing.T) {
+	api := NewAPI()
+	track, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+
+	sender, err := api.NewRTPSender(track, &DTLSTransport{})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	assert.Empty(t, sender.senderReports(now), "no reports are sent before packets are")
+
+	encoding := sender.trackEncodings[0]
+	encoding.packetCount, encoding.octetCount = 10, 1000
+	encoding.lastRTPTimestamp, encoding.lastPacketTime = 90000, now.Add(-time.Second)
+
+	pkts := sender.senderReports(now)
+	assert.Equal(t, []rtcp.Packet{
+		&rtcp.SenderReport{SSRC: 1234, NTPTime: ntpTime(now), RTPTime: 180000, PacketCount: 10, OctetCount: 1000},
+		&rtcp.SourceDescription{Chunks: []rtcp.SourceDescriptionChunk{{
+			Source: 1234,
+			Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: "pion"}},
+		}}},
+	}, pkts)
+
+	assert.Equal(t, []SenderReportStats{{
+		SSRC:        1234,
+		NTPTime:     ntpTime(now),
+		RTPTime:     180000,
+		PacketCount: 10,
+		OctetCount:  1000,
+		SentAt:      now,
+		ReportsSent: 1,
+	}}, sender.SenderReportStats())
+}
@@ -48,10 +48,18 @@ type SettingEngine struct {
 		SRTP  *uint
 		SRTCP *uint
 	}
+	pacing struct {
+		Enabled  bool
+		Rate     uint64
+		Factor   float64
+		Priority RTPCodecType
+	}
 	answeringDTLSRole                         DTLSRole
 	disableCertificateFingerprintVerification bool
 	disableSRTPReplayProtection               bool
 	disableSRTCPReplayProtection              bool
+	twccFeedbackInterval                      time.Duration
+	disableReceiverReports                    bool
 	vnet                                      *vnet.Net
 	LoggerFactory                             logging.LoggerFactory
 }
@@ -239,3 +247,35 @@ func (e *SettingEngine) DisableSRTPReplayProtection(isDisabled bool) {
 func (e *SettingEngine) DisableSRTCPReplayProtection(isDisabled bool) {
 	e.disableSRTCPReplayProtection = isDisabled
 }
+
+// SetTWCCFeedbackInterval sets how often transport-cc feedback is sent for the packets
+// received with the transport-wide sequence number header extension. The default is 100ms.
+func (e *SettingEngine) SetTWCCFeedbackInterval(interval time.Duration) {
+	e.twccFeedbackInterval = interval
+}
+
+// SetPacing enables the pacer shared by the RTPSenders of each PeerConnection, which spreads the
+// packets of large frames over time instead of sending them as a burst. Packets are sent at
+// factor times the target bitrate of the BandwidthEstimator, 0 uses the default factor of 2.5.
+func (e *SettingEngine) SetPacing(factor float64) {
+	e.pacing.Enabled = true
+	e.pacing.Factor = factor
+}
+
+// SetPacingRate enables the pacer with a fixed pacing rate in bits per second, the target
+// bitrate of the BandwidthEstimator is then ignored.
+func (e *SettingEngine) SetPacingRate(bitrate uint64) {
+	e.pacing.Enabled = true
+	e.pacing.Rate = bitrate
+}
+
+// SetPacingPriority sets the kind of the packets the pacer sends first. Audio is sent first by default.
+func (e *SettingEngine) SetPacingPriority(kind RTPCodecType) {
+	e.pacing.Priority = kind
+}
+
+// DisableReceiverReports disables the RTCP Receiver Reports RTPReceivers send periodically
+// for the streams they receive. Applications can then send their own.
+func (e *SettingEngine) DisableReceiverReports(isDisabled bool) {
+	e.disableReceiverReports = isDisabled
+}
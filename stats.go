@@ -2,6 +2,7 @@ package webrtc
 
 import (
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -1195,7 +1196,7 @@ func toStatsICECandidatePairState(state ice.CandidatePairState) (StatsICECandida
 		err := fmt.Errorf(
 			"cannot convert to StatsICECandidatePairStateSucceeded invalid ice candidate state: %s",
 			state.String())
-		return StatsICECandidatePairState(Unknown), err
+		return StatsICECandidatePairState(unknownStr), err
 	}
 }
 
@@ -1452,3 +1453,79 @@ type CertificateStats struct {
 	// (i.e. a self-signed certificate), this will not be set.
 	IssuerCertificateID string `json:"issuerCertificateId"`
 }
+
+// RTPStreamRates are the rates of an inbound or outbound RTP stream between two StatsReports.
+type RTPStreamRates struct {
+	// SSRC of the stream
+	SSRC uint32
+
+	// Type is either StatsTypeInboundRTP or StatsTypeOutboundRTP
+	Type StatsType
+
+	// Kind is either "audio" or "video"
+	Kind string
+
+	// Bitrate of the payloads received or sent, in bits per second
+	Bitrate float64
+
+	// PacketRate is the number of packets received or sent per second
+	PacketRate float64
+
+	// PacketLossPercentage is the percentage of the packets of the interval that were lost. For
+	// outbound streams it is known once the remote reported the losses in a Receiver Report.
+	PacketLossPercentage float64
+}
+
+// Rates computes the rates of the inbound and outbound RTP streams of r since previous, a
+// StatsReport of the same PeerConnection, RTPSender or RTPReceiver taken earlier. The rates
+// are keyed by the stats ID of their stream, streams missing from previous are skipped.
+func (r StatsReport) Rates(previous StatsReport) map[string]RTPStreamRates {
+	rates := map[string]RTPStreamRates{}
+	for id, stats := range r {
+		switch current := stats.(type) {
+		case InboundRTPStreamStats:
+			prior, ok := previous[id].(InboundRTPStreamStats)
+			if !ok {
+				continue
+			}
+
+			received := float64(current.PacketsReceived) - float64(prior.PacketsReceived)
+			lost := float64(current.PacketsLost) - float64(prior.PacketsLost)
+			rates[id] = statsRates(current.SSRC, current.Type, current.Kind, current.Timestamp-prior.Timestamp,
+				float64(current.BytesReceived)-float64(prior.BytesReceived), received, lost, received+lost)
+		case OutboundRTPStreamStats:
+			prior, ok := previous[id].(OutboundRTPStreamStats)
+			if !ok {
+				continue
+			}
+
+			// The losses of an outbound stream are the ones the remote reported. Until both reports
+			// describe the stream the losses of the interval are unknown, and count as none.
+			lost := float64(0)
+			currentRemote, currentOK := r[current.RemoteID].(RemoteInboundRTPStreamStats)
+			priorRemote, priorOK := previous[prior.RemoteID].(RemoteInboundRTPStreamStats)
+			if currentOK && priorOK {
+				lost = float64(currentRemote.PacketsLost) - float64(priorRemote.PacketsLost)
+			}
+
+			sent := float64(current.PacketsSent) - float64(prior.PacketsSent)
+			rates[id] = statsRates(current.SSRC, current.Type, current.Kind, current.Timestamp-prior.Timestamp,
+				float64(current.BytesSent)-float64(prior.BytesSent), sent, lost, sent)
+		}
+	}
+	return rates
+}
+
+// statsRates computes the rates of the bytes and packets of a stream during an interval in
+// milliseconds. Duplicated packets can make lost negative, these count as no loss.
+func statsRates(ssrc uint32, statsType StatsType, kind string, interval StatsTimestamp, bytes, packets, lost, expected float64) RTPStreamRates {
+	rates := RTPStreamRates{SSRC: ssrc, Type: statsType, Kind: kind}
+	if seconds := float64(interval) / 1000; seconds > 0 {
+		rates.Bitrate = bytes * 8 / seconds
+		rates.PacketRate = packets / seconds
+	}
+	if lost > 0 && expected > 0 {
+		rates.PacketLossPercentage = math.Min(lost/expected*100, 100)
+	}
+	return rates
+}
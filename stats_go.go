@@ -2,6 +2,8 @@
 
 package webrtc
 
+import "fmt"
+
 // GetConnectionStats is a helper method to return the associated stats for a given PeerConnection
 func (r StatsReport) GetConnectionStats(conn *PeerConnection) (PeerConnectionStats, bool) {
 	statsID := conn.getStatsID()
@@ -61,3 +63,23 @@ func (r StatsReport) GetICECandidatePairStats(c *ICECandidatePair) (ICECandidate
 	}
 	return candidateStats, true
 }
+
+// collectCodecStats collects the stats of a codec sent or received by a RTP stream, and returns their ID
+func collectCodecStats(collector *statsReportCollector, codec *RTPCodec, codecType CodecType) string {
+	stats := CodecStats{
+		Timestamp:   statsTimestampNow(),
+		Type:        StatsTypeCodec,
+		ID:          fmt.Sprintf("Codec-%s-%d", codecType, codec.PayloadType),
+		PayloadType: uint32(codec.PayloadType),
+		CodecType:   codecType,
+		TransportID: "iceTransport",
+		MimeType:    codec.mimeType(),
+		ClockRate:   codec.ClockRate,
+		Channels:    uint32(codec.Channels),
+		SDPFmtpLine: codec.SDPFmtpLine,
+	}
+
+	collector.Collecting()
+	collector.Collect(stats.ID, stats)
+	return stats.ID
+}
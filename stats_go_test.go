@@ -5,10 +5,14 @@ package webrtc
 import (
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/pion/rtcp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v2/pkg/media"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -307,3 +311,188 @@ func TestPeerConnection_GetStats_Closed(t *testing.T) {
 
 	pc.GetStats()
 }
+
+func TestPeerConnection_GetStats_RTPStreams(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// The reception reports are read with the RTCP of the RTPSender
+	go func() {
+		for {
+			if _, readErr := sender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	// The Sender Reports are read with the RTCP of the RTPReceiver
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		go func() {
+			for {
+				if _, readErr := r.ReadRTCP(); readErr != nil {
+					return
+				}
+			}
+		}()
+
+		assert.NoError(t, pcAnswer.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: remoteTrack.SSRC()}}))
+		for {
+			if _, readErr := remoteTrack.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	var offerReport, answerReport StatsReport
+	for {
+		assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+		time.Sleep(20 * time.Millisecond)
+
+		offerReport, answerReport = pcOffer.GetStats(), pcAnswer.GetStats()
+		_, haveRemoteInbound := offerReport[fmt.Sprintf("RemoteInboundRTP-%d", track.SSRC())]
+		_, haveRemoteOutbound := answerReport[fmt.Sprintf("RemoteOutboundRTP-%d", track.SSRC())]
+		outbound, haveOutbound := offerReport[fmt.Sprintf("OutboundRTP-%d", track.SSRC())].(OutboundRTPStreamStats)
+		if haveRemoteInbound && haveRemoteOutbound && haveOutbound && outbound.PLICount != 0 {
+			break
+		}
+	}
+
+	outbound := offerReport[fmt.Sprintf("OutboundRTP-%d", track.SSRC())].(OutboundRTPStreamStats)
+	assert.Equal(t, StatsTypeOutboundRTP, outbound.Type)
+	assert.Equal(t, "video", outbound.Kind)
+	assert.Equal(t, uint32(1), outbound.PLICount)
+	assert.NotZero(t, outbound.PacketsSent)
+	assert.Equal(t, uint64(outbound.PacketsSent)*2, outbound.BytesSent, "every payload is the VP8 descriptor and a byte")
+
+	codec := offerReport[outbound.CodecID].(CodecStats)
+	assert.Equal(t, CodecTypeEncode, codec.CodecType)
+	assert.Equal(t, "video/VP8", codec.MimeType)
+	assert.Equal(t, uint32(90000), codec.ClockRate)
+
+	remoteInbound := offerReport[outbound.RemoteID].(RemoteInboundRTPStreamStats)
+	assert.Equal(t, track.SSRC(), remoteInbound.SSRC)
+	assert.Equal(t, outbound.ID, remoteInbound.LocalID)
+	assert.Equal(t, int32(0), remoteInbound.PacketsLost)
+
+	inbound := answerReport[fmt.Sprintf("InboundRTP-%d", track.SSRC())].(InboundRTPStreamStats)
+	assert.Equal(t, StatsTypeInboundRTP, inbound.Type)
+	assert.Equal(t, uint32(1), inbound.PLICount)
+	assert.NotZero(t, inbound.PacketsReceived)
+	assert.Equal(t, uint64(inbound.PacketsReceived)*2, inbound.BytesReceived)
+	assert.Equal(t, CodecTypeDecode, answerReport[inbound.CodecID].(CodecStats).CodecType)
+
+	remoteOutbound := answerReport[inbound.RemoteID].(RemoteOutboundRTPStreamStats)
+	assert.Equal(t, inbound.ID, remoteOutbound.LocalID)
+	assert.NotZero(t, remoteOutbound.PacketsSent)
+
+	// The RTPSender and RTPReceiver only report their streams and what these refer to
+	senderReport := sender.GetStats()
+	assert.Len(t, senderReport, 4)
+	for _, id := range []string{outbound.ID, outbound.CodecID, outbound.RemoteID, outbound.TransportID} {
+		assert.Contains(t, senderReport, id)
+	}
+
+	receiverReport := pcAnswer.GetReceivers()[0].GetStats()
+	assert.Len(t, receiverReport, 4)
+	for _, id := range []string{inbound.ID, inbound.CodecID, inbound.RemoteID, inbound.TransportID} {
+		assert.Contains(t, receiverReport, id)
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// The Sender Reports are handled by the RTPReceiver even if the application doesn't read its RTCP
+func TestPeerConnection_GetStats_RemoteOutboundUnread(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, rand.Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	pcAnswer.OnTrack(func(remoteTrack *Track, r *RTPReceiver) {
+		for {
+			if _, readErr := remoteTrack.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	for {
+		assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+		time.Sleep(20 * time.Millisecond)
+
+		if remoteOutbound, ok := pcAnswer.GetStats()[fmt.Sprintf("RemoteOutboundRTP-%d", track.SSRC())].(RemoteOutboundRTPStreamStats); ok {
+			assert.NotZero(t, remoteOutbound.PacketsSent)
+			assert.NotZero(t, remoteOutbound.BytesSent)
+			break
+		}
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestStatsReport_Rates(t *testing.T) {
+	previous := StatsReport{
+		"InboundRTP-1": InboundRTPStreamStats{
+			Timestamp: 1000, Type: StatsTypeInboundRTP, ID: "InboundRTP-1", SSRC: 1, Kind: "video",
+			PacketsReceived: 100, PacketsLost: 10, BytesReceived: 100000,
+		},
+		"OutboundRTP-2": OutboundRTPStreamStats{
+			Timestamp: 1000, Type: StatsTypeOutboundRTP, ID: "OutboundRTP-2", SSRC: 2, Kind: "audio",
+			PacketsSent: 50, BytesSent: 5000, RemoteID: "RemoteInboundRTP-2",
+		},
+		"RemoteInboundRTP-2": RemoteInboundRTPStreamStats{ID: "RemoteInboundRTP-2", PacketsLost: 1},
+	}
+	current := StatsReport{
+		"InboundRTP-1": InboundRTPStreamStats{
+			Timestamp: 3000, Type: StatsTypeInboundRTP, ID: "InboundRTP-1", SSRC: 1, Kind: "video",
+			PacketsReceived: 290, PacketsLost: 20, BytesReceived: 350000,
+		},
+		"OutboundRTP-2": OutboundRTPStreamStats{
+			Timestamp: 3000, Type: StatsTypeOutboundRTP, ID: "OutboundRTP-2", SSRC: 2, Kind: "audio",
+			PacketsSent: 150, BytesSent: 15000, RemoteID: "RemoteInboundRTP-2",
+		},
+		"RemoteInboundRTP-2": RemoteInboundRTPStreamStats{ID: "RemoteInboundRTP-2", PacketsLost: 3},
+		"InboundRTP-3":       InboundRTPStreamStats{Timestamp: 3000, ID: "InboundRTP-3", SSRC: 3, PacketsReceived: 10},
+	}
+
+	assert.Equal(t, map[string]RTPStreamRates{
+		"InboundRTP-1": {
+			SSRC: 1, Type: StatsTypeInboundRTP, Kind: "video",
+			Bitrate: 1000000, PacketRate: 95, PacketLossPercentage: 5,
+		},
+		"OutboundRTP-2": {
+			SSRC: 2, Type: StatsTypeOutboundRTP, Kind: "audio",
+			Bitrate: 40000, PacketRate: 50, PacketLossPercentage: 2,
+		},
+	}, current.Rates(previous))
+
+	// Duplicated packets don't count as negative losses
+	duplicated := StatsReport{
+		"InboundRTP-1": InboundRTPStreamStats{Timestamp: 2000, PacketsReceived: 120, PacketsLost: 5},
+	}
+	assert.Equal(t, float64(0), duplicated.Rates(previous)["InboundRTP-1"].PacketLossPercentage)
+
+	// The losses the remote reported before the previous report aren't losses of the interval
+	delete(previous, "RemoteInboundRTP-2")
+	assert.Equal(t, float64(0), current.Rates(previous)["OutboundRTP-2"].PacketLossPercentage)
+}
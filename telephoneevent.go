@@ -0,0 +1,95 @@
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"sync"
+)
+
+const (
+	// RFC 4733 payload: event (8 bits), end (1 bit), reserved (1 bit), volume (6 bits), duration (16 bits)
+	telephoneEventLength     = 4
+	telephoneEventEndMask    = 0x80
+	telephoneEventVolumeMask = 0x3F
+
+	// DTMF tones by event code, the other events aren't DTMF
+	dtmfEvents = "0123456789*#ABCD"
+)
+
+var errShortTelephoneEvent = errors.New("telephone-event payload is too short")
+
+// telephoneEvent is the payload of a RFC 4733 telephone-event packet
+type telephoneEvent struct {
+	event    uint8
+	end      bool
+	volume   uint8
+	duration uint16
+}
+
+func (e telephoneEvent) marshal() []byte {
+	b := make([]byte, telephoneEventLength)
+	b[0] = e.event
+	b[1] = e.volume & telephoneEventVolumeMask
+	if e.end {
+		b[1] |= telephoneEventEndMask
+	}
+	b[2] = byte(e.duration >> 8)
+	b[3] = byte(e.duration)
+	return b
+}
+
+func (e *telephoneEvent) unmarshal(b []byte) error {
+	if len(b) < telephoneEventLength {
+		return errShortTelephoneEvent
+	}
+
+	e.event = b[0]
+	e.end = b[1]&telephoneEventEndMask != 0
+	e.volume = b[1] & telephoneEventVolumeMask
+	e.duration = uint16(b[2])<<8 | uint16(b[3])
+	return nil
+}
+
+// telephoneEventDecoder turns the telephone-event packets of a stream into DTMF tone changes.
+// All the packets of an event have its RTP timestamp, the last ones have the end bit
+type telephoneEventDecoder struct {
+	mu sync.Mutex
+
+	started   bool
+	ended     bool
+	timestamp uint32
+}
+
+// decode returns the tone changes caused by a packet: the tone when an event starts and an
+// empty string when it ends. Retransmitted end packets and packets of past events are ignored
+func (d *telephoneEventDecoder) decode(timestamp uint32, payload []byte) []string {
+	e := telephoneEvent{}
+	if e.unmarshal(payload) != nil || int(e.event) >= len(dtmfEvents) {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	changes := []string{}
+	if !d.started || timestamp != d.timestamp {
+		if d.started && int32(timestamp-d.timestamp) < 0 {
+			return nil
+		}
+
+		// The end packets of the previous event were lost
+		if d.started && !d.ended {
+			changes = append(changes, "")
+		}
+
+		d.started, d.ended, d.timestamp = true, false, timestamp
+		changes = append(changes, dtmfEvents[e.event:e.event+1])
+	}
+
+	if e.end && !d.ended {
+		d.ended = true
+		changes = append(changes, "")
+	}
+	return changes
+}
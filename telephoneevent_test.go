@@ -0,0 +1,49 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelephoneEvent_MarshalUnmarshal(t *testing.T) {
+	e := telephoneEvent{event: 11, end: true, volume: 10, duration: 800}
+	b := e.marshal()
+	assert.Equal(t, []byte{0x0b, 0x8a, 0x03, 0x20}, b)
+
+	decoded := telephoneEvent{}
+	assert.NoError(t, decoded.unmarshal(b))
+	assert.Equal(t, e, decoded)
+
+	assert.Equal(t, errShortTelephoneEvent, decoded.unmarshal(b[:3]))
+}
+
+func TestTelephoneEventDecoder(t *testing.T) {
+	payload := func(event uint8, end bool) []byte {
+		return telephoneEvent{event: event, end: end, volume: 10, duration: 160}.marshal()
+	}
+
+	d := telephoneEventDecoder{}
+	assert.Equal(t, []string{"1"}, d.decode(1000, payload(1, false)))
+	assert.Equal(t, []string{}, d.decode(1000, payload(1, false)))
+	assert.Equal(t, []string{""}, d.decode(1000, payload(1, true)))
+
+	// Retransmitted end packets
+	assert.Equal(t, []string{}, d.decode(1000, payload(1, true)))
+
+	// Packets of past events
+	assert.Nil(t, d.decode(500, payload(2, false)))
+
+	// The end packets of # are lost
+	assert.Equal(t, []string{"#"}, d.decode(2000, payload(11, false)))
+	assert.Equal(t, []string{"", "A"}, d.decode(3000, payload(12, false)))
+
+	// A single end packet
+	assert.Equal(t, []string{"", "*", ""}, d.decode(4000, payload(10, true)))
+
+	// Events that aren't DTMF and short payloads
+	assert.Nil(t, d.decode(5000, payload(16, false)))
+	assert.Nil(t, d.decode(5000, []byte{0x01}))
+}
@@ -26,6 +26,7 @@ type Track struct {
 	kind        RTPCodecType
 	label       string
 	ssrc        uint32
+	rid         string
 	codec       *RTPCodec
 
 	packetizer rtp.Packetizer
@@ -70,6 +71,15 @@ func (t *Track) SSRC() uint32 {
 	return t.ssrc
 }
 
+// RID gets the RTP Stream ID of this Track
+// With Simulcast you will have multiple tracks with the same ID, but different RID values.
+// In many cases a Track will not have an RID, so it is important to assert it is non-zero
+func (t *Track) RID() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.rid
+}
+
 // Codec gets the Codec of the track
 func (t *Track) Codec() *RTPCodec {
 	t.mu.RLock()
@@ -77,6 +87,19 @@ func (t *Track) Codec() *RTPCodec {
 	return t.codec
 }
 
+// NACKGeneratorStats returns the loss counters of a remote Track. These are only
+// counted if the codec of the Track was registered with the nack RTCPFeedback
+func (t *Track) NACKGeneratorStats() NACKGeneratorStats {
+	t.mu.RLock()
+	r := t.receiver
+	t.mu.RUnlock()
+
+	if r == nil {
+		return NACKGeneratorStats{}
+	}
+	return r.nackGeneratorStats(t)
+}
+
 // Packetizer gets the Packetizer of the track
 func (t *Track) Packetizer() rtp.Packetizer {
 	t.mu.RLock()
@@ -94,7 +117,7 @@ func (t *Track) Read(b []byte) (n int, err error) {
 	r := t.receiver
 	t.mu.RUnlock()
 
-	return r.readRTP(b)
+	return r.readRTP(b, t)
 }
 
 // ReadRTP is a convenience method that wraps Read and unmarshals for you
@@ -130,6 +153,10 @@ func (t *Track) Write(b []byte) (n int, err error) {
 
 // WriteSample packetizes and writes to the track
 func (t *Track) WriteSample(s media.Sample) error {
+	if t.packetizer == nil {
+		return ErrNoPayloader
+	}
+
 	packets := t.packetizer.Packetize(s.Data, s.Samples)
 	for _, p := range packets {
 		err := t.WriteRTP(p)
@@ -157,7 +184,7 @@ func (t *Track) WriteRTP(p *rtp.Packet) error {
 	}
 
 	for _, s := range senders {
-		_, err := s.SendRTP(&p.Header, p.Payload)
+		_, err := s.writeRTP(t, &p.Header, p.Payload)
 		if err != nil {
 			return err
 		}
@@ -168,18 +195,28 @@ func (t *Track) WriteRTP(p *rtp.Packet) error {
 
 // NewTrack initializes a new *Track
 func NewTrack(payloadType uint8, ssrc uint32, id, label string, codec *RTPCodec) (*Track, error) {
+	return NewTrackWithRID(payloadType, ssrc, id, label, "", codec)
+}
+
+// NewTrackWithRID initializes a new *Track that is sent as the Simulcast encoding identified by rid.
+// Tracks that are encodings of the same source should share the id and label
+func NewTrackWithRID(payloadType uint8, ssrc uint32, id, label, rid string, codec *RTPCodec) (*Track, error) {
 	if ssrc == 0 {
 		return nil, fmt.Errorf("SSRC supplied to NewTrack() must be non-zero")
 	}
 
-	packetizer := rtp.NewPacketizer(
-		rtpOutboundMTU,
-		payloadType,
-		ssrc,
-		codec.Payloader,
-		rtp.NewRandomSequencer(),
-		codec.ClockRate,
-	)
+	// Tracks of codecs without Payloader only accept RTP packets
+	var packetizer rtp.Packetizer
+	if codec.Payloader != nil {
+		packetizer = rtp.NewPacketizer(
+			rtpOutboundMTU,
+			payloadType,
+			ssrc,
+			codec.Payloader,
+			rtp.NewRandomSequencer(),
+			codec.ClockRate,
+		)
+	}
 
 	return &Track{
 		id:          id,
@@ -187,6 +224,7 @@ func NewTrack(payloadType uint8, ssrc uint32, id, label string, codec *RTPCodec)
 		kind:        codec.Type,
 		label:       label,
 		ssrc:        ssrc,
+		rid:         rid,
 		codec:       codec,
 		packetizer:  packetizer,
 	}, nil
@@ -200,6 +238,13 @@ func (t *Track) determinePayloadType() error {
 		return err
 	}
 
+	// DTMF tones are sent with the PayloadType of telephone-event in the stream of the audio codec
+	for t.receiver != nil && t.receiver.api.mediaEngine.isTelephoneEvent(r.PayloadType) {
+		if r, err = t.ReadRTP(); err != nil {
+			return err
+		}
+	}
+
 	t.mu.Lock()
 	t.payloadType = r.PayloadType
 	defer t.mu.Unlock()
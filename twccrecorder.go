@@ -0,0 +1,256 @@
+// +build !js
+
+package webrtc
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+const (
+	// How often transport-cc feedback is sent when the SettingEngine doesn't configure it
+	defaultTWCCFeedbackInterval = 100 * time.Millisecond
+	// Receive deltas are expressed in multiples of 250us
+	twccDeltaTick = rtcp.TypeTCCDeltaScaleFactor * time.Microsecond
+	// The reference time is expressed in multiples of 64ms
+	twccTicksPerReferenceTime = 256
+	// Maximum number of packets described by a single feedback packet, this keeps it below the MTU
+	twccMaxStatusCount = 400
+	// Number of 2 bit symbols in a status vector chunk
+	twccSymbolsPerChunk = 7
+	// Size of the RTCP header and the fixed fields of a feedback packet
+	twccFixedLength = 20
+)
+
+type twccArrival struct {
+	sequenceNumber int64
+	ticks          int64
+}
+
+// twccRecorder records the arrival time of every packet that carries a transport wide sequence
+// number, and periodically sends the transport-cc feedback the remote uses to estimate the bandwidth.
+// It is shared by all the RTPReceivers of a DTLSTransport.
+type twccRecorder struct {
+	mu sync.Mutex
+
+	interval  time.Duration
+	writeRTCP func([]rtcp.Packet) error
+
+	startTime  time.Time
+	mediaSSRC  uint32
+	fbPktCount uint8
+	arrivals   []twccArrival
+
+	// Sequence numbers are unwrapped to count cycles, packets before nextSequenceNumber were already reported
+	haveSequenceNumber bool
+	lastSequenceNumber int64
+	nextSequenceNumber int64
+
+	started   bool
+	closed    chan interface{}
+	closeOnce sync.Once
+}
+
+func newTWCCRecorder(interval time.Duration, writeRTCP func([]rtcp.Packet) error) *twccRecorder {
+	if interval <= 0 {
+		interval = defaultTWCCFeedbackInterval
+	}
+
+	return &twccRecorder{
+		interval:  interval,
+		writeRTCP: writeRTCP,
+		startTime: time.Now(),
+		closed:    make(chan interface{}),
+	}
+}
+
+// record stores the arrival of a packet, feedback is sent from the first recorded packet on
+func (r *twccRecorder) record(mediaSSRC uint32, sequenceNumber uint16, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	unwrapped := int64(sequenceNumber)
+	if r.haveSequenceNumber {
+		unwrapped = r.lastSequenceNumber + int64(int16(sequenceNumber-uint16(r.lastSequenceNumber)))
+	} else {
+		r.haveSequenceNumber = true
+		r.lastSequenceNumber = unwrapped
+		r.nextSequenceNumber = unwrapped
+	}
+	if unwrapped > r.lastSequenceNumber {
+		r.lastSequenceNumber = unwrapped
+	}
+
+	// Packets that arrive after their range was reported were already reported as lost
+	if unwrapped < r.nextSequenceNumber {
+		return
+	}
+
+	r.mediaSSRC = mediaSSRC
+	r.arrivals = append(r.arrivals, twccArrival{
+		sequenceNumber: unwrapped,
+		ticks:          int64(now.Sub(r.startTime) / twccDeltaTick),
+	})
+
+	if !r.started {
+		r.started = true
+		go r.run()
+	}
+}
+
+func (r *twccRecorder) run() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.closed:
+			return
+		case <-ticker.C:
+			pkts := r.buildFeedback()
+			if len(pkts) == 0 {
+				continue
+			}
+
+			// Errors are ignored, the next feedback describes the packets that arrive after these
+			_ = r.writeRTCP(pkts)
+		}
+	}
+}
+
+func (r *twccRecorder) close() {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+	})
+}
+
+// buildFeedback returns the feedback packets that describe every packet since the last feedback
+func (r *twccRecorder) buildFeedback() []rtcp.Packet {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.arrivals) == 0 {
+		return nil
+	}
+
+	sort.Slice(r.arrivals, func(i, j int) bool {
+		return r.arrivals[i].sequenceNumber < r.arrivals[j].sequenceNumber
+	})
+
+	pkts := []rtcp.Packet{}
+	var feedback *twccFeedback
+	for _, arrival := range r.arrivals {
+		if feedback != nil && arrival.sequenceNumber < feedback.nextSequenceNumber {
+			continue // Duplicate
+		}
+
+		if feedback == nil || !feedback.add(arrival) {
+			if feedback != nil {
+				pkts = append(pkts, feedback.packet())
+			}
+
+			feedback = newTWCCFeedback(r.mediaSSRC, r.fbPktCount, arrival)
+			feedback.add(arrival)
+			r.fbPktCount++
+		}
+	}
+	pkts = append(pkts, feedback.packet())
+
+	r.nextSequenceNumber = feedback.nextSequenceNumber
+	r.arrivals = r.arrivals[:0]
+	return pkts
+}
+
+// twccFeedback builds a single transport-cc feedback packet
+type twccFeedback struct {
+	mediaSSRC  uint32
+	fbPktCount uint8
+
+	baseSequenceNumber int64
+	nextSequenceNumber int64
+	referenceTime      int64
+	lastTicks          int64
+
+	symbols    []uint16
+	deltas     []*rtcp.RecvDelta
+	deltaBytes int
+}
+
+func newTWCCFeedback(mediaSSRC uint32, fbPktCount uint8, first twccArrival) *twccFeedback {
+	referenceTime := first.ticks / twccTicksPerReferenceTime
+	return &twccFeedback{
+		mediaSSRC:          mediaSSRC,
+		fbPktCount:         fbPktCount,
+		baseSequenceNumber: first.sequenceNumber,
+		nextSequenceNumber: first.sequenceNumber,
+		referenceTime:      referenceTime,
+		lastTicks:          referenceTime * twccTicksPerReferenceTime,
+	}
+}
+
+// add describes the arrival and the packets missing before it, it returns false if
+// the arrival doesn't fit and must go in the next feedback packet
+func (f *twccFeedback) add(arrival twccArrival) bool {
+	missing := arrival.sequenceNumber - f.nextSequenceNumber
+	if int64(len(f.symbols))+missing+1 > twccMaxStatusCount {
+		return false
+	}
+
+	delta := arrival.ticks - f.lastTicks
+	if delta < math.MinInt16 || delta > math.MaxInt16 {
+		return false
+	}
+
+	for i := int64(0); i < missing; i++ {
+		f.symbols = append(f.symbols, rtcp.TypeTCCPacketNotReceived)
+	}
+
+	symbol := uint16(rtcp.TypeTCCPacketReceivedSmallDelta)
+	f.deltaBytes++
+	if delta < 0 || delta > math.MaxUint8 {
+		symbol = rtcp.TypeTCCPacketReceivedLargeDelta
+		f.deltaBytes++
+	}
+	f.symbols = append(f.symbols, symbol)
+	f.deltas = append(f.deltas, &rtcp.RecvDelta{Type: symbol, Delta: delta * rtcp.TypeTCCDeltaScaleFactor})
+
+	f.lastTicks = arrival.ticks
+	f.nextSequenceNumber = arrival.sequenceNumber + 1
+	return true
+}
+
+func (f *twccFeedback) packet() *rtcp.TransportLayerCC {
+	chunks := []rtcp.PacketStatusChunk{}
+	for i := 0; i < len(f.symbols); i += twccSymbolsPerChunk {
+		symbolList := make([]uint16, twccSymbolsPerChunk)
+		copy(symbolList, f.symbols[i:])
+
+		chunks = append(chunks, &rtcp.StatusVectorChunk{
+			Type:       rtcp.TypeTCCStatusVectorChunk,
+			SymbolSize: rtcp.TypeTCCSymbolSizeTwoBit,
+			SymbolList: symbolList,
+		})
+	}
+
+	length := twccFixedLength + len(chunks)*2 + f.deltaBytes
+	paddedLength := (length + 3) / 4 * 4
+	return &rtcp.TransportLayerCC{
+		Header: rtcp.Header{
+			Padding: paddedLength != length,
+			Count:   rtcp.FormatTCC,
+			Type:    rtcp.TypeTransportSpecificFeedback,
+			Length:  uint16(paddedLength/4 - 1),
+		},
+		MediaSSRC:          f.mediaSSRC,
+		BaseSequenceNumber: uint16(f.baseSequenceNumber),
+		PacketStatusCount:  uint16(len(f.symbols)),
+		ReferenceTime:      uint32(f.referenceTime) & 0xFFFFFF,
+		FbPktCount:         f.fbPktCount,
+		PacketChunks:       chunks,
+		RecvDeltas:         f.deltas,
+	}
+}
@@ -0,0 +1,97 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// unmarshalTWCC marshals and parses the feedback again, to check it is valid on the wire
+func unmarshalTWCC(t *testing.T, pkt rtcp.Packet) *rtcp.TransportLayerCC {
+	raw, err := pkt.Marshal()
+	assert.NoError(t, err)
+
+	pkts, err := rtcp.Unmarshal(raw)
+	assert.NoError(t, err)
+	assert.Len(t, pkts, 1)
+
+	feedback, ok := pkts[0].(*rtcp.TransportLayerCC)
+	assert.True(t, ok)
+	return feedback
+}
+
+func TestTWCCRecorder(t *testing.T) {
+	t.Run("Lost, Reordered and Wrapped", func(t *testing.T) {
+		r := newTWCCRecorder(0, nil)
+		r.started = true // Don't start sending feedback
+		now := r.startTime
+
+		r.record(1234, 65534, now)
+		r.record(1234, 1, now.Add(time.Millisecond))
+		r.record(1234, 65535, now.Add(2*time.Millisecond))
+		r.record(1234, 65535, now.Add(3*time.Millisecond))
+		r.record(1234, 2, now.Add(100*time.Millisecond))
+
+		pkts := r.buildFeedback()
+		assert.Len(t, pkts, 1)
+
+		feedback := unmarshalTWCC(t, pkts[0])
+		assert.Equal(t, uint32(1234), feedback.MediaSSRC)
+		assert.Equal(t, uint16(65534), feedback.BaseSequenceNumber)
+		assert.Equal(t, uint16(5), feedback.PacketStatusCount)
+		assert.Equal(t, uint32(0), feedback.ReferenceTime)
+		assert.Equal(t, uint8(0), feedback.FbPktCount)
+		assert.Equal(t, []uint16{
+			rtcp.TypeTCCPacketReceivedSmallDelta,
+			rtcp.TypeTCCPacketReceivedSmallDelta,
+			rtcp.TypeTCCPacketNotReceived,
+			rtcp.TypeTCCPacketReceivedLargeDelta,
+			rtcp.TypeTCCPacketReceivedLargeDelta,
+			rtcp.TypeTCCPacketNotReceived,
+			rtcp.TypeTCCPacketNotReceived,
+		}, feedback.PacketChunks[0].(*rtcp.StatusVectorChunk).SymbolList)
+
+		deltas := []int64{}
+		for _, d := range feedback.RecvDeltas {
+			deltas = append(deltas, d.Delta)
+		}
+		assert.Equal(t, []int64{0, 2000, -1000, 99000}, deltas)
+
+		// Packets of a range that was already reported are ignored
+		r.record(1234, 0, now.Add(101*time.Millisecond))
+		assert.Empty(t, r.buildFeedback())
+
+		r.record(1234, 3, now.Add(200*time.Millisecond))
+		pkts = r.buildFeedback()
+		assert.Len(t, pkts, 1)
+
+		feedback = unmarshalTWCC(t, pkts[0])
+		assert.Equal(t, uint16(3), feedback.BaseSequenceNumber)
+		assert.Equal(t, uint32(3), feedback.ReferenceTime)
+		assert.Equal(t, uint8(1), feedback.FbPktCount)
+	})
+
+	t.Run("Split", func(t *testing.T) {
+		r := newTWCCRecorder(0, nil)
+		r.started = true
+		now := r.startTime
+
+		for i := 0; i < twccMaxStatusCount+10; i++ {
+			r.record(1234, uint16(i), now)
+		}
+		r.record(1234, uint16(twccMaxStatusCount+10), now.Add(10*time.Second))
+
+		pkts := r.buildFeedback()
+		assert.Len(t, pkts, 3)
+
+		counts := []uint16{}
+		for _, pkt := range pkts {
+			counts = append(counts, unmarshalTWCC(t, pkt).PacketStatusCount)
+		}
+		assert.Equal(t, []uint16{twccMaxStatusCount, 10, 1}, counts)
+	})
+}